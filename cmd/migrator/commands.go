@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// nameRe описывает допустимое имя новой миграции
+var nameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// cmdUp применяет все или n следующих миграций
+func cmdUp(m *migrate.Migrate, opts options, args []string) error {
+	n, err := parseCount(args, 0)
+	if err != nil {
+		return err
+	}
+	if opts.dryRun {
+		return dryRun(m, opts, func(p *planner, from int) ([]step, error) { return p.up(from, n) })
+	}
+
+	if n == 0 {
+		err = m.Up() // применяем все миграции
+	} else {
+		err = m.Steps(n) // применяем n миграций
+	}
+	return report(err, "migrations applied")
+}
+
+// cmdDown откатывает n миграций, по умолчанию одну
+func cmdDown(m *migrate.Migrate, opts options, args []string) error {
+	n, err := parseCount(args, 1)
+	if err != nil {
+		return err
+	}
+	if opts.dryRun {
+		return dryRun(m, opts, func(p *planner, from int) ([]step, error) { return p.down(from, n) })
+	}
+	return report(m.Steps(-n), "migrations rolled back")
+}
+
+// cmdGoto мигрирует базу до указанной версии
+func cmdGoto(m *migrate.Migrate, opts options, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: goto requires exactly one version", errUsage)
+	}
+	version, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid version %q", errUsage, args[0])
+	}
+	if opts.dryRun {
+		return dryRun(m, opts, func(p *planner, from int) ([]step, error) { return p.to(from, uint(version)) })
+	}
+	return report(m.Migrate(uint(version)), fmt.Sprintf("migrated to version %d", version))
+}
+
+// cmdForce записывает версию без выполнения миграций (для восстановления после dirty состояния)
+func cmdForce(m *migrate.Migrate, opts options, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: force requires exactly one version", errUsage)
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < -1 {
+		return fmt.Errorf("%w: invalid version %q", errUsage, args[0])
+	}
+	if opts.dryRun {
+		fmt.Printf("would force version %d\n", version)
+		return nil
+	}
+	if err := m.Force(version); err != nil {
+		return fmt.Errorf("force version %d: %w", version, err)
+	}
+	fmt.Printf("version forced to %d\n", version)
+	return nil
+}
+
+// cmdStatus выводит текущую версию, флаг dirty и список неприменённых миграций
+func cmdStatus(m *migrate.Migrate, opts options, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("%w: status takes no arguments", errUsage)
+	}
+	current, dirty, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+	p, err := newPlanner(opts.migrationsPath)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	pending, err := p.up(current, 0)
+	if err != nil {
+		return err
+	}
+
+	if current < 0 {
+		fmt.Println("version: none")
+	} else {
+		fmt.Printf("version: %d\n", current)
+	}
+	fmt.Printf("dirty:   %t\n", dirty)
+	fmt.Printf("pending: %d\n", len(pending))
+	for _, s := range pending {
+		fmt.Printf("  %s\n", s.file())
+	}
+	return nil
+}
+
+// cmdCreate создаёт пустую пару файлов для новой миграции со следующим номером
+func cmdCreate(opts options, args []string) error {
+	if len(args) != 1 || !nameRe.MatchString(args[0]) {
+		return fmt.Errorf("%w: create requires one name matching %s", errUsage, nameRe)
+	}
+
+	p, err := newPlanner(opts.migrationsPath)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	last, err := p.last()
+	if err != nil {
+		return err
+	}
+	next := last + 1
+
+	for _, dir := range []string{"up", "down"} {
+		path := filepath.Join(opts.migrationsPath, fmt.Sprintf("%d_%s.%s.sql", next, args[0], dir))
+		if opts.dryRun {
+			fmt.Println("would create", path)
+			continue
+		}
+		// O_EXCL не даёт перезаписать существующий файл
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return fmt.Errorf("create migration file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("create migration file: %w", err)
+		}
+		fmt.Println("created", path)
+	}
+	return nil
+}
+
+// dryRun строит план миграций и печатает SQL, не изменяя базу
+func dryRun(m *migrate.Migrate, opts options, build func(p *planner, from int) ([]step, error)) error {
+	current, dirty, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database is dirty at version %d, fix it and run force first", current)
+	}
+
+	p, err := newPlanner(opts.migrationsPath)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	steps, err := build(p, current)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		fmt.Println("no migrations to apply")
+		return nil
+	}
+	return p.print(os.Stdout, steps)
+}
+
+// currentVersion возвращает текущую версию базы или -1, если миграции ещё не применялись
+func currentVersion(m *migrate.Migrate) (int, bool, error) {
+	version, dirty, err := m.Version()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return -1, false, nil
+		}
+		return 0, false, fmt.Errorf("read version: %w", err)
+	}
+	return int(version), dirty, nil
+}
+
+// report обрабатывает результат применения миграций
+func report(err error, done string) error {
+	if err != nil {
+		// обработка случая, когда нет изменений для применения
+		if errors.Is(err, migrate.ErrNoChange) {
+			fmt.Println("no migrations to apply")
+			return nil
+		}
+		var dirty migrate.ErrDirty
+		if errors.As(err, &dirty) {
+			return fmt.Errorf("%w (fix the schema manually, then run force %d)", err, dirty.Version)
+		}
+		return err
+	}
+	fmt.Println(done)
+	return nil
+}
+
+// parseCount разбирает необязательный аргумент с количеством миграций
+func parseCount(args []string, def int) (int, error) {
+	switch len(args) {
+	case 0:
+		return def, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%w: count must be a positive number, got %q", errUsage, args[0])
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("%w: too many arguments", errUsage)
+	}
+}
+
+// isNotExist сообщает, что источник не нашёл запрошенную миграцию
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
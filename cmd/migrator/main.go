@@ -7,44 +7,122 @@ import (
 	"github.com/golang-migrate/migrate/v4"                    // пакет для управления миграциями
 	_ "github.com/golang-migrate/migrate/v4/database/sqlite3" // подключение поддержки SQLite
 	_ "github.com/golang-migrate/migrate/v4/source/file"      // подключение источника миграций из файлов
+	"os"                                                      // пакет для работы с ОС (коды завершения)
 )
 
+// Коды завершения утилиты
+const (
+	exitOK    = 0 // успешное выполнение
+	exitError = 1 // ошибка при выполнении команды
+	exitUsage = 2 // некорректные аргументы командной строки
+)
+
+const usage = `usage: migrator -storage-path <path> -migrations-path <path> [flags] <command> [args]
+
+commands:
+  up [n]            применить все (или n) следующих миграций
+  down [n]          откатить n миграций (по умолчанию одну)
+  goto <version>    мигрировать вверх или вниз до указанной версии
+  force <version>   записать версию без применения миграций и снять флаг dirty
+  status            показать текущую версию, флаг dirty и список неприменённых миграций
+  create <name>     создать пару файлов <n>_<name>.up.sql / .down.sql
+  seed [flags]      идемпотентно применить фикстуры окружения (seed -h для списка флагов)
+
+если команда не указана, выполняется up
+
+flags:
+`
+
+// errUsage обозначает ошибку в аргументах командной строки
+var errUsage = errors.New("invalid usage")
+
+// options содержит общие параметры всех команд
+type options struct {
+	storagePath    string // путь к файлу базы данных
+	migrationsPath string // путь к директории с миграциями
+	migrationTable string // имя таблицы с версией миграций
+	dryRun         bool   // вывести SQL вместо применения миграций
+}
+
 func main() {
-	var storagePath, migrationPath, migrationTable string
+	os.Exit(run())
+}
+
+// run разбирает аргументы, выполняет команду и возвращает код завершения
+func run() int {
+	var opts options
 	// парсинг аргумента для пути к хранилищу
-	flag.StringVar(&storagePath, "storage-path", "", "storage path")
+	flag.StringVar(&opts.storagePath, "storage-path", "", "storage path")
 	// парсинг аргумента для пути к миграциям
-	flag.StringVar(&migrationPath, "migrations-path", "", "migration path")
+	flag.StringVar(&opts.migrationsPath, "migrations-path", "", "migration path")
 	// парсинг аргумента для имени таблицы миграций
-	flag.StringVar(&migrationTable, "migrations-table", "migrations", "name of migrations table")
+	flag.StringVar(&opts.migrationTable, "migrations-table", "migrations", "name of migrations table")
+	// режим пробного запуска: SQL выводится, но не выполняется
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "print SQL that would be executed without applying it")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 	// разбор аргументов командной строки
 	flag.Parse()
 
-	// проверка, что передан путь к хранилищу
-	if storagePath == "" {
-		panic("storage-path is required") // выброс ошибки, если аргумент не задан
+	cmd, args := "up", flag.Args() // по умолчанию применяем все миграции, как и раньше
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	if err := execute(opts, cmd, args); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, err)
+			flag.Usage()
+			return exitUsage
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// execute проверяет параметры и вызывает обработчик команды
+func execute(opts options, cmd string, args []string) error {
+	// начальные данные не зависят от файлов миграций
+	if cmd == "seed" {
+		return cmdSeed(opts, args)
 	}
 	// проверка, что передан путь к миграциям
-	if migrationPath == "" {
-		panic("migration-path is required") // выброс ошибки, если аргумент не задан
+	if opts.migrationsPath == "" {
+		return fmt.Errorf("%w: migrations-path is required", errUsage)
+	}
+	// команда create не требует подключения к базе
+	if cmd == "create" {
+		return cmdCreate(opts, args)
+	}
+	// проверка, что передан путь к хранилищу
+	if opts.storagePath == "" {
+		return fmt.Errorf("%w: storage-path is required", errUsage)
 	}
 
 	// создание объекта для управления миграциями
 	m, err := migrate.New(
-		"file://"+migrationPath, // источник миграций (файловая система)
-		fmt.Sprintf("sqlite3://%s?x-migrations-table=%s", storagePath, migrationTable)) // подключение к базе SQLite и таблице миграций
+		"file://"+opts.migrationsPath, // источник миграций (файловая система)
+		fmt.Sprintf("sqlite3://%s?x-migrations-table=%s", opts.storagePath, opts.migrationTable)) // подключение к базе SQLite и таблице миграций
 	if err != nil {
-		panic(err) // выброс ошибки, если объект не удалось создать
+		return fmt.Errorf("open migrations: %w", err)
 	}
+	defer m.Close()
 
-	// применение миграций
-	if err := m.Up(); err != nil {
-		// обработка случая, когда нет изменений для применения
-		if errors.Is(err, migrate.ErrNoChange) {
-			fmt.Println("no migrations to apply") // вывод сообщения об отсутствии миграций
-			return
-		}
-		panic(err) // выброс ошибки в случае других проблем
+	switch cmd {
+	case "up":
+		return cmdUp(m, opts, args)
+	case "down":
+		return cmdDown(m, opts, args)
+	case "goto":
+		return cmdGoto(m, opts, args)
+	case "force":
+		return cmdForce(m, opts, args)
+	case "status":
+		return cmdStatus(m, opts, args)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
 	}
-	fmt.Println("migrations applied") // сообщение об успешном применении миграций
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+	"io"
+)
+
+// step описывает одну миграцию, которую предстоит выполнить
+type step struct {
+	version    uint   // версия миграции
+	up         bool   // направление: true - up, false - down
+	identifier string // описание миграции из имени файла
+	missing    bool   // файл миграции в этом направлении отсутствует
+}
+
+// direction возвращает направление миграции строкой
+func (s step) direction() string {
+	if s.up {
+		return "up"
+	}
+	return "down"
+}
+
+// file возвращает имя файла миграции
+func (s step) file() string {
+	if s.missing {
+		return fmt.Sprintf("%d (no %s file)", s.version, s.direction())
+	}
+	return fmt.Sprintf("%d_%s.%s.sql", s.version, s.identifier, s.direction())
+}
+
+// planner вычисляет последовательность миграций без обращения к базе данных
+type planner struct {
+	src source.Driver // источник файлов миграций
+}
+
+// newPlanner открывает источник миграций из директории
+func newPlanner(migrationsPath string) (*planner, error) {
+	src, err := source.Open("file://" + migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("open migrations source: %w", err)
+	}
+	return &planner{src: src}, nil
+}
+
+// Close закрывает источник миграций
+func (p *planner) Close() error {
+	return p.src.Close()
+}
+
+// up возвращает до limit миграций вверх от версии from (limit 0 - все)
+func (p *planner) up(from int, limit int) ([]step, error) {
+	var steps []step
+	for limit == 0 || len(steps) < limit {
+		next, err := p.next(from)
+		if err != nil {
+			if isNotExist(err) {
+				break // больше миграций нет
+			}
+			return nil, err
+		}
+		s, err := p.step(next, true)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+		from = int(next)
+	}
+	return steps, nil
+}
+
+// down возвращает до limit миграций вниз от версии from
+func (p *planner) down(from int, limit int) ([]step, error) {
+	var steps []step
+	for from >= 0 && len(steps) < limit {
+		s, err := p.step(uint(from), false)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+
+		prev, err := p.src.Prev(uint(from))
+		if err != nil {
+			if !isNotExist(err) {
+				return nil, err
+			}
+			from = -1 // откатили самую первую миграцию
+			continue
+		}
+		from = int(prev)
+	}
+	return steps, nil
+}
+
+// to возвращает миграции, необходимые для перехода от версии from к версии target
+func (p *planner) to(from int, target uint) ([]step, error) {
+	if s, err := p.step(target, true); err != nil || s.missing {
+		return nil, fmt.Errorf("migration %d not found", target)
+	}
+
+	var steps []step
+	for from != int(target) {
+		if from < int(target) {
+			more, err := p.up(from, 1)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, more...)
+			from = int(more[0].version)
+			continue
+		}
+		more, err := p.down(from, 1)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, more...)
+		prev, err := p.src.Prev(uint(from))
+		if err != nil {
+			return nil, err
+		}
+		from = int(prev)
+	}
+	return steps, nil
+}
+
+// last возвращает номер последней миграции или 0, если их нет
+func (p *planner) last() (uint, error) {
+	last, err := p.src.First()
+	for err == nil {
+		var next uint
+		if next, err = p.src.Next(last); err == nil {
+			last = next
+		}
+	}
+	if !isNotExist(err) {
+		return 0, err
+	}
+	return last, nil
+}
+
+// print выводит SQL каждой миграции плана
+func (p *planner) print(w io.Writer, steps []step) error {
+	for _, s := range steps {
+		if s.missing {
+			fmt.Fprintf(w, "-- %d %s: no migration file, only the version will change\n\n", s.version, s.direction())
+			continue
+		}
+		r, _, err := p.read(s.version, s.up)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", s.file(), err)
+		}
+		body, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", s.file(), err)
+		}
+		fmt.Fprintf(w, "-- %s\n%s\n\n", s.file(), body)
+	}
+	return nil
+}
+
+// next возвращает версию, следующую за from (для -1 - первую)
+func (p *planner) next(from int) (uint, error) {
+	if from < 0 {
+		return p.src.First()
+	}
+	return p.src.Next(uint(from))
+}
+
+// step собирает описание миграции в нужном направлении
+func (p *planner) step(version uint, up bool) (step, error) {
+	r, identifier, err := p.read(version, up)
+	if err != nil {
+		if !isNotExist(err) {
+			return step{}, err
+		}
+		// у миграции нет файла в этом направлении: migrate только обновит версию
+		return step{version: version, up: up, missing: true}, nil
+	}
+	r.Close()
+	return step{version: version, up: up, identifier: identifier}, nil
+}
+
+// read открывает тело миграции в нужном направлении
+func (p *planner) read(version uint, up bool) (io.ReadCloser, string, error) {
+	if up {
+		return p.src.ReadUp(version)
+	}
+	return p.src.ReadDown(version)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/seed"
+	"github.com/linemk/gRPC_auth/internal/storage/sqlite"
+)
+
+// errDryRun откатывает транзакцию в режиме пробного запуска
+var errDryRun = errors.New("dry run")
+
+// cmdSeed применяет фикстуры выбранного окружения в одной транзакции
+func cmdSeed(opts options, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	seedsPath := fs.String("seeds-path", "./seeds", "directory with per-environment fixtures")
+	env := fs.String("env", "local", "environment whose fixtures are applied (subdirectory of seeds-path)")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("%w: seed takes no positional arguments", errUsage)
+	}
+	if opts.storagePath == "" {
+		return fmt.Errorf("%w: storage-path is required", errUsage)
+	}
+
+	fx, err := seed.Load(*seedsPath, *env)
+	if err != nil {
+		return err
+	}
+
+	st, err := sqlite.NewStorage(opts.storagePath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var changes []seed.Change
+	err = st.WithTx(context.Background(), func(tx *sqlite.Storage) error {
+		var err error
+		if changes, err = seed.Apply(context.Background(), tx, fx); err != nil {
+			return err
+		}
+		if opts.dryRun {
+			return errDryRun // ничего не фиксируем
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return err
+	}
+
+	for _, c := range changes {
+		fmt.Printf("%-5s %-40s %s\n", c.Kind, c.Key, c.Result)
+	}
+	if opts.dryRun {
+		fmt.Println("dry run: changes rolled back")
+	} else {
+		fmt.Printf("seeds for %q applied\n", *env)
+	}
+	return nil
+}
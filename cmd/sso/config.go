@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"                                        // Импорт разбора флагов команды
+	"fmt"                                         // Импорт вывода результата проверки
+	"github.com/linemk/gRPC_auth/internal/config" // Импорт загрузчика конфигурации
+	"os"                                          // Импорт стандартных потоков вывода
+)
+
+// runConfig выполняет команду sso config и возвращает код завершения процесса.
+// sso config check --config=path проверяет файл вместе с переменными окружения и секретами, не запуская сервер.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintln(os.Stderr, "usage: sso config check [--config=path]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config check", flag.ContinueOnError)
+	path := flags.String("config", os.Getenv("CONFIG_PATH"), "path to config file")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if _, err := config.Load(*path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("config %s is valid\n", *path)
+	return 0
+}
@@ -1,58 +1,83 @@
 package main
 
 import (
-	"github.com/linemk/gRPC_auth/internal/app"    // Импорт приложения
-	"github.com/linemk/gRPC_auth/internal/config" // Импорт загрузчика конфигурации
-	"log/slog"                                    // Импорт библиотеки логирования
-	"os"                                          // Импорт для работы с ОС
-	"os/signal"                                   // Импорт для обработки сигналов ОС
-	"syscall"                                     // Импорт системных вызовов
+	"context"                                         // Импорт контекста для остановки приложения
+	"fmt"                                             // Импорт форматирования причины остановки и вывода ошибок
+	"github.com/linemk/gRPC_auth/internal/app"        // Импорт приложения
+	"github.com/linemk/gRPC_auth/internal/config"     // Импорт загрузчика конфигурации
+	"github.com/linemk/gRPC_auth/internal/lib/logger" // Импорт журнала со скрытием персональных данных
+	"io"                                              // Импорт закрытия файла журнала
+	"log/slog"                                        // Импорт библиотеки логирования
+	"os"                                              // Импорт для работы с ОС
+	"os/signal"                                       // Импорт для обработки сигналов ОС
+	"syscall"                                         // Импорт системных вызовов
 )
 
-const (
-	envLocal = "local" // Среда разработки - локальная
-	envDev   = "dev"   // Среда разработки - dev
-	envProd  = "prod"  // Среда разработки - production
-)
-
-// setupLogger настраивает логгер в зависимости от среды
-func setupLogger(env string) *slog.Logger {
-	var log *slog.Logger // Переменная для логгера
-
-	switch env { // Логика выбора обработчика логов
-	case envLocal:
-		log = slog.New(
-			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}), // Логгер для local: текстовый вывод с уровнем отладки
-		)
-	case envDev:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}), // Логгер для dev: JSON вывод с уровнем отладки
-		)
-	case envProd:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}), // Логгер для prod: JSON вывод с уровнем информации
-		)
-	}
-	return log // Возвращаем настроенный логгер
+// setupLogger создает логгер по настройкам log. Уровень задаётся level и меняется при перезагрузке конфигурации.
+// Возвращаемый io.Closer закрывает файл журнала.
+func setupLogger(cfg *config.Config, level *slog.LevelVar) (*slog.Logger, io.Closer, error) {
+	return logger.New(logger.Config{
+		Format:     cfg.LogFormat(),
+		Output:     cfg.Log.Output,
+		File:       cfg.Log.File,
+		MaxSize:    int64(cfg.Log.MaxSizeMB) << 20,
+		MaxBackups: cfg.Log.MaxBackups,
+		Sampling: logger.Sampling{
+			Initial:    cfg.Log.Sampling.Initial,
+			Thereafter: cfg.Log.Sampling.Thereafter,
+			Tick:       cfg.Log.Sampling.Tick,
+		},
+		Redact: logger.Policy{
+			Emails: cfg.Log.Redact.Emails,
+			Tokens: cfg.Log.Redact.Tokens,
+		},
+	}, level)
 }
 
 func main() {
-	cfg := config.MustLoad() // Загружаем конфигурацию
+	if len(os.Args) > 1 && os.Args[1] == "config" { // Команды работы с конфигурацией без запуска сервера
+		os.Exit(runConfig(os.Args[2:]))
+	}
 
-	log := setupLogger(cfg.Env)                            // Настраиваем логгер в зависимости от среды
-	log.Info("starting application", slog.Any("env", cfg)) // Логгируем запуск приложения
+	configPath := config.FetchPath()
+	cfg, err := config.Load(configPath) // Загружаем и проверяем конфигурацию
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	// Создаем объект приложения
-	application := app.New(log, cfg.GRPC.Port, cfg.StoragePath, cfg.TokenTTL)
+	level := new(slog.LevelVar) // Уровень журнала из log.level или по среде
+	level.Set(cfg.SlogLevel())
+	log, logFile, err := setupLogger(cfg, level) // Настраиваем логгер по настройкам log
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	log.Info("starting application", slog.Any("config", cfg)) // Логгируем запуск приложения; секреты скрыты
 
-	go application.GRPCSrv.MustRun() // Запускаем gRPC сервер в отдельной го-рутине
+	// Создаем объект приложения
+	application := app.New(log, cfg)
+	if err := application.WatchConfig(configPath, level); err != nil { // Перезагружаем конфигурацию при изменении файла и по SIGHUP
+		panic(err)
+	}
 
-	// Создаем канал для получения сигнала остановки
+	// Приложение останавливается по сигналу ОС
+	ctx, cancel := context.WithCancelCause(context.Background())
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM) // Подписываемся на сигналы завершения из ОС
-	stopSign := <-stop                                   // Ожидаем сигнал остановки
+	go func() {
+		stopSign := <-stop                                                    // Ожидаем сигнал остановки
+		log.Info("received signal", slog.String("signal", stopSign.String())) // Логгируем полученный сигнал
+		cancel(fmt.Errorf("received signal %s", stopSign))
+	}()
 
-	log.Info("received signal", slog.String("signal", stopSign.String())) // Логгируем полученный сигнал
-	application.GRPCSrv.Stop()                                            // Останавливаем gRPC сервер
-	log.Info("application stopped")                                       // Логгируем остановку приложения
+	// Работает до сигнала или сбоя любого компонента; остановка ограничена по времени
+	err = application.Run(ctx, cfg.ShutdownTimeout)
+	if err != nil {
+		log.Error("application failed", slog.String("error", err.Error()))
+	}
+	_ = logFile.Close() // Файл журнала закрывается последним, после записей об остановке
+	if err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/services/audit"
+	"github.com/linemk/gRPC_auth/internal/storage/sqlite"
+)
+
+// cmdAuditVerify проверяет цепочку хэшей журнала аудита
+func cmdAuditVerify(ctx context.Context, st *sqlite.Storage, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("%w: audit-verify takes no arguments", errUsage)
+	}
+	report, err := audit.Verify(ctx, st)
+	if err != nil {
+		return err
+	}
+	// хэш последней записи стоит сохранить вне базы: по нему видно, если записи удалят с конца
+	fmt.Printf("audit log is intact: %d events, head %s\n", report.Events, report.Head)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/services/dataexport"
+	"io"
+	"os"
+)
+
+// cmdVerifyExport проверяет подпись архива персональных данных
+func cmdVerifyExport(args []string) error {
+	fs := flag.NewFlagSet("verify-export", flag.ContinueOnError)
+	input := fs.String("i", "-", "archive file (- for stdin)")
+	keyPath := fs.String("key", "", "ed25519 public key (or signing key) in PEM")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *keyPath == "" {
+		return fmt.Errorf("%w: -key is required", errUsage)
+	}
+
+	pub, err := dataexport.LoadPublicKey(*keyPath)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	header, err := dataexport.Verify(r, pub)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("signature ok: user %d, generated at %s, key %s\n",
+		header.UserID, header.GeneratedAt.Format("2006-01-02T15:04:05Z07:00"), header.KeyID)
+	return nil
+}
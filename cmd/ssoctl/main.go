@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"   // пакет для работы с контекстом
+	"errors"    // пакет для работы с ошибками
+	"flag"      // пакет для обработки аргументов командной строки
+	"fmt"       // пакет для форматированного вывода
+	"os"        // пакет для работы с ОС
+	"os/signal" // пакет для обработки сигналов ОС
+
+	"github.com/linemk/gRPC_auth/internal/storage/sqlite" // хранилище SQLite
+)
+
+// Коды завершения утилиты
+const (
+	exitOK    = 0 // успешное выполнение
+	exitError = 1 // ошибка при выполнении команды
+	exitUsage = 2 // некорректные аргументы командной строки
+)
+
+const usage = `usage: ssoctl -storage-path <path> <command> [flags] [args]
+
+commands:
+  backup <file>     сделать согласованную копию работающей базы
+  restore <file>    восстановить базу из копии (сервис должен быть остановлен)
+  export [flags]    выгрузить приложения, роли и пользователей в JSONL
+  import [flags]    загрузить выгрузку JSONL (идемпотентно, в одной транзакции)
+  import-users      импортировать пользователей с хэшами PBKDF2, SHA-512-crypt или bcrypt
+  verify-export     проверить подпись архива персональных данных (не требует storage-path)
+  audit-verify      проверить, что журнал аудита не изменён (цепочка хэшей)
+
+flags:
+`
+
+// errUsage обозначает ошибку в аргументах командной строки
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run())
+}
+
+// run разбирает аргументы, выполняет команду и возвращает код завершения
+func run() int {
+	var storagePath string
+	// парсинг аргумента для пути к хранилищу
+	flag.StringVar(&storagePath, "storage-path", "", "storage path")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// прерывание по Ctrl+C корректно останавливает длинные операции
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := execute(ctx, storagePath, flag.Args()); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, err)
+			flag.Usage()
+			return exitUsage
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// execute открывает хранилище и вызывает обработчик команды
+func execute(ctx context.Context, storagePath string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: command is required", errUsage)
+	}
+	cmd, args := args[0], args[1:]
+	// проверка архива не обращается к базе
+	if cmd == "verify-export" {
+		return cmdVerifyExport(args)
+	}
+	if storagePath == "" {
+		return fmt.Errorf("%w: storage-path is required", errUsage)
+	}
+
+	handlers := map[string]func(context.Context, *sqlite.Storage, []string) error{
+		"backup":       cmdBackup,
+		"restore":      cmdRestore,
+		"export":       cmdExport,
+		"import":       cmdImport,
+		"import-users": cmdImportUsers,
+		"audit-verify": cmdAuditVerify,
+	}
+	handler, ok := handlers[cmd]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+
+	st, err := sqlite.NewStorage(storagePath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	return handler(ctx, st, args)
+}
+
+// parseFlags разбирает флаги подкоманды и возвращает ошибку использования
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
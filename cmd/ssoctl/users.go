@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/services/userimport"
+	"github.com/linemk/gRPC_auth/internal/storage/sqlite"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cmdImportUsers импортирует пользователей с хэшами паролей из внешней системы
+func cmdImportUsers(ctx context.Context, st *sqlite.Storage, args []string) error {
+	fs := flag.NewFlagSet("import-users", flag.ContinueOnError)
+	input := fs.String("i", "-", "input file (- for stdin)")
+	format := fs.String("format", userimport.FormatCSV, "input format: csv or jsonl")
+	checkpointPath := fs.String("checkpoint", "", "file that stores the last processed line to resume an interrupted import")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	reader, err := userimport.NewReader(*format, in)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	checkpoint, err := readCheckpoint(*checkpointPath)
+	if err != nil {
+		return err
+	}
+	if checkpoint > 0 {
+		fmt.Fprintf(os.Stderr, "resuming after line %d\n", checkpoint)
+	}
+
+	// ошибки строк выводит сама команда, поэтому логгер импортёра отключён
+	importer := userimport.New(slog.New(slog.NewTextHandler(io.Discard, nil)), st)
+
+	var writeErr error
+	p, err := importer.Run(ctx, reader, checkpoint, func(p userimport.Progress, rowErr *userimport.RowError) {
+		if rowErr != nil {
+			fmt.Fprintln(os.Stderr, "rejected:", rowErr)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "processed=%d imported=%d skipped=%d failed=%d\n", p.Processed, p.Imported, p.Skipped, p.Failed)
+		if writeErr == nil {
+			writeErr = writeCheckpoint(*checkpointPath, p.Checkpoint)
+		}
+	})
+	if err != nil {
+		// точку возобновления сохраняем и при ошибке, чтобы не повторять обработанные строки
+		if cpErr := writeCheckpoint(*checkpointPath, p.Checkpoint); cpErr != nil {
+			return errors.Join(err, cpErr)
+		}
+		return err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if p.Failed > 0 {
+		return fmt.Errorf("%d rows rejected", p.Failed)
+	}
+	return nil
+}
+
+// readCheckpoint читает номер последней обработанной строки
+func readCheckpoint(path string) (int64, error) {
+	if path == "" {
+		return 0, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil // первый запуск
+		}
+		return 0, err
+	}
+	line, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint file %s: %w", path, err)
+	}
+	return line, nil
+}
+
+// writeCheckpoint атомарно сохраняет номер последней обработанной строки
+func writeCheckpoint(path string, line int64) error {
+	if path == "" {
+		return nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(line, 10)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -1,30 +1,396 @@
 package app
 
 import (
-	grpcapp "github.com/linemk/gRPC_auth/internal/app/grpc" // Импорт модуля gRPC приложения
-	"github.com/linemk/gRPC_auth/internal/services/auth"    // Импорт модуля сервиса авторизации
-	"github.com/linemk/gRPC_auth/internal/storage/sqlite"   // Импорт модуля хранилища, реализованного на SQLite
+	"context"    // Импорт контекста для создания экспортёра трасс
+	"crypto/tls" // Импорт TLS конфигурации HTTP шлюза
+	"fmt"        // Импорт форматирования ошибок конфигурации слушателей
+	"net/http"   // Импорт маршрутизации HTTP шлюза
+	"time"       // Импорт срока остановки
 
-	"log/slog" // Импорт логгера
-	"time"     // Импорт пакета для работы со временем
+	gatewayapp "github.com/linemk/gRPC_auth/internal/app/gateway" // Импорт модуля HTTP шлюза
+	grpcapp "github.com/linemk/gRPC_auth/internal/app/grpc"       // Импорт модуля gRPC приложения
+	metricsapp "github.com/linemk/gRPC_auth/internal/app/metrics" // Импорт модуля сервера метрик
+	"github.com/linemk/gRPC_auth/internal/config"                 // Импорт настроек приложения
+	authgrpc "github.com/linemk/gRPC_auth/internal/grpc/auth"     // Импорт обработчиков сервиса авторизации
+	"github.com/linemk/gRPC_auth/internal/grpc/gateway"           // Импорт HTTP шлюза
+	healthgrpc "github.com/linemk/gRPC_auth/internal/grpc/health" // Импорт проверки состояния gRPC сервера
+	"github.com/linemk/gRPC_auth/internal/grpc/interceptors"      // Импорт перехватчиков gRPC
+	oauthhttp "github.com/linemk/gRPC_auth/internal/http/oauth"   // Импорт конечных точек OAuth 2.0
+	"github.com/linemk/gRPC_auth/internal/lib/auditsink"          // Импорт получателей событий аудита
+	"github.com/linemk/gRPC_auth/internal/lib/tlsconfig"          // Импорт TLS конфигурации gRPC сервера
+	"github.com/linemk/gRPC_auth/internal/lib/tracing"            // Импорт трассировки OpenTelemetry
+	"github.com/linemk/gRPC_auth/internal/metrics"                // Импорт метрик Prometheus
+	"github.com/linemk/gRPC_auth/internal/services/audit"         // Импорт журнала аудита
+	"github.com/linemk/gRPC_auth/internal/services/auth"          // Импорт модуля сервиса авторизации
+	"github.com/linemk/gRPC_auth/internal/services/dataexport"    // Импорт модуля выгрузки персональных данных
+	"github.com/linemk/gRPC_auth/internal/services/erasure"       // Импорт модуля очистки удалённых учётных записей
+	"github.com/linemk/gRPC_auth/internal/services/events"        // Импорт доставки событий на вебхуки приложений
+	"github.com/linemk/gRPC_auth/internal/services/oauth"         // Импорт сервера авторизации OAuth 2.0
+	"github.com/linemk/gRPC_auth/internal/services/userimport"    // Импорт модуля импорта пользователей
+	"github.com/linemk/gRPC_auth/internal/storage/sqlite"         // Импорт модуля хранилища, реализованного на SQLite
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc" // Импорт трассировки gRPC вызовов
+	"google.golang.org/grpc"                                                      // Импорт опций gRPC сервера
+	"google.golang.org/grpc/credentials"                                          // Импорт TLS транспорта gRPC
+	"log/slog"                                                                    // Импорт логгера
 )
 
+// traceFlushTimeout ограничивает отправку оставшихся трасс при остановке
+const traceFlushTimeout = 5 * time.Second
+
 // App представляет основное приложение
 type App struct {
-	GRPCSrv *grpcapp.App // gRPC сервер приложения
+	GRPCSrv    *grpcapp.App       // gRPC сервер приложения
+	MetricsSrv *metricsapp.App    // HTTP сервер метрик, nil - метрики не отдаются
+	GatewaySrv *gatewayapp.App    // HTTP шлюз: JSON API, gRPC-Web и Connect; nil - шлюз не запускается
+	Purger     *erasure.Purger    // Фоновая очистка удалённых учётных записей
+	Audit      *audit.Log         // Журнал аудита, доставляет события получателям
+	Events     *events.Dispatcher // Доставка событий из outbox на вебхуки приложений
+	Watcher    *events.Watcher    // Подписки WatchEvents на события из outbox
+	Tracing    *tracing.Provider  // Поставщик трасс, останавливается последним, чтобы отправить спаны
+
+	lifecycle *Lifecycle                  // Порядок запуска и остановки компонентов
+	log       *slog.Logger                // Логгер перезагрузки конфигурации
+	cfg       *config.Config              // Действующая конфигурация; меняется только при перезагрузке
+	auth      *auth.Auth                  // Сервис авторизации, получает новое время жизни токена
+	deadlines *interceptors.DeadlineStore // Сроки вызовов всех слушателей и шлюза
 }
 
 // New создает новый экземпляр App
-func New(log *slog.Logger, grpcPort int, storagePath string, tokenTTL time.Duration) *App {
-	storage, err := sqlite.NewStorage(storagePath) // Инициализируем SQLite хранилище
+func New(log *slog.Logger, cfg *config.Config) *App {
+	storage, err := sqlite.NewStorage(cfg.StoragePath) // Инициализируем SQLite хранилище
 	if err != nil {
 		panic(err) // Завершаем работу приложения, если хранилище не удалось инициализировать
 	}
 
-	authService := auth.New(log, storage, storage, storage, tokenTTL) // Создаем сервис авторизации
+	// Трассировка настраивается первой: остальные компоненты берут глобальный поставщик трасс
+	tracer, err := tracing.New(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		panic(err) // Неизвестный экспортёр или недоступный файл трасс - ошибка конфигурации
+	}
+
+	appMetrics := metrics.New()          // Создаем метрики сервиса
+	storage.SetQueryObserver(appMetrics) // Измеряем длительность методов хранилища
+
+	auditLog := audit.New(log, storage) // Создаем журнал аудита событий безопасности
+	if err := addAuditSinks(auditLog, cfg.Audit); err != nil {
+		panic(err) // Неверные параметры получателя - ошибка конфигурации
+	}
+	authService := auth.New(log, storage, storage, storage, storage, cfg.TokenTTL, cfg.Deletion.GracePeriod, appMetrics, auditLog) // Создаем сервис авторизации
+	importer := userimport.New(log, storage)                                                                                       // Создаем сервис импорта пользователей
+
+	// Создаем фоновую очистку персональных данных удалённых пользователей
+	purger, err := erasure.New(log, storage, auditLog, cfg.Deletion.GracePeriod, cfg.Deletion.PurgeInterval, cfg.Deletion.PurgeMode)
+	if err != nil {
+		panic(err) // Неизвестный режим очистки - ошибка конфигурации
+	}
+
+	dispatcher, err := newDispatcher(log, storage, cfg.Events) // Создаем доставку событий на вебхуки
+	if err != nil {
+		panic(err) // Два вебхука у одного приложения - ошибка конфигурации
+	}
+	watcher := events.NewWatcher(log, storage, events.WatchOptions{ // Создаем подписки на события
+		PollInterval: cfg.Events.PollInterval,
+		BatchSize:    cfg.Events.BatchSize,
+		MaxWatchers:  cfg.Events.MaxWatchers,
+	})
+
+	signer, err := newExportSigner(log, cfg.DataExport.SigningKey) // Загружаем ключ подписи архивов персональных данных
+	if err != nil {
+		panic(err)
+	}
+	exporter := dataexport.New(log, storage, signer) // Создаем сервис выгрузки персональных данных
+
+	health := healthgrpc.New(log, storage, cfg.GRPC.Health.CheckInterval) // Готовность зависит от хранилища и версии миграций
+
+	deadlines := interceptors.NewDeadlineStore(callDeadlines(cfg.GRPC)) // Сроки вызовов общие и меняются при перезагрузке конфигурации
+
+	// У каждого слушателя свои перехватчики и TLS
+	var listeners []grpcapp.Listener
+	for _, lc := range cfg.GRPC.ListenerConfigs() {
+		opts, err := listenerOptions(log, deadlines, lc, appMetrics, tracer)
+		if err != nil {
+			panic(err) // Неизвестный перехватчик или неверные параметры TLS - ошибка конфигурации
+		}
+		listeners = append(listeners, grpcapp.Listener{
+			Name:     lc.Name,
+			Network:  lc.Network,
+			Address:  lc.Address,
+			Services: lc.Services,
+			Options:  opts,
+		})
+	}
+	grpcApp, err := grpcapp.New(log, authService, importer, exporter, auditLog, dispatcher, watcher, health, cfg.GRPC.Health.DrainDelay, listeners) // Создаем gRPC приложение
+	if err != nil {
+		panic(err) // Неизвестный сервис или сеть слушателя - ошибка конфигурации
+	}
+
+	var metricsApp *metricsapp.App
+	if cfg.Metrics.Addr != "" { // Сервер метрик запускается, только если задан адрес
+		metricsApp = metricsapp.New(log, cfg.Metrics.Addr, cfg.Metrics.Path, appMetrics.Handler())
+	}
+
+	var gatewayApp *gatewayapp.App
+	if cfg.Gateway.Addr != "" { // Шлюз запускается, только если задан адрес
+		// Шлюз использует перехватчики и сертификаты из grpc.interceptors и grpc.tls
+		unary, stream, err := newChains(log, deadlines, cfg.GRPC.Interceptors, cfg.GRPC.TLS.ClientApps, appMetrics)
+		if err != nil {
+			panic(err)
+		}
+		reloader, err := newReloader(log, cfg.GRPC.TLS)
+		if err != nil {
+			panic(err)
+		}
+		gw := gateway.New(log, unary, stream, gateway.CORS{
+			Origins: cfg.Gateway.CORS.Origins,
+			MaxAge:  cfg.Gateway.CORS.MaxAge,
+		})
+		authgrpc.Register(gw, authService, exporter) // Шлюз отдаёт методы сервиса авторизации
+		api, err := gw.Handler()
+		if err != nil {
+			panic(err)
+		}
+		// OAuth 2.0 обслуживается вне фильтра CORS шлюза: на страницу входа переходит браузер пользователя,
+		// а к конечной точке токена обращаются серверы приложений
+		oauthServer := oauth.New(log, storage, authService, auditLog, cfg.OAuth.CodeTTL)
+		handler := http.NewServeMux()
+		handler.Handle(oauthhttp.Prefix, oauthhttp.New(log, oauthServer))
+		handler.Handle("/", api)
+		var tlsCfg *tls.Config
+		if reloader != nil {
+			tlsCfg = reloader.ServerConfig("h2", "http/1.1")
+		}
+		gatewayApp = gatewayapp.New(log, cfg.Gateway.Addr, handler, tlsCfg)
+	}
+
+	application := &App{
+		GRPCSrv:    grpcApp,    // Записываем gRPC сервер в основное приложение
+		MetricsSrv: metricsApp, // Записываем сервер метрик в основное приложение
+		GatewaySrv: gatewayApp, // Записываем HTTP шлюз в основное приложение
+		Purger:     purger,     // Записываем фоновую очистку в основное приложение
+		Audit:      auditLog,   // Записываем журнал аудита в основное приложение
+		Events:     dispatcher, // Записываем доставку событий в основное приложение
+		Watcher:    watcher,    // Записываем подписки на события в основное приложение
+		Tracing:    tracer,     // Записываем поставщик трасс в основное приложение
+		log:        log,
+		cfg:        cfg,
+		auth:       authService,
+		deadlines:  deadlines,
+	}
+	application.lifecycle, err = newLifecycle(log, application, storage)
+	if err != nil {
+		panic(err)
+	}
+	return application
+}
+
+// Run запускает компоненты приложения и работает до отмены ctx или сбоя одного из них.
+// На остановку всех компонентов отводится shutdownTimeout.
+func (a *App) Run(ctx context.Context, shutdownTimeout time.Duration) error {
+	return a.lifecycle.Run(ctx, shutdownTimeout)
+}
+
+// newLifecycle регистрирует компоненты приложения. Серверы зависят от хранилища, трассировки и метрик,
+// поэтому останавливаются первыми; трассы отправляются последними, чтобы попали спаны остановки.
+// Доставка событий аудита останавливается после всех, кто их записывает.
+func newLifecycle(log *slog.Logger, a *App, storage *sqlite.Storage) (*Lifecycle, error) {
+	const op = "app.newLifecycle"
+
+	lc := NewLifecycle(log)
+	components := []Component{
+		{
+			Name: "tracing",
+			Stop: func(ctx context.Context) error {
+				// Трассы отправляются даже после истечения ctx: иначе теряются спаны самой остановки
+				flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), traceFlushTimeout)
+				defer cancel()
+				return a.Tracing.Shutdown(flushCtx)
+			},
+		},
+		{
+			Name: "storage",
+			Stop: func(context.Context) error { return storage.Close() },
+		},
+		{
+			Name: "audit",
+			Run:  a.Audit.Run, // Оставшиеся события отправляются или сохраняются на диск при отмене ctx
+		},
+		{
+			Name:      "events",
+			DependsOn: []string{"storage"},
+			Run: func(ctx context.Context) error {
+				a.Events.Run(ctx) // Доставка останавливается отменой ctx; недоставленные события остаются в outbox
+				return nil
+			},
+		},
+		{
+			Name:      "purger",
+			DependsOn: []string{"storage", "audit"},
+			Run: func(ctx context.Context) error {
+				a.Purger.Run(ctx) // Очистка останавливается отменой ctx
+				return nil
+			},
+		},
+	}
+	serverDeps := []string{"storage", "tracing", "audit"}
+	if a.MetricsSrv != nil {
+		components = append(components, Component{
+			Name: "metrics",
+			Run:  func(context.Context) error { return a.MetricsSrv.Run() },
+			Stop: a.MetricsSrv.Stop,
+		})
+		serverDeps = append(serverDeps, "metrics") // Сервер метрик останавливается после серверов, чтобы учесть последние вызовы
+	}
+	components = append(components, Component{
+		Name:      "grpc",
+		DependsOn: serverDeps,
+		Run:       func(context.Context) error { return a.GRPCSrv.Run() },
+		Stop: func(ctx context.Context) error {
+			a.Watcher.Stop() // Подписки WatchEvents бесконечны: без этого сервер ждал бы их до истечения срока остановки
+			return a.GRPCSrv.Stop(ctx)
+		},
+	})
+	if a.GatewaySrv != nil {
+		components = append(components, Component{
+			Name:      "gateway",
+			DependsOn: serverDeps,
+			Run:       func(context.Context) error { return a.GatewaySrv.Run() },
+			Stop:      a.GatewaySrv.Stop,
+		})
+	}
+	for _, c := range components {
+		if err := lc.Add(c); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return lc, nil
+}
+
+// listenerOptions собирает опции gRPC сервера слушателя: перехватчики, TLS и трассировку
+func listenerOptions(log *slog.Logger, deadlines *interceptors.DeadlineStore, lc config.ListenerConfig, observer interceptors.CallObserver, tracer *tracing.Provider) ([]grpc.ServerOption, error) {
+	unary, stream, err := newChains(log, deadlines, *lc.Interceptors, lc.TLS.ClientApps, observer)
+	if err != nil {
+		return nil, fmt.Errorf("listener %s: %w", lc.Name, err)
+	}
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream)}
+
+	reloader, err := newReloader(log.With(slog.String("listener", lc.Name)), *lc.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("listener %s: %w", lc.Name, err)
+	}
+	if reloader != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.ServerConfig("h2"))))
+	}
+	if tracer.Enabled() {
+		opts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler())) // Серверный спан каждого вызова с контекстом из traceparent
+	}
+	return opts, nil
+}
 
-	grpcApp := grpcapp.New(log, authService, grpcPort) // Создаем gRPC приложение
-	return &App{
-		GRPCSrv: grpcApp, // Записываем gRPC сервер в основное приложение
+// newChains собирает цепочки перехватчиков по настройкам
+func newChains(log *slog.Logger, deadlines *interceptors.DeadlineStore, ic config.InterceptorsConfig, clientApps map[string]int, observer interceptors.CallObserver) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor, error) {
+	return interceptors.Chains(log, interceptors.Config{
+		Enabled:         ic.Enabled,
+		RequestIDHeader: ic.RequestIDHeader,
+		Observer:        observer,
+		ClientApps:      clientApps,
+		Deadlines:       deadlines,
+	})
+}
+
+// callDeadlines собирает серверные сроки вызовов из настроек gRPC
+func callDeadlines(gc config.GRPCConfig) interceptors.Deadlines {
+	return interceptors.Deadlines{
+		Default: gc.Timeout,
+		Methods: gc.MethodTimeouts,
+	}
+}
+
+// newReloader загружает сертификаты сервера; nil, если сертификат не задан и сервер слушает без шифрования
+func newReloader(log *slog.Logger, tc config.TLSConfig) (*tlsconfig.Reloader, error) {
+	if tc.CertFile == "" {
+		return nil, nil
+	}
+	return tlsconfig.New(log, tlsconfig.Config{
+		CertFile:       tc.CertFile,
+		KeyFile:        tc.KeyFile,
+		MinVersion:     tc.MinVersion,
+		CipherSuites:   tc.CipherSuites,
+		ClientCAFile:   tc.ClientCAFile,
+		ClientAuth:     tc.ClientAuth,
+		ReloadInterval: tc.ReloadInterval,
+	})
+}
+
+// addAuditSinks создаёт получателей событий аудита по настройкам
+func addAuditSinks(auditLog *audit.Log, ac config.AuditConfig) error {
+	for _, sc := range ac.SinkConfigs() {
+		var (
+			sink audit.Sink
+			err  error
+		)
+		switch sc.Type {
+		case "file":
+			sink, err = auditsink.NewFile(sc.Path, int64(sc.MaxSizeMB)<<20, sc.MaxBackups)
+		case "syslog":
+			sink, err = auditsink.NewSyslog(sc.Network, sc.Address, sc.Facility, sc.AppName)
+		case "webhook":
+			sink = auditsink.NewWebhook(sc.URL, string(sc.Secret), sc.Timeout)
+		default:
+			err = fmt.Errorf("unknown type %q", sc.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("audit sink %s: %w", sc.Name, err)
+		}
+		err = auditLog.AddSink(sc.Name, sink, audit.SinkOptions{
+			Filter:        audit.Filter{Types: sc.Types, Outcomes: sc.Outcomes},
+			SpoolDir:      ac.SpoolDir,
+			BufferSize:    ac.BufferSize,
+			BatchSize:     ac.BatchSize,
+			FlushInterval: ac.FlushInterval,
+			RetryInterval: ac.RetryInterval,
+		})
+		if err != nil {
+			return fmt.Errorf("audit sink %s: %w", sc.Name, err)
+		}
+	}
+	return nil
+}
+
+// newDispatcher создаёт доставку событий на вебхуки приложений по настройкам
+func newDispatcher(log *slog.Logger, storage *sqlite.Storage, ec config.EventsConfig) (*events.Dispatcher, error) {
+	endpoints := make([]events.Endpoint, 0, len(ec.Webhooks))
+	for _, w := range ec.Webhooks {
+		endpoints = append(endpoints, events.Endpoint{AppID: w.AppID, URL: w.URL, Secret: string(w.Secret), Types: w.Types})
+	}
+	return events.New(log, storage, endpoints, events.Options{
+		PollInterval:     ec.PollInterval,
+		BatchSize:        ec.BatchSize,
+		Timeout:          ec.Timeout,
+		MaxAttempts:      ec.MaxAttempts,
+		RetryInterval:    ec.RetryInterval,
+		MaxRetryInterval: ec.MaxRetryInterval,
+		Retention:        ec.Retention,
+	})
+}
+
+// newExportSigner разбирает ключ подписи архивов или создаёт временный, если ключ не задан
+func newExportSigner(log *slog.Logger, key config.Secret) (*dataexport.Signer, error) {
+	if key != "" {
+		return dataexport.ParseSigner([]byte(key))
+	}
+	signer, err := dataexport.GenerateSigner()
+	if err != nil {
+		return nil, err
 	}
+	log.Warn("data_export.signing_key is not set, archives are signed with a temporary key",
+		slog.String("key_id", signer.KeyID()))
+	return signer, nil
 }
@@ -0,0 +1,66 @@
+package gatewayapp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// readHeaderTimeout ограничивает чтение заголовков запроса к шлюзу
+const readHeaderTimeout = 5 * time.Second
+
+// App представляет HTTP сервер шлюза JSON API
+type App struct {
+	log    *slog.Logger // Логгер для записи событий
+	server *http.Server // HTTP сервер шлюза
+}
+
+// New создает сервер шлюза на адресе addr. С tlsConfig сервер принимает только TLS соединения.
+func New(log *slog.Logger, addr string, handler http.Handler, tlsConfig *tls.Config) *App {
+	return &App{
+		log: log,
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+	}
+}
+
+// Run запускает HTTP сервер шлюза
+func (a *App) Run() error {
+	const op = "gatewayapp.Run"
+
+	l, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if a.server.TLSConfig != nil {
+		l = tls.NewListener(l, a.server.TLSConfig) // Сертификаты берутся из конфигурации при каждом соединении
+	}
+	a.log.Info("http gateway is running", slog.String("op", op),
+		slog.String("addr", l.Addr().String()), slog.Bool("tls", a.server.TLSConfig != nil))
+	if err := a.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Stop останавливает сервер, дожидаясь завершения текущих запросов.
+// Если ctx истёк раньше, соединения закрываются принудительно.
+func (a *App) Stop(ctx context.Context) error {
+	const op = "gatewayapp.Stop"
+
+	a.log.Info("stopping http gateway", slog.String("op", op))
+	if err := a.server.Shutdown(ctx); err != nil {
+		_ = a.server.Close()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
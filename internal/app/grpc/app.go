@@ -1,59 +1,238 @@
 package grpcapp
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"sync"
 
-	authgrpc "github.com/linemk/gRPC_auth/internal/grpc/auth" // Пакет для работы с gRPC авторизацией
-	"google.golang.org/grpc"                                  // gRPC библиотека
-	"log/slog"                                                // Логирование
-	"net"                                                     // Работа с сетевыми соединениями
+	admingrpc "github.com/linemk/gRPC_auth/internal/grpc/admin"   // Пакет административного gRPC API
+	authgrpc "github.com/linemk/gRPC_auth/internal/grpc/auth"     // Пакет для работы с gRPC авторизацией
+	healthgrpc "github.com/linemk/gRPC_auth/internal/grpc/health" // Пакет проверки состояния grpc.health.v1
+	"google.golang.org/grpc"                                      // gRPC библиотека
+	"log/slog"                                                    // Логирование
+	"net"                                                         // Работа с сетевыми соединениями
+	"time"                                                        // Работа со временем
 )
 
+// Имена сервисов в настройках слушателя
+const (
+	ServiceAuth   = "auth"   // Сервис авторизации auth.Auth
+	ServiceAdmin  = "admin"  // Административный сервис auth.Admin
+	ServiceHealth = "health" // Проверка состояния grpc.health.v1
+)
+
+// services - все сервисы; слушатель без списка сервисов обслуживает их все
+var services = []string{ServiceAuth, ServiceAdmin, ServiceHealth}
+
+var (
+	ErrUnknownService   = errors.New("unknown service")              // Неизвестное имя сервиса в настройках слушателя
+	ErrUnknownNetwork   = errors.New("unknown listener network")     // Сеть слушателя не tcp и не unix
+	ErrNoListeners      = errors.New("no listeners configured")      // Не задано ни одного слушателя
+	ErrSocketInUse      = errors.New("unix socket is in use")        // Сокет уже слушает другой процесс
+	ErrDuplicateAddress = errors.New("duplicate listener address")   // Два слушателя на одном адресе
+	ErrForcedStop       = errors.New("grpc server stopped forcibly") // Активные вызовы не завершились до истечения срока остановки
+)
+
+// Listener описывает один слушатель gRPC: адрес, сервисы и опции сервера (TLS, перехватчики)
+type Listener struct {
+	Name     string              // Имя для журнала
+	Network  string              // tcp или unix
+	Address  string              // host:port или путь к сокету
+	Services []string            // Сервисы слушателя; пустой - все
+	Options  []grpc.ServerOption // Опции gRPC сервера слушателя
+}
+
+// server - gRPC сервер одного слушателя
+type server struct {
+	Listener
+	gRPCServer *grpc.Server
+}
+
 // App представляет gRPC-приложение
 type App struct {
-	log        *slog.Logger // Логгер для записи событий
-	gRPCServer *grpc.Server // gRPC сервер
-	port       int          // Порт, на котором запускается сервер
+	log        *slog.Logger       // Логгер для записи событий
+	servers    []server           // gRPC серверы слушателей
+	health     *healthgrpc.Server // Сервер проверки состояния
+	drainDelay time.Duration      // Пауза между NOT_SERVING и остановкой, чтобы балансировщик вывел сервер
+	stopChecks context.CancelFunc // Останавливает периодические проверки готовности
+	checksCtx  context.Context    // Контекст периодических проверок готовности
+}
+
+// AuthService объединяет методы сервиса авторизации, нужные gRPC обработчикам
+type AuthService interface {
+	authgrpc.Auth
+	admingrpc.Authorizer
+	admingrpc.UserManager
 }
 
-// New создает новый экземпляр App
-func New(log *slog.Logger, authService authgrpc.Auth, port int) *App {
-	gRPCServer := grpc.NewServer()             // Создаем новый gRPC сервер
-	authgrpc.Register(gRPCServer, authService) // Регистрируем сервис авторизации в gRPC сервере
+// New создает новый экземпляр App с отдельным gRPC сервером на каждый слушатель
+func New(
+	log *slog.Logger,
+	authService AuthService,
+	importer admingrpc.Importer,
+	exporter authgrpc.DataExporter,
+	auditLog admingrpc.AuditLog,
+	deadLetters admingrpc.DeadLetters,
+	watcher admingrpc.EventWatcher,
+	health *healthgrpc.Server,
+	drainDelay time.Duration,
+	listeners []Listener,
+) (*App, error) {
+	const op = "grpcapp.New"
+
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoListeners)
+	}
+	servers := make([]server, 0, len(listeners))
+	addresses := make(map[string]bool, len(listeners))
+	for _, l := range listeners {
+		if l.Network != "tcp" && l.Network != "unix" {
+			return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownNetwork, l.Network)
+		}
+		if addresses[l.Network+":"+l.Address] {
+			return nil, fmt.Errorf("%s: %w: %s", op, ErrDuplicateAddress, l.Address)
+		}
+		addresses[l.Network+":"+l.Address] = true
+		if len(l.Services) == 0 {
+			l.Services = services
+		}
+		for _, name := range l.Services {
+			if !slices.Contains(services, name) {
+				return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownService, name)
+			}
+		}
+
+		gRPCServer := grpc.NewServer(l.Options...) // Создаем gRPC сервер слушателя
+		if slices.Contains(l.Services, ServiceAuth) {
+			authgrpc.Register(gRPCServer, authService, exporter) // Регистрируем сервис авторизации в gRPC сервере
+		}
+		if slices.Contains(l.Services, ServiceAdmin) {
+			admingrpc.Register(gRPCServer, authService, authService, importer, exporter, auditLog, deadLetters, watcher) // Регистрируем административный сервис
+		}
+		if slices.Contains(l.Services, ServiceHealth) {
+			health.Register(gRPCServer) // Регистрируем проверку состояния последней, чтобы она видела все сервисы
+		}
+		servers = append(servers, server{Listener: l, gRPCServer: gRPCServer})
+	}
+
+	checksCtx, stopChecks := context.WithCancel(context.Background())
 	return &App{
 		log:        log,        // Устанавливаем логгер
-		gRPCServer: gRPCServer, // Устанавливаем gRPC сервер
-		port:       port,       // Устанавливаем порт сервера
-	}
+		servers:    servers,    // Устанавливаем gRPC серверы
+		health:     health,     // Устанавливаем сервер проверки состояния
+		drainDelay: drainDelay, // Устанавливаем паузу перед остановкой
+		checksCtx:  checksCtx,  // Устанавливаем контекст проверок готовности
+		stopChecks: stopChecks, // Устанавливаем остановку проверок готовности
+	}, nil
 }
 
-// MustRun запускает сервер и паникует при ошибке
-func (a *App) MustRun() {
-	if err := a.Run(); err != nil { // Запускаем сервер
-		panic(err) // Если возникает ошибка, падаем с паникой
+// Run открывает все слушатели и обслуживает их до остановки.
+// Если хотя бы один адрес занят, не запускается ни один сервер.
+func (a *App) Run() error {
+	const op = "grpc.Run" // Обозначаем операцию для логирования
+
+	listeners := make([]net.Listener, 0, len(a.servers))
+	for _, srv := range a.servers {
+		l, err := listen(srv.Network, srv.Address) // Открываем соединение на заданном адресе
+		if err != nil {
+			for _, opened := range listeners {
+				_ = opened.Close()
+			}
+			return fmt.Errorf("%s:%w", op, err) // Возвращаем ошибку при невозможности открыть соединение
+		}
+		listeners = append(listeners, l)
 	}
+
+	go a.health.Run(a.checksCtx) // Сервер готов, когда доступно хранилище и применены миграции
+
+	errs := make([]error, len(a.servers))
+	var wg sync.WaitGroup
+	for i, srv := range a.servers {
+		a.log.Info(" grpc server is running", slog.String("op", op), slog.String("listener", srv.Name),
+			slog.String("addr", listeners[i].Addr().String()), slog.Any("services", srv.Services)) // Логируем успешный запуск gRPC сервера
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.gRPCServer.Serve(listeners[i]); err != nil { // Запускаем gRPC сервер на прослушивании соединения
+				errs[i] = fmt.Errorf("%s: %s: %w", op, srv.Name, err)
+				// Без одного из слушателей приложение работает не так, как настроено. Остальные серверы
+				// закрываются без ожидания: открытые потоки, например WatchEvents, сами не завершатся
+				a.closeServers()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...) // Возвращаем ошибки серверов, завершившихся со сбоем
 }
 
-// Run запускает gRPC сервер
-func (a *App) Run() error {
-	const op = "grpc.Run"                                              // Обозначаем операцию для логирования
-	log := a.log.With(slog.String("op", op), slog.Int("port", a.port)) // Добавляем в лог информацию о порте и операции
+// Stop переводит все сервисы в NOT_SERVING, ждёт drainDelay и останавливает gRPC серверы.
+// Если активные вызовы не завершились до истечения ctx, соединения закрываются без ожидания.
+func (a *App) Stop(ctx context.Context) error {
+	const op = "grpc.Stop"                   // Обозначаем операцию для логирования
+	log := a.log.With(slog.String("op", op)) // Добавляем в лог информацию об операции
+	log.Info("stopping grpc server")         // Логируем остановку сервера
+	a.health.Shutdown()                      // Проверки состояния больше не вернут SERVING
+	a.stopChecks()                           // Останавливаем периодические проверки готовности
+	if a.drainDelay > 0 {
+		log.Info("draining grpc server", slog.Duration("delay", a.drainDelay))
+		select { // Даём балансировщику заметить NOT_SERVING, новые вызовы пока обслуживаются
+		case <-time.After(a.drainDelay):
+		case <-ctx.Done():
+		}
+	}
 
-	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port)) // Открываем TCP соединение на заданном порту
-	if err != nil {
-		return fmt.Errorf("%s:%w", op, err) // Возвращаем ошибку при невозможности открыть соединение
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		a.stopServers() // Останавливаем серверы с завершением активных соединений
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		log.Warn("graceful stop timed out, closing connections")
+		a.closeServers() // Прерывает активные вызовы, после чего GracefulStop тоже возвращается
+		<-stopped
+		return fmt.Errorf("%s: %w", op, ErrForcedStop)
 	}
-	log.Info(" grpc server is running", slog.String("addr", l.Addr().String())) // Логируем успешный запуск gRPC сервера
-	if err := a.gRPCServer.Serve(l); err != nil {                               // Запускаем gRPC сервер на прослушивании соединения
-		return fmt.Errorf("%s:%w", op, err) // Возвращаем ошибку в случае сбоя
+}
+
+// stopServers одновременно останавливает серверы всех слушателей, дожидаясь завершения активных вызовов
+func (a *App) stopServers() {
+	var wg sync.WaitGroup
+	for _, srv := range a.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srv.gRPCServer.GracefulStop()
+		}()
 	}
-	return nil // Возвращаем nil при успешном запуске
+	wg.Wait()
 }
 
-// Stop останавливает gRPC сервер
-func (a *App) Stop() {
-	const op = "grpc.Stop"                                     // Обозначаем операцию для логирования
-	log := a.log.With(slog.String("op", op))                   // Добавляем в лог информацию об операции
-	log.Info("stopping grpc server", slog.Int("port", a.port)) // Логируем остановку сервера
-	a.gRPCServer.GracefulStop()                                // Останавливаем сервер с завершением активных соединений
+// closeServers останавливает серверы всех слушателей, прерывая активные вызовы
+func (a *App) closeServers() {
+	for _, srv := range a.servers {
+		srv.gRPCServer.Stop()
+	}
+}
+
+// listen открывает слушатель. Файл unix сокета, оставшийся после аварийной остановки, удаляется,
+// если к нему никто не подключен.
+func listen(network, address string) (net.Listener, error) {
+	if network == "unix" {
+		if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if conn, err := net.Dial("unix", address); err == nil {
+				_ = conn.Close()
+				return nil, fmt.Errorf("%w: %s", ErrSocketInUse, address)
+			}
+			if err := os.Remove(address); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return net.Listen(network, address)
 }
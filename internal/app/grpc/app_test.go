@@ -0,0 +1,309 @@
+package grpcapp
+
+import (
+	"context"
+	"errors"
+	healthgrpc "github.com/linemk/gRPC_auth/internal/grpc/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// readyProbe - хранилище, которое всегда готово
+type readyProbe struct{}
+
+func (readyProbe) Ping(context.Context) error        { return nil }
+func (readyProbe) CheckSchema(context.Context) error { return nil }
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newApp создаёт приложение со слушателями listeners. Обработчики сервисов не нужны:
+// тесты проверяют слушатели и набор зарегистрированных сервисов, а не вызовы.
+func newApp(listeners ...Listener) (*App, error) {
+	health := healthgrpc.New(discardLogger(), readyProbe{}, time.Hour)
+	return New(discardLogger(), nil, nil, nil, nil, nil, nil, health, 0, listeners)
+}
+
+// freeAddr возвращает свободный адрес TCP на localhost
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	return addr
+}
+
+// socketPath возвращает путь к unix сокету. Путь короткий: длина адреса unix сокета ограничена
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "sso")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return filepath.Join(dir, "sso.sock")
+}
+
+// runApp запускает приложение и возвращает канал с результатом Run.
+// Проверки готовности останавливаются в конце теста, даже если Stop не вызывался.
+func runApp(t *testing.T, app *App) <-chan error {
+	t.Helper()
+	t.Cleanup(app.stopChecks)
+	done := make(chan error, 1)
+	go func() { done <- app.Run() }()
+	return done
+}
+
+// waitDial ждёт, пока адрес начнёт принимать соединения
+func waitDial(t *testing.T, network, address string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial(network, address)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// waitRun ждёт завершения Run
+func waitRun(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("grpc app did not stop")
+		return nil
+	}
+}
+
+func TestNew_FailCases(t *testing.T) {
+	socket := socketPath(t)
+	tests := []struct {
+		name      string
+		listeners []Listener
+		wantErr   error
+	}{
+		{name: "no listeners", wantErr: ErrNoListeners},
+		{name: "unknown network", listeners: []Listener{{Network: "udp", Address: "127.0.0.1:0"}}, wantErr: ErrUnknownNetwork},
+		{
+			name:      "unknown service",
+			listeners: []Listener{{Network: "tcp", Address: "127.0.0.1:0", Services: []string{"auth", "reflection"}}},
+			wantErr:   ErrUnknownService,
+		},
+		{
+			name:      "duplicate tcp address",
+			listeners: []Listener{{Network: "tcp", Address: "127.0.0.1:44044"}, {Network: "tcp", Address: "127.0.0.1:44044"}},
+			wantErr:   ErrDuplicateAddress,
+		},
+		{
+			name:      "duplicate unix socket",
+			listeners: []Listener{{Network: "unix", Address: socket}, {Network: "unix", Address: socket}},
+			wantErr:   ErrDuplicateAddress,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newApp(tt.listeners...)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNew_ListenerServices(t *testing.T) {
+	app, err := newApp(
+		Listener{Name: "public", Network: "tcp", Address: "127.0.0.1:0", Services: []string{ServiceAuth, ServiceHealth}},
+		Listener{Name: "internal", Network: "unix", Address: socketPath(t), Services: []string{ServiceAdmin}},
+		Listener{Name: "all", Network: "tcp", Address: "127.0.0.1:1"},
+	)
+	require.NoError(t, err)
+
+	registered := func(i int) []string {
+		var names []string
+		for name := range app.servers[i].gRPCServer.GetServiceInfo() {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return names
+	}
+	assert.Equal(t, []string{"auth.Auth", "grpc.health.v1.Health"}, registered(0))
+	assert.Equal(t, []string{"auth.Admin"}, registered(1))
+	assert.Equal(t, []string{"auth.Admin", "auth.Auth", "grpc.health.v1.Health"}, registered(2))
+}
+
+func TestRun_TCPAndUnix(t *testing.T) {
+	tcpAddr, socket := freeAddr(t), socketPath(t)
+	app, err := newApp(
+		Listener{Name: "tcp", Network: "tcp", Address: tcpAddr},
+		Listener{Name: "unix", Network: "unix", Address: socket, Services: []string{ServiceAdmin}},
+	)
+	require.NoError(t, err)
+
+	done := runApp(t, app)
+	waitDial(t, "tcp", tcpAddr)
+	waitDial(t, "unix", socket)
+
+	require.NoError(t, app.Stop(context.Background()))
+	require.NoError(t, waitRun(t, done))
+	_, err = net.Dial("tcp", tcpAddr)
+	assert.Error(t, err)
+}
+
+func TestRun_AddressInUse_NoServerStarts(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	free := freeAddr(t)
+	app, err := newApp(
+		Listener{Name: "free", Network: "tcp", Address: free},
+		Listener{Name: "busy", Network: "tcp", Address: busy.Addr().String()},
+	)
+	require.NoError(t, err)
+
+	require.Error(t, waitRun(t, runApp(t, app)))
+	// Уже открытый первый слушатель закрыт: адрес снова свободен
+	l, err := net.Listen("tcp", free)
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+}
+
+func TestRun_ServerFails_AllServersClose(t *testing.T) {
+	tcpAddr := freeAddr(t)
+	app, err := newApp(
+		Listener{Name: "healthy", Network: "tcp", Address: tcpAddr},
+		Listener{Name: "failing", Network: "unix", Address: socketPath(t)},
+	)
+	require.NoError(t, err)
+	// Остановленный заранее сервер завершает Serve с ошибкой сразу после запуска
+	app.servers[1].gRPCServer.Stop()
+
+	err = waitRun(t, runApp(t, app))
+	require.ErrorIs(t, err, grpc.ErrServerStopped)
+	assert.Contains(t, err.Error(), "failing")
+	_, err = net.Dial("tcp", tcpAddr)
+	assert.Error(t, err, "healthy listener must be closed")
+}
+
+func TestListen_UnixSocket(t *testing.T) {
+	t.Run("stale socket is removed", func(t *testing.T) {
+		path := socketPath(t)
+		stale, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false) // Как после аварийной остановки: файл сокета остаётся
+		require.NoError(t, stale.Close())
+		_, err = os.Stat(path)
+		require.NoError(t, err)
+
+		l, err := listen("unix", path)
+		require.NoError(t, err)
+		defer l.Close()
+		waitDial(t, "unix", path)
+	})
+
+	t.Run("socket in use", func(t *testing.T) {
+		path := socketPath(t)
+		active, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		defer active.Close()
+
+		_, err = listen("unix", path)
+		require.ErrorIs(t, err, ErrSocketInUse)
+		// Сокет работающего процесса не удалён
+		waitDial(t, "unix", path)
+	})
+
+	t.Run("regular file is kept", func(t *testing.T) {
+		path := socketPath(t)
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+		_, err := listen("unix", path)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, ErrSocketInUse))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(data))
+	})
+}
+
+// blockingCall запускает приложение с перехватчиком, который держит вызов до закрытия release
+// или отмены вызова сервером, и начинает проверку состояния. Возвращает приложение и каналы с результатами Run и вызова.
+func blockingCall(t *testing.T, release <-chan struct{}) (*App, <-chan error, <-chan error) {
+	t.Helper()
+	started := make(chan struct{})
+	block := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return handler(ctx, req)
+	}
+	addr := freeAddr(t)
+	app, err := newApp(Listener{Name: "tcp", Network: "tcp", Address: addr, Options: []grpc.ServerOption{grpc.UnaryInterceptor(block)}})
+	require.NoError(t, err)
+	done := runApp(t, app)
+	waitDial(t, "tcp", addr)
+
+	cc, err := grpc.DialContext(context.Background(), addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+
+	call := make(chan error, 1)
+	go func() {
+		_, err := healthpb.NewHealthClient(cc).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		call <- err
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not reach server")
+	}
+	return app, done, call
+}
+
+func TestStop_Graceful(t *testing.T) {
+	release := make(chan struct{})
+	app, done, call := blockingCall(t, release)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- app.Stop(context.Background()) }()
+	// Пока вызов активен, GracefulStop его ждёт
+	select {
+	case err := <-stopped:
+		t.Fatalf("stop returned before active call finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-call)
+	require.NoError(t, waitRun(t, stopped))
+	require.NoError(t, waitRun(t, done))
+}
+
+func TestStop_ForcedAfterTimeout(t *testing.T) {
+	app, done, call := blockingCall(t, make(chan struct{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := app.Stop(ctx)
+	require.ErrorIs(t, err, ErrForcedStop)
+
+	// Активный вызов прерван, Run завершился
+	assert.Error(t, waitRun(t, call))
+	require.NoError(t, waitRun(t, done))
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrDuplicateComponent   = errors.New("duplicate component")            // Компонент с таким именем уже добавлен
+	ErrUnknownDependency    = errors.New("unknown component dependency")   // Зависимость не добавлена в жизненный цикл
+	ErrDependencyCycle      = errors.New("component dependency cycle")     // Компоненты зависят друг от друга по кругу
+	ErrUnexpectedStop       = errors.New("component stopped unexpectedly") // Run вернулся без ошибки до остановки
+	ErrComponentStopTimeout = errors.New("component did not stop in time") // Run не вернулся до истечения срока остановки
+)
+
+// Component - часть приложения с запуском и остановкой.
+// Компонент запускается после своих зависимостей и останавливается до них.
+type Component struct {
+	Name      string                          // Имя для журнала и зависимостей
+	DependsOn []string                        // Компоненты, которые должны работать, пока работает этот
+	Run       func(ctx context.Context) error // Работает до остановки; nil - компонент только останавливается (хранилище)
+	Stop      func(ctx context.Context) error // Останавливает компонент; после него Run должен вернуться. ctx Run отменяется следом
+}
+
+// Lifecycle запускает компоненты в порядке зависимостей и останавливает их в обратном порядке.
+// Сбой любого компонента останавливает все остальные.
+type Lifecycle struct {
+	log        *slog.Logger
+	components []Component
+	names      map[string]bool
+}
+
+// NewLifecycle создает пустой жизненный цикл
+func NewLifecycle(log *slog.Logger) *Lifecycle {
+	return &Lifecycle{log: log, names: make(map[string]bool)}
+}
+
+// Add добавляет компонент. Зависимости проверяются при запуске, поэтому порядок добавления не важен.
+func (l *Lifecycle) Add(c Component) error {
+	const op = "app.Lifecycle.Add"
+
+	if l.names[c.Name] {
+		return fmt.Errorf("%s: %w: %s", op, ErrDuplicateComponent, c.Name)
+	}
+	l.names[c.Name] = true
+	l.components = append(l.components, c)
+	return nil
+}
+
+// running - запущенный компонент
+type running struct {
+	Component
+	cancel   context.CancelFunc // Отменяет ctx Run
+	done     chan struct{}      // Закрывается, когда Run вернулся
+	stopping atomic.Bool        // Остановка запрошена: возврат из Run ожидаем
+}
+
+// Run запускает компоненты и работает, пока не отменён ctx или не завершился один из компонентов.
+// Затем компоненты останавливаются в обратном порядке; на всю остановку отводится shutdownTimeout.
+// Возвращает ошибки компонентов и остановки; отмена ctx ошибкой не считается.
+func (l *Lifecycle) Run(ctx context.Context, shutdownTimeout time.Duration) error {
+	const op = "app.Lifecycle.Run"
+	log := l.log.With(slog.String("op", op))
+
+	ordered, err := l.order()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	failed := make(chan error, len(ordered)) // Ошибки компонентов, завершившихся до остановки
+	started := make([]*running, 0, len(ordered))
+	for _, c := range ordered {
+		r := &running{Component: c, cancel: func() {}, done: make(chan struct{})}
+		started = append(started, r)
+		if c.Run == nil {
+			close(r.done)
+			continue
+		}
+		var runCtx context.Context
+		runCtx, r.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		log.Info("starting component", slog.String("component", c.Name))
+		go func() {
+			defer close(r.done)
+			err := c.Run(runCtx)
+			if r.stopping.Load() { // Run вернулся по Stop или отмене ctx
+				if err != nil {
+					log.Error("component stopped with error", slog.String("component", c.Name), slog.String("error", err.Error()))
+				}
+				return
+			}
+			if err == nil {
+				err = ErrUnexpectedStop
+			}
+			failed <- fmt.Errorf("%s: %w", c.Name, err)
+		}()
+	}
+	log.Info("application started", slog.Int("components", len(started)))
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+		log.Info("stopping application", slog.String("reason", context.Cause(ctx).Error()))
+	case err := <-failed:
+		log.Error("component failed, stopping application", slog.String("error", err.Error()))
+		errs = append(errs, err)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := l.stop(stopCtx, started[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", started[i].Name, err))
+		}
+	}
+	for len(failed) > 0 { // Компоненты, упавшие уже во время остановки
+		errs = append(errs, <-failed)
+	}
+	log.Info("application stopped")
+	return errors.Join(errs...)
+}
+
+// stop останавливает компонент и дожидается завершения его Run
+func (l *Lifecycle) stop(ctx context.Context, r *running) error {
+	log := l.log.With(slog.String("component", r.Name))
+	log.Info("stopping component")
+	start := time.Now()
+
+	r.stopping.Store(true)
+	var err error
+	if r.Stop != nil {
+		err = r.Stop(ctx)
+	}
+	r.cancel()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		err = errors.Join(err, ErrComponentStopTimeout)
+	}
+	if err != nil {
+		log.Error("component stopped with error", slog.String("error", err.Error()), slog.Duration("duration", time.Since(start)))
+		return err
+	}
+	log.Info("component stopped", slog.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// order сортирует компоненты так, чтобы зависимости шли раньше зависимых.
+// Независимые компоненты сохраняют порядок добавления.
+func (l *Lifecycle) order() ([]Component, error) {
+	byName := make(map[string]Component, len(l.components))
+	for _, c := range l.components {
+		byName[c.Name] = c
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(l.components))
+	ordered := make([]Component, 0, len(l.components))
+
+	var visit func(c Component) error
+	visit = func(c Component) error {
+		switch state[c.Name] {
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, c.Name)
+		case visited:
+			return nil
+		}
+		state[c.Name] = visiting
+		for _, name := range c.DependsOn {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, c.Name, name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[c.Name] = visited
+		ordered = append(ordered, c)
+		return nil
+	}
+	for _, c := range l.components {
+		if err := visit(c); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
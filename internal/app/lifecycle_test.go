@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder записывает остановки компонентов по порядку
+type recorder struct {
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (r *recorder) add(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = append(r.stopped, name)
+}
+
+// waiting возвращает компонент, который работает до отмены ctx и записывает свою остановку
+func (r *recorder) waiting(name string, deps ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: deps,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		},
+		Stop: func(context.Context) error {
+			r.add(name)
+			return nil
+		},
+	}
+}
+
+func newTestLifecycle(t *testing.T, components ...Component) *Lifecycle {
+	t.Helper()
+	l := NewLifecycle(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, c := range components {
+		require.NoError(t, l.Add(c))
+	}
+	return l
+}
+
+func names(components []Component) []string {
+	out := make([]string, 0, len(components))
+	for _, c := range components {
+		out = append(out, c.Name)
+	}
+	return out
+}
+
+func TestLifecycle_Order(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []Component
+		want       []string
+		wantErr    error
+	}{
+		{
+			name:       "independent keep insertion order",
+			components: []Component{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			want:       []string{"a", "b", "c"},
+		},
+		{
+			name: "dependencies first",
+			components: []Component{
+				{Name: "grpc", DependsOn: []string{"auth", "storage"}},
+				{Name: "auth", DependsOn: []string{"storage"}},
+				{Name: "metrics"},
+				{Name: "storage"},
+			},
+			want: []string{"storage", "auth", "grpc", "metrics"},
+		},
+		{
+			name:       "unknown dependency",
+			components: []Component{{Name: "grpc", DependsOn: []string{"storage"}}},
+			wantErr:    ErrUnknownDependency,
+		},
+		{
+			name: "cycle",
+			components: []Component{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"c"}},
+				{Name: "c", DependsOn: []string{"a"}},
+			},
+			wantErr: ErrDependencyCycle,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := newTestLifecycle(t, tt.components...).order()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names(ordered))
+		})
+	}
+}
+
+func TestLifecycle_Add_Duplicate(t *testing.T) {
+	l := newTestLifecycle(t, Component{Name: "storage"})
+	assert.ErrorIs(t, l.Add(Component{Name: "storage"}), ErrDuplicateComponent)
+}
+
+func TestLifecycle_Run(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name        string
+		components  func(r *recorder) []Component
+		cancel      bool // Остановить приложение отменой ctx
+		timeout     time.Duration
+		wantErr     []error
+		wantStopped []string
+	}{
+		{
+			name: "cancel stops in reverse dependency order",
+			components: func(r *recorder) []Component {
+				return []Component{r.waiting("grpc", "auth"), r.waiting("auth", "storage"), r.waiting("storage")}
+			},
+			cancel:      true,
+			wantStopped: []string{"grpc", "auth", "storage"},
+		},
+		{
+			name: "failed component stops the others",
+			components: func(r *recorder) []Component {
+				return []Component{
+					r.waiting("storage"),
+					{Name: "grpc", DependsOn: []string{"storage"}, Run: func(context.Context) error { return errBoom }},
+				}
+			},
+			wantErr:     []error{errBoom},
+			wantStopped: []string{"storage"},
+		},
+		{
+			name: "run returned without error",
+			components: func(r *recorder) []Component {
+				return []Component{r.waiting("storage"), {Name: "metrics", Run: func(context.Context) error { return nil }}}
+			},
+			wantErr:     []error{ErrUnexpectedStop},
+			wantStopped: []string{"storage"},
+		},
+		{
+			name: "stop error is returned",
+			components: func(r *recorder) []Component {
+				c := r.waiting("gateway")
+				c.Stop = func(context.Context) error { return errBoom }
+				return []Component{r.waiting("storage"), c}
+			},
+			cancel:      true,
+			wantErr:     []error{errBoom},
+			wantStopped: []string{"storage"},
+		},
+		{
+			name: "component ignoring stop times out",
+			components: func(r *recorder) []Component {
+				stuck := make(chan struct{})
+				t.Cleanup(func() { close(stuck) })
+				return []Component{
+					r.waiting("storage"),
+					{Name: "stream", Run: func(context.Context) error { <-stuck; return nil }},
+				}
+			},
+			cancel:      true,
+			timeout:     50 * time.Millisecond,
+			wantErr:     []error{ErrComponentStopTimeout},
+			wantStopped: []string{"storage"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			l := newTestLifecycle(t, tt.components(r)...)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = time.Second
+			}
+
+			err := l.Run(ctx, timeout)
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+			}
+			for _, want := range tt.wantErr {
+				assert.ErrorIs(t, err, want)
+			}
+			assert.Equal(t, tt.wantStopped, r.stopped)
+		})
+	}
+}
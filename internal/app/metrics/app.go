@@ -0,0 +1,62 @@
+package metricsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// readHeaderTimeout ограничивает чтение заголовков запроса к серверу метрик
+const readHeaderTimeout = 5 * time.Second
+
+// App представляет HTTP сервер, отдающий метрики
+type App struct {
+	log    *slog.Logger // Логгер для записи событий
+	server *http.Server // HTTP сервер метрик
+}
+
+// New создает сервер метрик, отдающий handler по пути path на адресе addr
+func New(log *slog.Logger, addr, path string, handler http.Handler) *App {
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	return &App{
+		log: log,
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+	}
+}
+
+// Run запускает HTTP сервер метрик
+func (a *App) Run() error {
+	const op = "metricsapp.Run"
+
+	l, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.log.Info("metrics server is running", slog.String("op", op), slog.String("addr", l.Addr().String()))
+	if err := a.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Stop останавливает сервер, дожидаясь завершения текущих запросов.
+// Если ctx истёк раньше, соединения закрываются принудительно.
+func (a *App) Stop(ctx context.Context) error {
+	const op = "metricsapp.Stop"
+
+	a.log.Info("stopping metrics server", slog.String("op", op))
+	if err := a.server.Shutdown(ctx); err != nil {
+		_ = a.server.Close()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/linemk/gRPC_auth/internal/config"
+)
+
+// reloadable - настройки, которые применяются без перезапуска
+var reloadable = []string{"token_ttl", "log.level", "grpc.timeout", "grpc.method_timeouts"}
+
+// WatchConfig перезагружает конфигурацию из configPath при изменении файла и по SIGHUP.
+// Настройки из reloadable применяются к работающим компонентам, изменения остальных журналируются
+// и не применяются до перезапуска. level - уровень журнала приложения. Вызывается до Run.
+func (a *App) WatchConfig(configPath string, level *slog.LevelVar) error {
+	return a.lifecycle.Add(Component{
+		Name: "config",
+		Run: func(ctx context.Context) error {
+			a.watchConfig(ctx, configPath, level)
+			return nil
+		},
+	})
+}
+
+// fileVersion отличает изменённый файл конфигурации от прежнего
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// statVersion возвращает версию файла; при ошибке - пустую, чтобы появление файла считалось изменением
+func statVersion(path string) fileVersion {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}
+	}
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}
+}
+
+// watchConfig ждёт SIGHUP или изменения файла, пока не отменён ctx
+func (a *App) watchConfig(ctx context.Context, configPath string, level *slog.LevelVar) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(a.cfg.WatchInterval)
+	defer ticker.Stop()
+
+	last := statVersion(configPath)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			a.log.Info("received SIGHUP, reloading config")
+			last = statVersion(configPath)
+			a.reloadConfig(configPath, level)
+		case <-ticker.C:
+			version := statVersion(configPath)
+			if version == last {
+				continue
+			}
+			last = version
+			a.log.Info("config file changed, reloading config")
+			a.reloadConfig(configPath, level)
+		}
+	}
+}
+
+// reloadConfig загружает и проверяет конфигурацию и применяет изменённые настройки из reloadable.
+// Неверная конфигурация не применяется целиком: работа продолжается с прежней.
+func (a *App) reloadConfig(configPath string, level *slog.LevelVar) {
+	const op = "app.reloadConfig"
+	log := a.log.With(slog.String("op", op))
+
+	next, err := config.Load(configPath)
+	if err != nil {
+		log.Error("config reload failed, keeping current config", slog.String("error", err.Error()))
+		return
+	}
+
+	var applied, ignored []string
+	for _, field := range a.cfg.Changed(next) {
+		if slices.Contains(reloadable, field) {
+			applied = append(applied, field)
+		} else {
+			ignored = append(ignored, field)
+		}
+	}
+	if len(ignored) > 0 {
+		log.Warn("config changes require restart and are ignored", slog.Any("fields", ignored))
+	}
+	if len(applied) == 0 {
+		log.Info("config reloaded, nothing to apply")
+		return
+	}
+
+	// Действующая конфигурация сохраняет прежние значения настроек, требующих перезапуска,
+	// поэтому их изменение будет снова показано при следующей перезагрузке
+	cfg := *a.cfg
+	cfg.TokenTTL = next.TokenTTL
+	cfg.Log.Level = next.Log.Level
+	cfg.GRPC.Timeout = next.GRPC.Timeout
+	cfg.GRPC.MethodTimeouts = next.GRPC.MethodTimeouts
+
+	a.auth.SetTokenTTL(cfg.TokenTTL)
+	a.deadlines.Store(callDeadlines(cfg.GRPC))
+	level.Set(cfg.SlogLevel())
+	a.cfg = &cfg
+	log.Info("config reloaded", slog.Any("applied", applied))
+}
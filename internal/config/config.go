@@ -1,37 +1,383 @@
+// Package config загружает настройки приложения из YAML файла.
+//
+// Любое поле можно переопределить переменной окружения с префиксом SSO_ и путём поля в верхнем регистре:
+// grpc.port - SSO_GRPC_PORT, grpc.tls.client_auth - SSO_GRPC_TLS_CLIENT_AUTH. Списки строк
+// задаются через запятую (SSO_GRPC_INTERCEPTORS_ENABLED=request_id,recovery), сроки методов -
+// парами метод:срок, а слушатели, origins, client_apps, audit.sinks и events.webhooks - в YAML (SSO_GRPC_LISTENERS='[{address: ":44045"}]').
+//
+// Секреты задаются значением или файлом с ключом <имя>_file (SSO_<ПУТЬ>_FILE), например смонтированным
+// секретом Kubernetes. В журнал секреты выводятся как [REDACTED].
+//
+// Часть настроек меняется без перезапуска при изменении файла или по SIGHUP: token_ttl, log.level,
+// grpc.timeout и grpc.method_timeouts. Изменения остальных настроек журналируются и не применяются.
 package config
 
 import (
+	"errors"                             // Модуль для объединения ошибок проверки
 	"flag"                               // Модуль для парсинга флагов командной строки
+	"fmt"                                // Модуль для форматирования ошибок загрузки
 	"github.com/ilyakaznacheev/cleanenv" // Библиотека для загрузки конфигурации из файла
+	"log/slog"                           // Модуль для уровня журнала
+	"net"                                // Модуль для сборки адреса слушателя
 	"os"                                 // Модуль для работы с операционной системой
+	"reflect"                            // Модуль для сравнения настроек при перезагрузке
+	"strconv"                            // Модуль для преобразования порта в строку
+	"strings"                            // Модуль для разбора тегов yaml
 	"time"                               // Модуль для работы с временем
 )
 
 // Config содержит основные настройки приложения
 type Config struct {
-	Env         string        `yaml:"env" env-default:"local"`          // Среда выполнения приложения, по умолчанию "local"
-	StoragePath string        `yaml:"storage_path" env-required:"true"` // Путь к файлу хранилища, обязателен для заполнения
-	TokenTTL    time.Duration `yaml:"token_ttl" env-required:"true"`    // Время жизни токена, обязателен для заполнения
-	GRPC        GRPCConfig    `yaml:"grpc"`                             // Настройки gRPC сервиса
+	Env             string           `yaml:"env" env:"SSO_ENV" env-default:"local"`                         // Среда выполнения приложения, по умолчанию "local"
+	StoragePath     string           `yaml:"storage_path" env:"SSO_STORAGE_PATH"`                           // Путь к файлу хранилища, обязателен для заполнения
+	TokenTTL        time.Duration    `yaml:"token_ttl" env:"SSO_TOKEN_TTL"`                                 // Время жизни токена, обязателен для заполнения
+	GRPC            GRPCConfig       `yaml:"grpc" env-prefix:"SSO_GRPC_"`                                   // Настройки gRPC сервиса
+	Deletion        DeletionConfig   `yaml:"deletion" env-prefix:"SSO_DELETION_"`                           // Настройки удаления учётных записей
+	DataExport      DataExportConfig `yaml:"data_export" env-prefix:"SSO_DATA_EXPORT_"`                     // Настройки выгрузки персональных данных
+	Metrics         MetricsConfig    `yaml:"metrics" env-prefix:"SSO_METRICS_"`                             // Настройки HTTP сервера метрик
+	Tracing         TracingConfig    `yaml:"tracing" env-prefix:"SSO_TRACING_"`                             // Настройки трассировки OpenTelemetry
+	Gateway         GatewayConfig    `yaml:"gateway" env-prefix:"SSO_GATEWAY_"`                             // Настройки HTTP/JSON шлюза
+	Log             LogConfig        `yaml:"log" env-prefix:"SSO_LOG_"`                                     // Настройки журнала приложения
+	Audit           AuditConfig      `yaml:"audit" env-prefix:"SSO_AUDIT_"`                                 // Доставка событий журнала аудита во внешние системы
+	Events          EventsConfig     `yaml:"events" env-prefix:"SSO_EVENTS_"`                               // Доставка событий предметной области на вебхуки приложений
+	OAuth           OAuthConfig      `yaml:"oauth" env-prefix:"SSO_OAUTH_"`                                 // Сервер авторизации OAuth 2.0 на адресе шлюза
+	ShutdownTimeout time.Duration    `yaml:"shutdown_timeout" env:"SSO_SHUTDOWN_TIMEOUT" env-default:"30s"` // Срок остановки; по его истечении серверы закрываются без ожидания вызовов
+	WatchInterval   time.Duration    `yaml:"watch_interval" env:"SSO_WATCH_INTERVAL" env-default:"10s"`     // Как часто проверять изменение файла конфигурации; перезагрузка также по SIGHUP
+}
+
+// SlogLevel возвращает уровень журнала: log.level или, если он не задан, debug для local и dev и info для prod
+func (c *Config) SlogLevel() slog.Level {
+	var level slog.Level
+	if c.Log.Level != "" && level.UnmarshalText([]byte(c.Log.Level)) == nil {
+		return level
+	}
+	if c.Env == "prod" {
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
+
+// Changed возвращает пути (как в YAML) настроек, значения которых в next отличаются от текущих.
+// Списки, словари и слушатели сравниваются целиком.
+func (c *Config) Changed(next *Config) []string {
+	return changedFields(reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem(), "")
+}
+
+// changedFields сравнивает вложенные настройки; path - путь к структуре в YAML
+func changedFields(cur, next reflect.Value, path string) []string {
+	var changed []string
+	t := cur.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := path + yamlKey(t.Field(i))
+		if t.Field(i).Type.Kind() == reflect.Struct {
+			changed = append(changed, changedFields(cur.Field(i), next.Field(i), key+".")...)
+			continue
+		}
+		if !reflect.DeepEqual(cur.Field(i).Interface(), next.Field(i).Interface()) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// yamlKey возвращает ключ поля в YAML
+func yamlKey(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("yaml"), ",")[0]
 }
 
 // GRPCConfig содержит настройки для gRPC сервера
 type GRPCConfig struct {
-	Port    int           `yaml:"port"`    // Порт, на котором запускается gRPC сервер
-	Timeout time.Duration `yaml:"timeout"` // Таймаут для gRPC соединений
+	Host           string                   `yaml:"host" env:"HOST"`                         // Адрес, на котором слушает сервер без listeners; пустой - все интерфейсы
+	Port           int                      `yaml:"port" env:"PORT"`                         // Порт, на котором запускается gRPC сервер без listeners
+	Timeout        time.Duration            `yaml:"timeout" env:"TIMEOUT"`                   // Серверный срок вызова для методов без своего значения; 0 - без срока
+	MethodTimeouts map[string]time.Duration `yaml:"method_timeouts" env:"METHOD_TIMEOUTS"`   // Полное имя метода, например /auth.Auth/Login -> срок вызова
+	Interceptors   InterceptorsConfig       `yaml:"interceptors" env-prefix:"INTERCEPTORS_"` // Настройки цепочки перехватчиков
+	Health         HealthConfig             `yaml:"health" env-prefix:"HEALTH_"`             // Настройки проверки состояния grpc.health.v1
+	TLS            TLSConfig                `yaml:"tls" env-prefix:"TLS_"`                   // Настройки TLS; без сертификата сервер слушает без шифрования
+	Listeners      Listeners                `yaml:"listeners" env:"LISTENERS"`               // Отдельные слушатели; пустой - один слушатель host:port со всеми сервисами
+}
+
+// ListenerConfig содержит настройки одного слушателя gRPC. У каждого слушателя свой набор сервисов,
+// TLS и цепочка перехватчиков, поэтому административные сервисы можно вынести на внутренний порт.
+type ListenerConfig struct {
+	Name         string              `yaml:"name"`         // Имя для журнала; пустое - адрес
+	Network      string              `yaml:"network"`      // Сеть: tcp (по умолчанию) или unix
+	Address      string              `yaml:"address"`      // host:port для tcp или путь к сокету для unix
+	Services     []string            `yaml:"services"`     // Сервисы: auth, admin, health; пустой - все
+	Interceptors *InterceptorsConfig `yaml:"interceptors"` // Перехватчики; не задано - grpc.interceptors
+	TLS          *TLSConfig          `yaml:"tls"`          // TLS; не задано - grpc.tls, без cert_file - без шифрования
+}
+
+// ListenerConfigs возвращает слушателей с заполненными значениями по умолчанию.
+// Без listeners это один слушатель host:port со всеми сервисами, как раньше.
+// Незаданные поля interceptors и tls слушателя берутся из grpc.interceptors и grpc.tls, кроме файлов сертификатов.
+func (c GRPCConfig) ListenerConfigs() []ListenerConfig {
+	if len(c.Listeners) == 0 {
+		return []ListenerConfig{{
+			Name:         "grpc",
+			Network:      "tcp",
+			Address:      net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+			Interceptors: &c.Interceptors,
+			TLS:          &c.TLS,
+		}}
+	}
+
+	listeners := make([]ListenerConfig, 0, len(c.Listeners))
+	for _, l := range c.Listeners {
+		if l.Network == "" {
+			l.Network = "tcp"
+		}
+		if l.Name == "" {
+			l.Name = l.Address
+		}
+		interceptors := c.Interceptors
+		if l.Interceptors != nil {
+			interceptors = *l.Interceptors
+			if interceptors.Enabled == nil {
+				interceptors.Enabled = c.Interceptors.Enabled
+			}
+			if interceptors.RequestIDHeader == "" {
+				interceptors.RequestIDHeader = c.Interceptors.RequestIDHeader
+			}
+		}
+		tls := c.TLS
+		if l.TLS != nil {
+			tls = *l.TLS
+			if tls.MinVersion == "" {
+				tls.MinVersion = c.TLS.MinVersion
+			}
+			if tls.ClientAuth == "" {
+				tls.ClientAuth = c.TLS.ClientAuth
+			}
+			if tls.ReloadInterval == 0 {
+				tls.ReloadInterval = c.TLS.ReloadInterval
+			}
+			if tls.CipherSuites == nil {
+				tls.CipherSuites = c.TLS.CipherSuites
+			}
+			if tls.ClientApps == nil {
+				tls.ClientApps = c.TLS.ClientApps
+			}
+		}
+		l.Interceptors, l.TLS = &interceptors, &tls
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+// TLSConfig содержит настройки TLS и проверки клиентских сертификатов gRPC сервера.
+// Файлы перечитываются после изменения без перезапуска.
+type TLSConfig struct {
+	CertFile       string        `yaml:"cert_file" env:"CERT_FILE"`                              // Сертификат сервера (PEM); пустой - TLS выключен
+	KeyFile        string        `yaml:"key_file" env:"KEY_FILE"`                                // Закрытый ключ сервера (PEM)
+	MinVersion     string        `yaml:"min_version" env:"MIN_VERSION" env-default:"1.2"`        // Минимальная версия TLS: 1.2 или 1.3
+	CipherSuites   []string      `yaml:"cipher_suites" env:"CIPHER_SUITES"`                      // Наборы шифров TLS 1.2 по именам IANA; пустой - по умолчанию
+	ClientCAFile   string        `yaml:"client_ca_file" env:"CLIENT_CA_FILE"`                    // CA для проверки клиентских сертификатов
+	ClientAuth     string        `yaml:"client_auth" env:"CLIENT_AUTH" env-default:"none"`       // Проверка клиентов: none, optional или require
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"RELOAD_INTERVAL" env-default:"1m"` // Как часто проверять изменение файлов сертификатов
+	ClientApps     AppIDs        `yaml:"client_apps" env:"CLIENT_APPS"`                          // URI, DNS имя или CN клиентского сертификата -> идентификатор приложения
+}
+
+// HealthConfig содержит настройки проверки готовности gRPC сервера
+type HealthConfig struct {
+	CheckInterval time.Duration `yaml:"check_interval" env:"CHECK_INTERVAL" env-default:"5s"` // Период проверки хранилища и версии миграций
+	DrainDelay    time.Duration `yaml:"drain_delay" env:"DRAIN_DELAY" env-default:"5s"`       // Пауза между NOT_SERVING и остановкой сервера
+}
+
+// InterceptorsConfig содержит настройки цепочки перехватчиков gRPC сервера.
+// Порядок в цепочке фиксирован, список только включает перехватчики.
+type InterceptorsConfig struct {
+	Enabled         []string `yaml:"enabled" env:"ENABLED" env-default:"request_id,access_log,metrics,recovery,deadline,client_cert"` // Включённые перехватчики: request_id, access_log, metrics, recovery, deadline, client_cert
+	RequestIDHeader string   `yaml:"request_id_header" env:"REQUEST_ID_HEADER" env-default:"x-request-id"`                            // Ключ метаданных с идентификатором запроса
+}
+
+// LogConfig содержит настройки журнала приложения
+type LogConfig struct {
+	Level      string            `yaml:"level" env:"LEVEL"`                               // Уровень: debug, info, warn или error; пустой - debug для local и dev, info для prod
+	Format     string            `yaml:"format" env:"FORMAT"`                             // Формат: text или json; пустой - text для local, json для остальных сред
+	Output     string            `yaml:"output" env:"OUTPUT" env-default:"stdout"`        // Выход: stdout, stderr или file
+	File       string            `yaml:"file" env:"FILE" env-default:"./storage/sso.log"` // Файл журнала для выхода file
+	MaxSizeMB  int               `yaml:"max_size_mb" env:"MAX_SIZE_MB" env-default:"100"` // Размер файла в мегабайтах, после которого он ротируется
+	MaxBackups int               `yaml:"max_backups" env:"MAX_BACKUPS" env-default:"5"`   // Сколько ротированных файлов хранить
+	Sampling   LogSamplingConfig `yaml:"sampling" env-prefix:"SAMPLING_"`                 // Ограничение частых записей уровней debug и info
+	Redact     LogRedactConfig   `yaml:"redact" env-prefix:"REDACT_"`                     // Скрытие персональных данных и секретов
 }
 
-// MustLoad загружает конфигурацию и завершает приложение при ошибке
-func MustLoad() *Config {
-	path := fetchConfigPath() // Получаем путь к файлу конфигурации
-	if path == "" {
-		panic("config path not found")
+// LogSamplingConfig содержит настройки ограничения частых записей: за интервал tick пишутся
+// первые initial записей с одним сообщением, затем каждая thereafter-я
+type LogSamplingConfig struct {
+	Initial    int           `yaml:"initial" env:"INITIAL"`                         // Записей без ограничения за интервал; 0 - ограничение выключено
+	Thereafter int           `yaml:"thereafter" env:"THEREAFTER" env-default:"100"` // После initial пишется каждая thereafter-я запись
+	Tick       time.Duration `yaml:"tick" env:"TICK" env-default:"1s"`              // Интервал сброса счётчиков
+}
+
+// LogRedactConfig задаёт, как в журнале скрываются email и токены
+type LogRedactConfig struct {
+	Emails string `yaml:"emails" env:"EMAILS" env-default:"mask"`   // none, mask (j***@example.com) или hash
+	Tokens string `yaml:"tokens" env:"TOKENS" env-default:"redact"` // redact или none
+}
+
+// LogFormat возвращает формат журнала: log.format или, если он не задан, text для local и json для остальных сред
+func (c *Config) LogFormat() string {
+	if c.Log.Format != "" {
+		return c.Log.Format
 	}
-	return MustLoadByPath(path)
+	if c.Env == "local" {
+		return "text"
+	}
+	return "json"
+}
+
+// AuditConfig содержит настройки доставки событий журнала аудита. Каждое событие доставляется
+// получателю не менее одного раза: недоставленные события хранятся в очереди на диске до восстановления получателя.
+type AuditConfig struct {
+	Sinks         AuditSinks    `yaml:"sinks" env:"SINKS"`                                             // Получатели событий; пустой - события только в базе
+	SpoolDir      string        `yaml:"spool_dir" env:"SPOOL_DIR" env-default:"./storage/audit-spool"` // Каталог очередей недоставленных событий, по файлу на получателя
+	BufferSize    int           `yaml:"buffer_size" env:"BUFFER_SIZE" env-default:"1000"`              // Ёмкость очереди в памяти; при переполнении события пишутся на диск
+	BatchSize     int           `yaml:"batch_size" env:"BATCH_SIZE" env-default:"100"`                 // Сколько событий отправляется за раз
+	FlushInterval time.Duration `yaml:"flush_interval" env:"FLUSH_INTERVAL" env-default:"1s"`          // Сколько неполная пачка ждёт новых событий
+	RetryInterval time.Duration `yaml:"retry_interval" env:"RETRY_INTERVAL" env-default:"5s"`          // Первый интервал повтора; удваивается до 5 минут
 }
 
-// fetchConfigPath получает путь к конфигурационному файлу
-func fetchConfigPath() string {
+// AuditSinkConfig содержит настройки одного получателя событий аудита. Используются поля его типа.
+type AuditSinkConfig struct {
+	Name     string   `yaml:"name"`     // Имя для журнала и файла очереди; пустое - тип
+	Type     string   `yaml:"type"`     // Тип: file, syslog или webhook
+	Types    []string `yaml:"types"`    // Типы событий, например user.login или user.*; пустой - все
+	Outcomes []string `yaml:"outcomes"` // Исходы: success, failure; пустой - все
+
+	Path       string `yaml:"path"`        // file: файл JSONL
+	MaxSizeMB  int    `yaml:"max_size_mb"` // file: размер в мегабайтах, после которого файл ротируется; 0 - 100
+	MaxBackups int    `yaml:"max_backups"` // file: сколько ротированных файлов хранить; 0 - 5
+
+	Network  string `yaml:"network"`  // syslog: udp, tcp или unix; пустой - udp
+	Address  string `yaml:"address"`  // syslog: host:port или путь к сокету, например /dev/log
+	Facility string `yaml:"facility"` // syslog: auth, authpriv или local0..local7; пустой - authpriv
+	AppName  string `yaml:"app_name"` // syslog: APP-NAME сообщения; пустой - sso
+
+	URL        string        `yaml:"url"`         // webhook: адрес, на который отправляется POST с событиями
+	Secret     Secret        `yaml:"secret"`      // webhook: ключ подписи HMAC-SHA256 в заголовке X-SSO-Signature, обязателен
+	SecretFile string        `yaml:"secret_file"` // webhook: файл с ключом подписи вместо secret
+	Timeout    time.Duration `yaml:"timeout"`     // webhook: срок запроса; 0 - 10 секунд
+}
+
+// SinkConfigs возвращает получателей с заполненными значениями по умолчанию
+func (c AuditConfig) SinkConfigs() []AuditSinkConfig {
+	sinks := make([]AuditSinkConfig, 0, len(c.Sinks))
+	for _, s := range c.Sinks {
+		if s.Name == "" {
+			s.Name = s.Type
+		}
+		switch s.Type {
+		case "file":
+			if s.MaxSizeMB == 0 {
+				s.MaxSizeMB = 100
+			}
+			if s.MaxBackups == 0 {
+				s.MaxBackups = 5
+			}
+		case "syslog":
+			if s.Network == "" {
+				s.Network = "udp"
+			}
+			if s.Facility == "" {
+				s.Facility = "authpriv"
+			}
+			if s.AppName == "" {
+				s.AppName = "sso"
+			}
+		case "webhook":
+			if s.Timeout == 0 {
+				s.Timeout = 10 * time.Second
+			}
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks
+}
+
+// EventsConfig содержит настройки доставки событий предметной области (регистрация, вход, смена состояния
+// учётной записи и т.п.) на вебхуки приложений и подписчикам WatchEvents. События пишутся в outbox всегда;
+// без вебхуков и подписчиков они только хранятся.
+type EventsConfig struct {
+	Webhooks         Webhooks      `yaml:"webhooks" env:"WEBHOOKS"`                                      // Вебхуки приложений, по одному на приложение
+	PollInterval     time.Duration `yaml:"poll_interval" env:"POLL_INTERVAL" env-default:"1s"`           // Как часто проверять новые события и повторы
+	BatchSize        int           `yaml:"batch_size" env:"BATCH_SIZE" env-default:"100"`                // Сколько событий обрабатывается за проход
+	Timeout          time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"10s"`                      // Срок одного запроса к вебхуку
+	MaxAttempts      int           `yaml:"max_attempts" env:"MAX_ATTEMPTS" env-default:"10"`             // После стольких неудачных попыток событие попадает в недоставленные
+	RetryInterval    time.Duration `yaml:"retry_interval" env:"RETRY_INTERVAL" env-default:"10s"`        // Интервал после первой неудачи; удваивается с каждой попыткой
+	MaxRetryInterval time.Duration `yaml:"max_retry_interval" env:"MAX_RETRY_INTERVAL" env-default:"1h"` // Больше интервал между попытками не растёт
+	Retention        time.Duration `yaml:"retention" env:"RETENTION" env-default:"168h"`                 // Сколько хранить доставленные события; подписчик WatchEvents, отставший больше, начинает заново
+	MaxWatchers      int           `yaml:"max_watchers" env:"MAX_WATCHERS" env-default:"100"`            // Сколько подписок WatchEvents обслуживается одновременно
+}
+
+// WebhookConfig содержит вебхук приложения. Запрос подписывается HMAC-SHA256 в заголовке X-SSO-Signature
+// вместе со временем отправки из X-SSO-Timestamp.
+type WebhookConfig struct {
+	AppID      int      `yaml:"app_id"`      // Приложение; события входа в другие приложения ему не доставляются
+	URL        string   `yaml:"url"`         // Адрес, на который отправляется POST с событием
+	Secret     Secret   `yaml:"secret"`      // Ключ подписи, обязателен
+	SecretFile string   `yaml:"secret_file"` // Файл с ключом подписи вместо secret
+	Types      []string `yaml:"types"`       // Типы событий, например user.registered или user.*; пустой - все
+}
+
+// MetricsConfig содержит настройки HTTP сервера метрик Prometheus
+type MetricsConfig struct {
+	Addr string `yaml:"addr" env:"ADDR"`                        // Адрес HTTP сервера метрик, например ":9102"; пустой - сервер не запускается
+	Path string `yaml:"path" env:"PATH" env-default:"/metrics"` // Путь, по которому отдаются метрики
+}
+
+// GatewayConfig содержит настройки HTTP шлюза к сервису авторизации: JSON API, gRPC-Web и Connect.
+// Шлюз использует перехватчики и TLS сертификаты gRPC сервера.
+type GatewayConfig struct {
+	Addr string     `yaml:"addr" env:"ADDR"`         // Адрес HTTP сервера шлюза, например ":8080"; пустой - шлюз не запускается
+	CORS CORSConfig `yaml:"cors" env-prefix:"CORS_"` // Браузерные приложения, которым разрешены запросы
+}
+
+// OAuthConfig содержит настройки сервера авторизации OAuth 2.0 (код авторизации с PKCE).
+// Конечные точки /oauth/authorize и /oauth/token работают на адресе шлюза, если он задан;
+// адреса возврата регистрируются у приложений в seed.
+type OAuthConfig struct {
+	CodeTTL time.Duration `yaml:"code_ttl" env:"CODE_TTL" env-default:"1m"` // Время жизни кода авторизации; не больше 10 минут (RFC 6749, раздел 4.1.2)
+}
+
+// CORSConfig содержит Origin браузерных приложений. Запросы с другим Origin отклоняются,
+// а с Origin из списка - допускают вход только в сопоставленное ему приложение.
+type CORSConfig struct {
+	Origins AppIDs        `yaml:"origins" env:"ORIGINS"`                   // Origin, например "https://app.example.com" -> идентификатор приложения
+	MaxAge  time.Duration `yaml:"max_age" env:"MAX_AGE" env-default:"10m"` // Сколько браузер хранит ответ на предварительный запрос
+}
+
+// TracingConfig содержит настройки экспорта трасс OpenTelemetry.
+// Контекст трассы входящих вызовов берётся из метаданных W3C traceparent.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"EXPORTER" env-default:"none"`           // Экспортёр: none, otlp, stdout или file
+	Endpoint    string  `yaml:"endpoint" env:"ENDPOINT" env-default:"localhost:4317"` // Адрес коллектора OTLP (gRPC)
+	Insecure    bool    `yaml:"insecure" env:"INSECURE"`                              // Подключаться к коллектору без TLS
+	File        string  `yaml:"file" env:"FILE" env-default:"./storage/traces.jsonl"` // Файл для экспортёра file
+	SampleRatio float64 `yaml:"sample_ratio" env:"SAMPLE_RATIO" env-default:"1"`      // Доля записываемых трасс, начатых в сервисе
+	ServiceName string  `yaml:"service_name" env:"SERVICE_NAME" env-default:"sso"`    // Имя сервиса в трассах
+}
+
+// DeletionConfig содержит настройки удаления учётных записей и очистки персональных данных
+type DeletionConfig struct {
+	GracePeriod   time.Duration `yaml:"grace_period" env:"GRACE_PERIOD" env-default:"720h"`   // Срок, в течение которого удалённую учётную запись можно восстановить
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"PURGE_INTERVAL" env-default:"1h"` // Период запуска фоновой очистки
+	PurgeMode     string        `yaml:"purge_mode" env:"PURGE_MODE" env-default:"anonymize"`  // Режим очистки: anonymize или erase
+}
+
+// DataExportConfig содержит настройки выгрузки персональных данных
+type DataExportConfig struct {
+	SigningKey     Secret `yaml:"signing_key" env:"SIGNING_KEY"`           // Закрытый ключ Ed25519 (PEM) для подписи архивов; пустой - ключ создаётся при запуске
+	SigningKeyFile string `yaml:"signing_key_file" env:"SIGNING_KEY_FILE"` // Файл с ключом подписи вместо signing_key
+	SigningKeyPath string `yaml:"signing_key_path" env:"SIGNING_KEY_PATH"` // Устаревшее имя signing_key_file
+}
+
+// ErrNoPath возвращается, если путь к файлу конфигурации не задан ни флагом, ни переменной окружения
+var ErrNoPath = errors.New("config path is not set: use --config or CONFIG_PATH")
+
+// FetchPath получает путь к конфигурационному файлу из флага --config или переменной CONFIG_PATH
+func FetchPath() string {
 	var res string // Переменная для хранения пути
 
 	flag.StringVar(&res, "config", "", "path to config file") // Читаем путь из аргументов командной строки
@@ -44,15 +390,39 @@ func fetchConfigPath() string {
 	return res // Возвращаем путь
 }
 
-func MustLoadByPath(configPath string) *Config {
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		panic("config file not found: " + configPath)
+// Read читает файл конфигурации и применяет переменные окружения, не загружая секреты и не проверяя значения.
+// Нужен клиентам сервиса, например e2e тестам, у которых нет доступа к секретам сервера.
+func Read(configPath string) (*Config, error) {
+	const op = "config.Read"
+
+	if configPath == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoPath)
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	var cfg Config
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		panic("failed to read config: " + err.Error())
+		return nil, fmt.Errorf("%s: %s: %w", op, configPath, err)
+	}
+	if cfg.DataExport.SigningKeyFile == "" {
+		cfg.DataExport.SigningKeyFile = cfg.DataExport.SigningKeyPath // Поддерживаем старое имя ключа
 	}
+	return &cfg, nil
+}
+
+// Load читает файл конфигурации, применяет переменные окружения и секреты из файлов и проверяет результат.
+// Ошибка проверки перечисляет все неверные поля, по одному на строку.
+func Load(configPath string) (*Config, error) {
+	const op = "config.Load"
 
-	return &cfg
+	cfg, err := Read(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := errors.Join(loadSecrets(cfg), cfg.Validate()); err != nil {
+		return nil, fmt.Errorf("%s: invalid config %s:\n%w", op, configPath, err)
+	}
+	return cfg, nil
 }
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted заменяет значение секрета в журнале и выводе конфигурации
+const redacted = "[REDACTED]"
+
+// Secret - секретное значение настройки. Рядом с полем Secret может быть строковое поле
+// с тем же именем и суффиксом File (ключ <имя>_file): тогда значение читается из этого файла.
+// fmt, slog и кодировщики JSON выводят непустой секрет как [REDACTED].
+type Secret string
+
+// String скрывает значение секрета
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// LogValue скрывает значение секрета в журнале
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalText скрывает значение секрета при выводе конфигурации в JSON или YAML
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// AppIDs сопоставляет строку (Origin, идентичность клиентского сертификата) идентификатору приложения.
+// В переменной окружения задаётся в YAML: {"https://app.example.com": 1}
+type AppIDs map[string]int
+
+// SetValue разбирает значение переменной окружения
+func (a *AppIDs) SetValue(s string) error {
+	*a = nil // Переменная окружения заменяет значение из файла, а не дополняет его
+	return yaml.Unmarshal([]byte(s), a)
+}
+
+// Listeners - слушатели gRPC. В переменной окружения задаются в YAML:
+// [{name: internal, network: unix, address: /run/sso.sock, services: [admin]}]
+type Listeners []ListenerConfig
+
+// SetValue разбирает значение переменной окружения
+func (l *Listeners) SetValue(s string) error {
+	*l = nil
+	return yaml.Unmarshal([]byte(s), l)
+}
+
+// AuditSinks - получатели событий аудита. В переменной окружения задаются в YAML:
+// [{type: syslog, address: "siem.internal:514", types: ["user.*"]}]
+type AuditSinks []AuditSinkConfig
+
+// SetValue разбирает значение переменной окружения
+func (a *AuditSinks) SetValue(s string) error {
+	*a = nil
+	return yaml.Unmarshal([]byte(s), a)
+}
+
+// Webhooks - вебхуки приложений. В переменной окружения задаются в YAML:
+// [{app_id: 1, url: "https://app.example.com/hooks/sso", secret_file: /run/secrets/sso-webhook, types: ["user.*"]}]
+type Webhooks []WebhookConfig
+
+// SetValue разбирает значение переменной окружения
+func (w *Webhooks) SetValue(s string) error {
+	*w = nil
+	return yaml.Unmarshal([]byte(s), w)
+}
+
+// secretType - тип секретных полей
+var secretType = reflect.TypeOf(Secret(""))
+
+// loadSecrets читает секреты из файлов, заданных ключами <имя>_file
+func loadSecrets(cfg *Config) error {
+	return errors.Join(walkSecrets(reflect.ValueOf(cfg).Elem(), "")...)
+}
+
+// walkSecrets обходит вложенные настройки; path - путь к структуре в YAML для сообщений об ошибках
+func walkSecrets(v reflect.Value, path string) []error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		key := path + yamlKey(field)
+		switch {
+		case field.Type == secretType:
+			fileField, ok := t.FieldByName(field.Name + "File")
+			if !ok || v.FieldByIndex(fileField.Index).String() == "" {
+				continue
+			}
+			if value.String() != "" {
+				errs = append(errs, fieldError(key, "set either %s or %s_file, not both", key, key))
+				continue
+			}
+			data, err := os.ReadFile(v.FieldByIndex(fileField.Index).String())
+			if err != nil {
+				errs = append(errs, fieldError(key+"_file", "%v", err))
+				continue
+			}
+			value.SetString(strings.TrimRight(string(data), "\r\n")) // Файлы секретов обычно заканчиваются переводом строки
+		case field.Type.Kind() == reflect.Struct:
+			errs = append(errs, walkSecrets(value, key+".")...)
+		case field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct && !value.IsNil():
+			errs = append(errs, walkSecrets(value.Elem(), key+".")...)
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			for j := 0; j < value.Len(); j++ {
+				errs = append(errs, walkSecrets(value.Index(j), fmt.Sprintf("%s[%d].", key, j))...)
+			}
+		}
+	}
+	return errs
+}
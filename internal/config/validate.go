@@ -0,0 +1,266 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Допустимые значения перечислимых настроек
+var (
+	envs         = []string{"local", "dev", "prod"}
+	logLevels    = []string{"debug", "info", "warn", "error"}
+	logFormats   = []string{"text", "json"}
+	logOutputs   = []string{"stdout", "stderr", "file"}
+	emailRedacts = []string{"none", "mask", "hash"}
+	tokenRedacts = []string{"redact", "none"}
+	interceptors = []string{"request_id", "access_log", "metrics", "recovery", "deadline", "client_cert"}
+	services     = []string{"auth", "admin", "health"}
+	networks     = []string{"tcp", "unix"}
+	tlsVersions  = []string{"1.2", "1.3"}
+	clientAuths  = []string{"none", "optional", "require"}
+	exporters    = []string{"none", "otlp", "stdout", "file"}
+	purgeModes   = []string{"anonymize", "erase"}
+	sinkTypes    = []string{"file", "syslog", "webhook"}
+	syslogNets   = []string{"udp", "tcp", "unix"}
+	facilities   = []string{"auth", "authpriv", "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7"}
+	outcomes     = []string{"success", "failure"}
+)
+
+// sinkName - допустимое имя получателя аудита: оно же имя файла очереди
+var sinkName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// methodPattern - формат полного имени метода в grpc.method_timeouts
+const methodPattern = "/<package>.<Service>/<Method>"
+
+// fieldError описывает ошибку в значении настройки: путь в YAML и что с ним не так
+func fieldError(field, format string, args ...any) error {
+	return fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...))
+}
+
+// validator собирает ошибки проверки, чтобы показать их все сразу
+type validator struct {
+	errs []error
+}
+
+// check добавляет ошибку поля, если условие не выполнено
+func (v *validator) check(ok bool, field, format string, args ...any) {
+	if !ok {
+		v.errs = append(v.errs, fieldError(field, format, args...))
+	}
+}
+
+// oneOf проверяет, что значение из списка допустимых
+func (v *validator) oneOf(field, value string, allowed []string) {
+	v.check(slices.Contains(allowed, value), field, "unknown value %q, want one of: %s", value, strings.Join(allowed, ", "))
+}
+
+// file проверяет, что заданный файл существует
+func (v *validator) file(field, path string) {
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		v.errs = append(v.errs, fieldError(field, "%v", err))
+	}
+}
+
+// Validate проверяет значения настроек и возвращает все найденные ошибки, по одной на строку
+func (c *Config) Validate() error {
+	v := &validator{}
+	v.oneOf("env", c.Env, envs)
+	v.check(c.StoragePath != "", "storage_path", "is required")
+	v.check(c.TokenTTL > 0, "token_ttl", "must be positive, got %s", c.TokenTTL)
+	v.check(c.ShutdownTimeout > 0, "shutdown_timeout", "must be positive, got %s", c.ShutdownTimeout)
+	v.check(c.WatchInterval > 0, "watch_interval", "must be positive, got %s", c.WatchInterval)
+
+	c.GRPC.validate(v)
+	c.Log.validate(v)
+	c.Audit.validate(v)
+	c.Events.validate(v)
+
+	v.check(c.Deletion.GracePeriod >= 0, "deletion.grace_period", "must not be negative")
+	v.check(c.Deletion.PurgeInterval > 0, "deletion.purge_interval", "must be positive, got %s", c.Deletion.PurgeInterval)
+	v.oneOf("deletion.purge_mode", c.Deletion.PurgeMode, purgeModes)
+
+	v.check(c.DataExport.SigningKeyPath == "" || c.DataExport.SigningKeyPath == c.DataExport.SigningKeyFile,
+		"data_export.signing_key_path", "is a deprecated name of signing_key_file, set only one of them")
+
+	v.check(c.Metrics.Addr == "" || strings.HasPrefix(c.Metrics.Path, "/"), "metrics.path", "must start with /, got %q", c.Metrics.Path)
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, exporters)
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	v.check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "tracing.endpoint", "is required for the otlp exporter")
+	v.check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file", "is required for the file exporter")
+
+	for _, origin := range slices.Sorted(maps.Keys(c.Gateway.CORS.Origins)) { // Ошибки в одном порядке при каждой проверке
+		appID := c.Gateway.CORS.Origins[origin]
+		u, err := url.Parse(origin)
+		v.check(err == nil && u.Scheme != "" && u.Host != "" && u.Path == "", "gateway.cors.origins",
+			"%q is not an origin, want scheme://host[:port]", origin)
+		v.check(appID > 0, "gateway.cors.origins", "%q: app id must be positive, got %d", origin, appID)
+	}
+	v.check(c.Gateway.CORS.MaxAge >= 0, "gateway.cors.max_age", "must not be negative")
+
+	v.check(c.OAuth.CodeTTL > 0 && c.OAuth.CodeTTL <= 10*time.Minute, "oauth.code_ttl",
+		"must be between 0 and 10m, got %s", c.OAuth.CodeTTL)
+
+	return errors.Join(v.errs...)
+}
+
+// validate проверяет настройки gRPC сервера и слушателей
+func (c GRPCConfig) validate(v *validator) {
+	v.check(c.Timeout >= 0, "grpc.timeout", "must not be negative")
+	for _, method := range slices.Sorted(maps.Keys(c.MethodTimeouts)) {
+		timeout := c.MethodTimeouts[method]
+		parts := strings.Split(method, "/")
+		v.check(len(parts) == 3 && parts[0] == "" && strings.Contains(parts[1], ".") && parts[2] != "",
+			"grpc.method_timeouts", "%q is not a full method name, want %s", method, methodPattern)
+		v.check(timeout >= 0, "grpc.method_timeouts", "%s: must not be negative", method)
+	}
+	if len(c.Listeners) == 0 {
+		v.check(c.Port > 0 && c.Port <= 65535, "grpc.port", "must be between 1 and 65535, got %d", c.Port)
+	}
+	v.check(c.Health.CheckInterval > 0, "grpc.health.check_interval", "must be positive, got %s", c.Health.CheckInterval)
+	v.check(c.Health.DrainDelay >= 0, "grpc.health.drain_delay", "must not be negative")
+
+	addresses := make(map[string]bool, len(c.Listeners))
+	for i, l := range c.ListenerConfigs() {
+		field := "grpc"
+		if len(c.Listeners) > 0 {
+			field = fmt.Sprintf("grpc.listeners[%d]", i)
+			v.oneOf(field+".network", l.Network, networks)
+			v.check(l.Address != "", field+".address", "is required")
+			v.check(!addresses[l.Network+":"+l.Address], field+".address", "%s is used by another listener", l.Address)
+			addresses[l.Network+":"+l.Address] = true
+			for _, name := range l.Services {
+				v.oneOf(field+".services", name, services)
+			}
+		}
+		for _, name := range l.Interceptors.Enabled {
+			v.oneOf(field+".interceptors.enabled", name, interceptors)
+		}
+		l.TLS.validate(v, field+".tls")
+	}
+}
+
+// validate проверяет настройки журнала
+func (c LogConfig) validate(v *validator) {
+	if c.Level != "" {
+		v.oneOf("log.level", c.Level, logLevels)
+	}
+	if c.Format != "" {
+		v.oneOf("log.format", c.Format, logFormats)
+	}
+	v.oneOf("log.output", c.Output, logOutputs)
+	v.check(c.Output != "file" || c.File != "", "log.file", "is required for the file output")
+	v.check(c.MaxSizeMB >= 0, "log.max_size_mb", "must not be negative")
+	v.check(c.MaxBackups >= 0, "log.max_backups", "must not be negative")
+	v.check(c.Sampling.Initial >= 0, "log.sampling.initial", "must not be negative")
+	v.check(c.Sampling.Thereafter >= 0, "log.sampling.thereafter", "must not be negative")
+	v.check(c.Sampling.Tick > 0, "log.sampling.tick", "must be positive, got %s", c.Sampling.Tick)
+	v.oneOf("log.redact.emails", c.Redact.Emails, emailRedacts)
+	v.oneOf("log.redact.tokens", c.Redact.Tokens, tokenRedacts)
+}
+
+// validate проверяет настройки доставки событий аудита и получателей
+func (c AuditConfig) validate(v *validator) {
+	if len(c.Sinks) == 0 {
+		return
+	}
+	v.check(c.SpoolDir != "", "audit.spool_dir", "is required with sinks")
+	v.check(c.BufferSize > 0, "audit.buffer_size", "must be positive, got %d", c.BufferSize)
+	v.check(c.BatchSize > 0, "audit.batch_size", "must be positive, got %d", c.BatchSize)
+	v.check(c.FlushInterval > 0, "audit.flush_interval", "must be positive, got %s", c.FlushInterval)
+	v.check(c.RetryInterval > 0, "audit.retry_interval", "must be positive, got %s", c.RetryInterval)
+
+	names := make(map[string]bool, len(c.Sinks))
+	for i, s := range c.SinkConfigs() {
+		field := fmt.Sprintf("audit.sinks[%d]", i)
+		v.oneOf(field+".type", s.Type, sinkTypes)
+		v.check(sinkName.MatchString(s.Name), field+".name", "%q must consist of lowercase letters, digits, _ and -", s.Name)
+		v.check(!names[s.Name], field+".name", "%q is used by another sink", s.Name)
+		names[s.Name] = true
+		for _, o := range s.Outcomes {
+			v.oneOf(field+".outcomes", o, outcomes)
+		}
+		for _, t := range s.Types {
+			v.check(t != "" && !strings.Contains(strings.TrimSuffix(t, "*"), "*"), field+".types",
+				"%q is not an event type, want a type like user.login or a prefix like user.*", t)
+		}
+
+		switch s.Type {
+		case "file":
+			v.check(s.Path != "", field+".path", "is required for the file sink")
+			v.check(s.MaxSizeMB > 0, field+".max_size_mb", "must not be negative")
+			v.check(s.MaxBackups > 0, field+".max_backups", "must not be negative")
+		case "syslog":
+			v.oneOf(field+".network", s.Network, syslogNets)
+			v.check(s.Address != "", field+".address", "is required for the syslog sink")
+			v.oneOf(field+".facility", s.Facility, facilities)
+		case "webhook":
+			u, err := url.Parse(s.URL)
+			v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", field+".url",
+				"%q is not an http(s) URL", s.URL)
+			v.check(s.Timeout > 0, field+".timeout", "must not be negative")
+			v.check(s.Secret != "", field+".secret", "is required for the webhook sink: requests are signed with it")
+		}
+	}
+}
+
+// validate проверяет настройки доставки событий на вебхуки
+func (c EventsConfig) validate(v *validator) {
+	v.check(c.PollInterval > 0, "events.poll_interval", "must be positive, got %s", c.PollInterval)
+	v.check(c.BatchSize > 0, "events.batch_size", "must be positive, got %d", c.BatchSize)
+	v.check(c.Timeout > 0, "events.timeout", "must be positive, got %s", c.Timeout)
+	v.check(c.MaxAttempts > 0, "events.max_attempts", "must be positive, got %d", c.MaxAttempts)
+	v.check(c.RetryInterval > 0, "events.retry_interval", "must be positive, got %s", c.RetryInterval)
+	v.check(c.MaxRetryInterval >= c.RetryInterval, "events.max_retry_interval", "must not be less than retry_interval")
+	v.check(c.Retention > 0, "events.retention", "must be positive, got %s", c.Retention)
+	v.check(c.MaxWatchers > 0, "events.max_watchers", "must be positive, got %d", c.MaxWatchers)
+
+	apps := make(map[int]bool, len(c.Webhooks))
+	for i, w := range c.Webhooks {
+		field := fmt.Sprintf("events.webhooks[%d]", i)
+		v.check(w.AppID > 0, field+".app_id", "must be positive, got %d", w.AppID)
+		v.check(!apps[w.AppID], field+".app_id", "app %d already has a webhook", w.AppID)
+		v.check(w.Secret != "", field+".secret", "is required: requests are signed with it")
+		apps[w.AppID] = true
+		u, err := url.Parse(w.URL)
+		v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", field+".url",
+			"%q is not an http(s) URL", w.URL)
+		for _, t := range w.Types {
+			v.check(t != "" && !strings.Contains(strings.TrimSuffix(t, "*"), "*"), field+".types",
+				"%q is not an event type, want a type like user.registered or a prefix like user.*", t)
+		}
+	}
+}
+
+// validate проверяет настройки TLS; field - путь к ним в YAML
+func (c TLSConfig) validate(v *validator, field string) {
+	v.oneOf(field+".min_version", c.MinVersion, tlsVersions)
+	v.oneOf(field+".client_auth", c.ClientAuth, clientAuths)
+	for _, name := range c.CipherSuites {
+		v.check(slices.ContainsFunc(tls.CipherSuites(), func(s *tls.CipherSuite) bool { return s.Name == name }),
+			field+".cipher_suites", "unknown or insecure cipher suite %q", name)
+	}
+	for _, identity := range slices.Sorted(maps.Keys(c.ClientApps)) {
+		appID := c.ClientApps[identity]
+		v.check(appID > 0, field+".client_apps", "%q: app id must be positive, got %d", identity, appID)
+	}
+	if c.CertFile == "" {
+		return
+	}
+	v.check(c.KeyFile != "", field+".key_file", "is required with cert_file")
+	v.check(c.ClientAuth == "none" || c.ClientCAFile != "", field+".client_ca_file", "is required when client_auth is %s", c.ClientAuth)
+	v.file(field+".cert_file", c.CertFile)
+	v.file(field+".key_file", c.KeyFile)
+	v.file(field+".client_ca_file", c.ClientCAFile)
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig возвращает настройки, проходящие проверку; тесты портят в них одно поле
+func validConfig() *Config {
+	return &Config{
+		Env:         "local",
+		StoragePath: "./storage/sso.db",
+		TokenTTL:    time.Hour,
+		GRPC: GRPCConfig{
+			Port:         44044,
+			Interceptors: InterceptorsConfig{Enabled: []string{"request_id", "recovery"}},
+			Health:       HealthConfig{CheckInterval: 5 * time.Second},
+			TLS:          TLSConfig{MinVersion: "1.2", ClientAuth: "none"},
+		},
+		Log: LogConfig{
+			Output:   "stdout",
+			Sampling: LogSamplingConfig{Tick: time.Second},
+			Redact:   LogRedactConfig{Emails: "mask", Tokens: "redact"},
+		},
+		Audit: AuditConfig{
+			Sinks:         AuditSinks{{Type: "file", Path: "./storage/audit.jsonl"}},
+			SpoolDir:      "./storage/audit-spool",
+			BufferSize:    1000,
+			BatchSize:     100,
+			FlushInterval: time.Second,
+			RetryInterval: 5 * time.Second,
+		},
+		Events: EventsConfig{
+			Webhooks:         Webhooks{{AppID: 1, URL: "http://localhost:18090/hooks/sso", Secret: "secret"}},
+			PollInterval:     time.Second,
+			BatchSize:        100,
+			Timeout:          10 * time.Second,
+			MaxAttempts:      10,
+			RetryInterval:    10 * time.Second,
+			MaxRetryInterval: time.Hour,
+			Retention:        168 * time.Hour,
+			MaxWatchers:      10,
+		},
+		Deletion:        DeletionConfig{PurgeInterval: time.Hour, PurgeMode: "anonymize"},
+		Metrics:         MetricsConfig{Addr: "localhost:9102", Path: "/metrics"},
+		Tracing:         TracingConfig{Exporter: "none", SampleRatio: 1},
+		Gateway:         GatewayConfig{CORS: CORSConfig{Origins: AppIDs{"http://localhost:5173": 1}}},
+		OAuth:           OAuthConfig{CodeTTL: time.Minute},
+		ShutdownTimeout: 10 * time.Second,
+		WatchInterval:   10 * time.Second,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string // Поля, которые должны быть в ошибке; пустой - ошибки нет
+	}{
+		{
+			name:   "valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name: "all errors reported at once",
+			modify: func(c *Config) {
+				c.Env = "staging"
+				c.StoragePath = ""
+				c.TokenTTL = 0
+			},
+			want: []string{"env: unknown value", "storage_path: is required", "token_ttl: must be positive"},
+		},
+		{
+			name:   "port out of range without listeners",
+			modify: func(c *Config) { c.GRPC.Port = 70000 },
+			want:   []string{"grpc.port"},
+		},
+		{
+			name:   "port not used with listeners",
+			modify: func(c *Config) { c.GRPC.Port = 0; c.GRPC.Listeners = Listeners{{Address: ":44044"}} },
+		},
+		{
+			name: "duplicate listener address",
+			modify: func(c *Config) {
+				c.GRPC.Listeners = Listeners{{Address: ":44044"}, {Address: ":44044", Services: []string{"admin", "billing"}}}
+			},
+			want: []string{"grpc.listeners[1].address: :44044 is used by another listener", `grpc.listeners[1].services: unknown value "billing"`},
+		},
+		{
+			name:   "method timeout name",
+			modify: func(c *Config) { c.GRPC.MethodTimeouts = map[string]time.Duration{"Login": time.Second} },
+			want:   []string{`grpc.method_timeouts: "Login" is not a full method name`},
+		},
+		{
+			name:   "unknown interceptor",
+			modify: func(c *Config) { c.GRPC.Interceptors.Enabled = []string{"auth"} },
+			want:   []string{"grpc.interceptors.enabled"},
+		},
+		{
+			name: "tls client ca required",
+			modify: func(c *Config) {
+				c.GRPC.TLS.CertFile = "/nonexistent/cert.pem"
+				c.GRPC.TLS.ClientAuth = "require"
+			},
+			want: []string{"grpc.tls.key_file: is required", "grpc.tls.client_ca_file: is required", "grpc.tls.cert_file"},
+		},
+		{
+			name:   "insecure cipher suite",
+			modify: func(c *Config) { c.GRPC.TLS.CipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"} },
+			want:   []string{"grpc.tls.cipher_suites"},
+		},
+		{
+			name:   "log file required for file output",
+			modify: func(c *Config) { c.Log.Output = "file" },
+			want:   []string{"log.file: is required"},
+		},
+		{
+			name:   "log level",
+			modify: func(c *Config) { c.Log.Level = "trace" },
+			want:   []string{"log.level"},
+		},
+		{
+			name: "audit sink names",
+			modify: func(c *Config) {
+				c.Audit.Sinks = AuditSinks{{Type: "file", Path: "a.jsonl"}, {Type: "file", Path: "b.jsonl"}, {Name: "Bad Name", Type: "kafka"}}
+			},
+			want: []string{`audit.sinks[1].name: "file" is used by another sink`, "audit.sinks[2].name", "audit.sinks[2].type"},
+		},
+		{
+			name:   "audit webhook secret required",
+			modify: func(c *Config) { c.Audit.Sinks = AuditSinks{{Type: "webhook", URL: "https://siem.example.com/events"}} },
+			want:   []string{"audit.sinks[0].secret: is required"},
+		},
+		{
+			name:   "audit event type pattern",
+			modify: func(c *Config) { c.Audit.Sinks[0].Types = []string{"user.*.failed"} },
+			want:   []string{"audit.sinks[0].types"},
+		},
+		{
+			name:   "audit settings ignored without sinks",
+			modify: func(c *Config) { c.Audit = AuditConfig{} },
+		},
+		{
+			name: "webhook secret and duplicate app",
+			modify: func(c *Config) {
+				c.Events.Webhooks = append(c.Events.Webhooks, WebhookConfig{AppID: 1, URL: "ftp://example.com"})
+			},
+			want: []string{"events.webhooks[1].app_id: app 1 already has a webhook", "events.webhooks[1].secret: is required", "events.webhooks[1].url"},
+		},
+		{
+			name:   "retry interval bounds",
+			modify: func(c *Config) { c.Events.MaxRetryInterval = time.Second },
+			want:   []string{"events.max_retry_interval"},
+		},
+		{
+			name:   "deprecated signing key path conflicts",
+			modify: func(c *Config) { c.DataExport.SigningKeyFile = "a.pem"; c.DataExport.SigningKeyPath = "b.pem" },
+			want:   []string{"data_export.signing_key_path"},
+		},
+		{
+			name:   "tracing endpoint for otlp",
+			modify: func(c *Config) { c.Tracing.Exporter = "otlp"; c.Tracing.SampleRatio = 2 },
+			want:   []string{"tracing.endpoint: is required", "tracing.sample_ratio"},
+		},
+		{
+			name:   "cors origin with path",
+			modify: func(c *Config) { c.Gateway.CORS.Origins = AppIDs{"http://localhost:5173/app": 0} },
+			want:   []string{"is not an origin", "app id must be positive"},
+		},
+		{
+			name:   "oauth code ttl limit",
+			modify: func(c *Config) { c.OAuth.CodeTTL = time.Hour },
+			want:   []string{"oauth.code_ttl"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+
+			err := c.Validate()
+			if len(tt.want) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			lines := strings.Split(err.Error(), "\n")
+			assert.Len(t, lines, len(tt.want), "errors: %v", err)
+			for _, want := range tt.want {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
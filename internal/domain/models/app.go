@@ -2,7 +2,9 @@ package models
 
 // App представляет приложение с его идентификатором, названием и секретным ключом.
 type App struct {
-	ID     int    // Уникальный идентификатор приложения.
-	Name   string // Название приложения.
-	Secret string // Секретный ключ приложения, используемый для токенов аутентификации.
+	ID           int      // Уникальный идентификатор приложения.
+	Name         string   // Название приложения.
+	Secret       string   // Секретный ключ приложения, используемый для токенов аутентификации.
+	RedirectURIs []string // Адреса, на которые OAuth 2.0 возвращает код авторизации; сравниваются точно.
+	PublicClient bool     // Публичный клиент OAuth 2.0: не хранит секрет и при обмене кода защищён только PKCE.
 }
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// AuditEvent - запись журнала аудита. Записи образуют цепочку: Hash каждой записи
+// вычисляется от её полей и PrevHash, поэтому изменение или удаление любой записи
+// нарушает цепочку. Персональные данные в записи не пишутся: пользователи указываются идентификаторами.
+type AuditEvent struct {
+	Seq       int64             // Порядковый номер записи, начиная с 1.
+	Time      time.Time         // Время события.
+	Type      AuditEventType    // Тип события.
+	Outcome   AuditOutcome      // Итог события.
+	ActorID   int64             // Кто выполнил действие, 0 - сам сервис или неизвестный клиент.
+	SubjectID int64             // Над чьей учётной записью выполнено действие, 0 - учётная запись не найдена.
+	AppID     int               // Приложение, 0 - не относится к приложению.
+	RequestID string            // Идентификатор запроса.
+	Details   map[string]string // Подробности события: причина отказа, новое состояние и т.п.
+	PrevHash  string            // Hash предыдущей записи, для первой - AuditGenesisHash.
+	Hash      string            // Хэш записи.
+}
+
+// AuditEventType - тип события аудита.
+type AuditEventType string
+
+const (
+	AuditUserRegister     AuditEventType = "user.register"      // Регистрация.
+	AuditUserLogin        AuditEventType = "user.login"         // Вход, в том числе неудачный.
+	AuditUserStatusChange AuditEventType = "user.status_change" // Смена состояния учётной записи администратором.
+	AuditUserDelete       AuditEventType = "user.delete"        // Мягкое удаление учётной записи.
+	AuditUserRestore      AuditEventType = "user.restore"       // Восстановление удалённой учётной записи.
+	AuditUserPurge        AuditEventType = "user.purge"         // Очистка персональных данных после льготного периода.
+	AuditAdminCheck       AuditEventType = "admin.check"        // Проверка прав администратора, в том числе отказ.
+	AuditOAuthAuthorize   AuditEventType = "oauth.authorize"    // Выдача кода авторизации OAuth 2.0 или отказ пользователя.
+	AuditOAuthToken       AuditEventType = "oauth.token"        // Отказ в обмене кода авторизации на токен.
+)
+
+// AuditOutcome - итог события аудита.
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success" // Действие выполнено.
+	AuditFailure AuditOutcome = "failure" // Действие отклонено или завершилось ошибкой.
+)
+
+// AuditGenesisHash - PrevHash первой записи журнала.
+var AuditGenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// AuditFilter отбирает записи журнала аудита. Нулевые поля не ограничивают выборку.
+type AuditFilter struct {
+	Types     []AuditEventType // Любой из типов.
+	Outcome   AuditOutcome     // Итог события.
+	ActorID   int64            // Кто выполнил действие.
+	SubjectID int64            // Над чьей учётной записью.
+	Since     time.Time        // Не раньше этого момента.
+	Until     time.Time        // Раньше этого момента.
+	AfterSeq  int64            // Записи с номером больше AfterSeq: курсор следующей страницы.
+	Limit     int              // Сколько записей вернуть.
+}
+
+// ComputeHash вычисляет хэш записи от PrevHash и остальных полей, кроме Hash.
+// Время учитывается с точностью до микросекунд в UTC: так оно хранится в базе.
+func (e AuditEvent) ComputeHash() string {
+	details := e.Details
+	if len(details) == 0 {
+		details = nil // Пустые и отсутствующие подробности хэшируются одинаково
+	}
+	payload, _ := json.Marshal(struct { // Поля структуры кодируются в фиксированном порядке, ключи Details - по алфавиту
+		Seq       int64             `json:"seq"`
+		Time      string            `json:"time"`
+		Type      AuditEventType    `json:"type"`
+		Outcome   AuditOutcome      `json:"outcome"`
+		ActorID   int64             `json:"actor_id"`
+		SubjectID int64             `json:"subject_id"`
+		AppID     int               `json:"app_id"`
+		RequestID string            `json:"request_id"`
+		Details   map[string]string `json:"details"`
+	}{
+		Seq:       e.Seq,
+		Time:      e.Time.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		Type:      e.Type,
+		Outcome:   e.Outcome,
+		ActorID:   e.ActorID,
+		SubjectID: e.SubjectID,
+		AppID:     e.AppID,
+		RequestID: e.RequestID,
+		Details:   details,
+	})
+	sum := sha256.Sum256(append([]byte(e.PrevHash+"\n"), payload...))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// DomainEvent - событие предметной области, о котором сообщается другим сервисам.
+// Записывается в outbox в одной транзакции с изменением состояния, поэтому публикуется,
+// только если изменение зафиксировано, и не теряется при сбое после фиксации.
+type DomainEvent struct {
+	ID        int64             // Номер события в outbox; получатель по нему отбрасывает повторы.
+	Type      EventType         // Тип события.
+	UserID    int64             // Пользователь, с которым произошло событие, 0 - не относится к пользователю.
+	AppID     int               // Приложение, 0 - событие для всех приложений.
+	Data      map[string]string // Подробности события: email, новое состояние, роль и т.п.
+	CreatedAt time.Time         // Время изменения состояния.
+}
+
+// EventType - тип события предметной области.
+type EventType string
+
+const (
+	EventUserRegistered    EventType = "user.registered"       // Создан пользователь: регистрация, импорт или фикстуры.
+	EventUserLoggedIn      EventType = "user.logged_in"        // Открыт сеанс; только для приложения входа.
+	EventPasswordChanged   EventType = "user.password_changed" // Пароль заменён другим. Перехэширование того же пароля событием не считается.
+	EventUserStatusChanged EventType = "user.status_changed"   // Состояние учётной записи изменено, например заблокирована.
+	EventUserDeleted       EventType = "user.deleted"          // Учётная запись мягко удалена.
+	EventUserRestored      EventType = "user.restored"         // Удалённая учётная запись восстановлена.
+	EventUserPurged        EventType = "user.purged"           // Персональные данные очищены.
+	EventRoleGranted       EventType = "role.granted"          // Пользователю назначена роль.
+)
+
+// WebhookDelivery - доставка события на вебхук одного приложения.
+type WebhookDelivery struct {
+	ID            int64          // Номер доставки.
+	Event         DomainEvent    // Доставляемое событие.
+	AppID         int            // Приложение-получатель.
+	Status        DeliveryStatus // Состояние доставки.
+	Attempts      int            // Сколько попыток сделано.
+	NextAttemptAt time.Time      // Когда следующая попытка.
+	LastError     string         // Ошибка последней попытки.
+	UpdatedAt     time.Time      // Время последнего изменения.
+}
+
+// DeliveryStatus - состояние доставки события.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"   // Ожидает отправки или повтора.
+	DeliveryDelivered DeliveryStatus = "delivered" // Получатель ответил 2xx.
+	DeliveryDead      DeliveryStatus = "dead"      // Попытки исчерпаны; доставка в списке недоставленных.
+)
+
+// DeliveryFilter - условия выборки недоставленных событий. Нулевые поля не ограничивают выборку.
+type DeliveryFilter struct {
+	AppID   int   // Приложение-получатель.
+	AfterID int64 // Курсор: доставки с номером больше AfterID.
+	Limit   int   // Размер страницы.
+}
+
+// EventFilter - условия подписки на события outbox. Нулевые поля не ограничивают выборку.
+type EventFilter struct {
+	Types   []string // Типы событий; "user.*" - все типы с префиксом "user.".
+	AppID   int      // События приложения и события, не привязанные к приложению, как для его вебхука.
+	AfterID int64    // Курсор: события с номером больше AfterID; 0 - с самого раннего хранящегося.
+}
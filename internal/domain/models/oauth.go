@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AuthCode - код авторизации OAuth 2.0, выданный приложению после входа пользователя.
+// Код одноразовый и обменивается на токен только с верификатором PKCE.
+type AuthCode struct {
+	CodeHash      string    // SHA-256 кода; сам код не хранится.
+	AppID         int       // Приложение, которому выдан код.
+	UserID        int64     // Пользователь, разрешивший доступ.
+	RedirectURI   string    // Адрес возврата из запроса авторизации; при обмене должен совпасть.
+	CodeChallenge string    // code_challenge PKCE (S256).
+	CreatedAt     time.Time // Время выдачи.
+	ExpiresAt     time.Time // После этого времени код не обменивается.
+	UsedAt        time.Time // Время обмена, нулевое - код не использован.
+	SessionID     string    // Сеанс, открытый по коду.
+}
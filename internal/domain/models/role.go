@@ -0,0 +1,8 @@
+package models
+
+// Role представляет роль, которую можно назначить пользователю.
+type Role struct {
+	ID          int64  // Уникальный идентификатор роли.
+	Name        string // Уникальное имя роли.
+	Description string // Описание назначения роли.
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Session представляет сеанс пользователя, открытый успешным входом.
+// Идентификатор сеанса записывается в токен (claim jti), что позволяет отзывать токены.
+type Session struct {
+	ID        string    // Уникальный идентификатор сеанса.
+	UserID    int64     // Идентификатор пользователя.
+	AppID     int       // Идентификатор приложения, для которого выдан токен.
+	CreatedAt time.Time // Время входа.
+	ExpiresAt time.Time // Время истечения токена.
+	RevokedAt time.Time // Время отзыва, нулевое - сеанс не отозван.
+}
+
+// Revoked сообщает, отозван ли сеанс.
+func (s Session) Revoked() bool {
+	return !s.RevokedAt.IsZero()
+}
@@ -1,7 +1,55 @@
 package models
 
+import "time"
+
 type User struct {
-	ID       int64  // Уникальный идентификатор пользователя.
-	Email    string // Электронная почта пользователя.
-	PassHash []byte // Хэш пароля пользователя.
+	ID          int64      // Уникальный идентификатор пользователя.
+	Email       string     // Электронная почта пользователя.
+	PassHash    []byte     // Хэш пароля пользователя.
+	PassAlgo    string     // Алгоритм, которым получен PassHash (см. пакет passhash).
+	IsAdmin     bool       // Является ли пользователь администратором.
+	Status      UserStatus // Состояние учётной записи.
+	CreatedAt   time.Time  // Время регистрации.
+	UpdatedAt   time.Time  // Время последнего изменения учётной записи.
+	LastLoginAt time.Time  // Время последнего успешного входа, нулевое - входа не было.
+	DeletedAt   time.Time  // Время мягкого удаления, нулевое - учётная запись не удалена.
+}
+
+// UserStatus описывает состояние жизненного цикла учётной записи.
+type UserStatus string
+
+const (
+	UserPendingVerification UserStatus = "pending_verification" // Учётная запись ждёт подтверждения.
+	UserActive              UserStatus = "active"               // Учётная запись активна.
+	UserSuspended           UserStatus = "suspended"            // Учётная запись приостановлена администратором.
+	UserLocked              UserStatus = "locked"               // Учётная запись заблокирована, например после подбора пароля.
+	UserDeleted             UserStatus = "deleted"              // Учётная запись удалена.
+)
+
+// userTransitions перечисляет допустимые переходы между состояниями.
+var userTransitions = map[UserStatus][]UserStatus{
+	UserPendingVerification: {UserActive, UserDeleted},
+	UserActive:              {UserSuspended, UserLocked, UserDeleted},
+	UserSuspended:           {UserActive, UserDeleted},
+	UserLocked:              {UserActive, UserDeleted},
+	UserDeleted:             {UserActive}, // Восстановление в течение льготного периода
+}
+
+// Valid сообщает, является ли значение известным состоянием.
+func (s UserStatus) Valid() bool {
+	switch s {
+	case UserPendingVerification, UserActive, UserSuspended, UserLocked, UserDeleted:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo сообщает, допустим ли переход из состояния s в состояние to.
+func (s UserStatus) CanTransitionTo(to UserStatus) bool {
+	for _, allowed := range userTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
 }
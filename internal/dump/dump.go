@@ -0,0 +1,309 @@
+package dump
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/passhash"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"io"
+	"time"
+)
+
+const (
+	Format  = "sso-export" // Имя формата выгрузки
+	Version = 2            // Текущая версия формата; версия 1 не содержит состояния пользователей
+)
+
+// Типы записей выгрузки
+const (
+	kindHeader = "header"
+	kindApp    = "app"
+	kindRole   = "role"
+	kindUser   = "user"
+)
+
+// StatDeletedUsers - число удалённых пользователей, не попавших в выгрузку
+const StatDeletedUsers = "deleted_user"
+
+var (
+	ErrMissingSecret = errors.New("record has no secret and does not exist yet") // Новая запись в выгрузке без секретов
+	ErrInvalidStatus = errors.New("invalid user status")                         // Состояние пользователя нельзя импортировать
+)
+
+// record - одна строка JSONL выгрузки
+type record struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Header - первая строка выгрузки
+type Header struct {
+	Format         string    `json:"format"`
+	Version        int       `json:"version"`
+	ExportedAt     time.Time `json:"exported_at"`
+	IncludeSecrets bool      `json:"include_secrets"`
+}
+
+type appRecord struct {
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	Secret       string   `json:"secret,omitempty"`
+	RedirectURIs []string `json:"redirect_uris"` // Нет в выгрузках, сделанных до появления OAuth: тогда адреса не меняются
+	PublicClient *bool    `json:"public_client"` // Нет в выгрузках, сделанных до появления флага: тогда он не меняется
+}
+
+type roleRecord struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type userRecord struct {
+	Email    string   `json:"email"`
+	PassHash []byte   `json:"pass_hash,omitempty"`
+	PassAlgo string   `json:"pass_algo,omitempty"`
+	IsAdmin  bool     `json:"is_admin"`
+	Status   string   `json:"status,omitempty"` // Нет в выгрузках версии 1: тогда состояние существующего пользователя не меняется
+	Roles    []string `json:"roles,omitempty"`
+}
+
+// Source - хранилище, из которого читается выгрузка
+type Source interface {
+	Apps(ctx context.Context) ([]models.App, error)
+	Roles(ctx context.Context) ([]models.Role, error)
+	ForEachUser(ctx context.Context, fn func(user models.User, roles []string) error) error
+}
+
+// Sink - хранилище, в которое загружается выгрузка
+type Sink interface {
+	App(ctx context.Context, appID int) (models.App, error)
+	UpsertApp(ctx context.Context, app models.App) (storage.UpsertResult, error)
+	UpsertRole(ctx context.Context, role models.Role) (storage.UpsertResult, error)
+	User(ctx context.Context, email string) (models.User, error)
+	UpsertUser(ctx context.Context, email string, passHash []byte, passAlgo string, isAdmin bool) (int64, storage.UpsertResult, error)
+	SetUserStatus(ctx context.Context, userID int64, from, to models.UserStatus) error
+	GrantRole(ctx context.Context, userID int64, roleName string) (storage.UpsertResult, error)
+}
+
+// Stats - количество записей по типам
+type Stats map[string]int
+
+// Export записывает в w приложения, роли и пользователей в формате JSONL.
+// Секреты приложений и хэши паролей попадают в выгрузку только при includeSecrets.
+// Удалённые пользователи не выгружаются: их данные ждут очистки и не должны пережить её в резервных копиях;
+// их число возвращается в Stats под ключом StatDeletedUsers.
+func Export(ctx context.Context, src Source, w io.Writer, includeSecrets bool) (Stats, error) {
+	const op = "dump.Export"
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	stats := Stats{}
+
+	write := func(kind string, data any) error {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		stats[kind]++
+		return enc.Encode(record{Kind: kind, Data: raw})
+	}
+
+	if err := write(kindHeader, Header{
+		Format:         Format,
+		Version:        Version,
+		ExportedAt:     time.Now().UTC(),
+		IncludeSecrets: includeSecrets,
+	}); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	delete(stats, kindHeader)
+
+	apps, err := src.Apps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, app := range apps {
+		rec := appRecord{ID: app.ID, Name: app.Name, RedirectURIs: app.RedirectURIs, PublicClient: &app.PublicClient}
+		if rec.RedirectURIs == nil {
+			rec.RedirectURIs = []string{}
+		}
+		if includeSecrets {
+			rec.Secret = app.Secret
+		}
+		if err := write(kindApp, rec); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	roles, err := src.Roles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, role := range roles {
+		if err := write(kindRole, roleRecord{Name: role.Name, Description: role.Description}); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = src.ForEachUser(ctx, func(user models.User, roles []string) error {
+		if !user.DeletedAt.IsZero() || user.Status == models.UserDeleted {
+			stats[StatDeletedUsers]++
+			return nil
+		}
+		rec := userRecord{Email: user.Email, IsAdmin: user.IsAdmin, Status: string(user.Status), Roles: roles}
+		if includeSecrets {
+			rec.PassHash, rec.PassAlgo = user.PassHash, user.PassAlgo
+		}
+		return write(kindUser, rec)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return stats, nil
+}
+
+// Import идемпотентно загружает выгрузку из r.
+// Записи без секретов обновляют только существующие приложения и пользователей.
+func Import(ctx context.Context, dst Sink, r io.Reader) (Stats, error) {
+	const op = "dump.Import"
+
+	dec := json.NewDecoder(r)
+	stats := Stats{}
+
+	var header Header
+	if err := readRecord(dec, kindHeader, &header); err != nil {
+		return nil, fmt.Errorf("%s: header: %w", op, err)
+	}
+	if header.Format != Format || header.Version < 1 || header.Version > Version {
+		return nil, fmt.Errorf("%s: unsupported format %q version %d", op, header.Format, header.Version)
+	}
+
+	for line := 2; ; line++ {
+		var rec record
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return stats, nil
+			}
+			return nil, fmt.Errorf("%s: line %d: %w", op, line, err)
+		}
+		if err := importRecord(ctx, dst, rec); err != nil {
+			return nil, fmt.Errorf("%s: line %d (%s): %w", op, line, rec.Kind, err)
+		}
+		stats[rec.Kind]++
+	}
+}
+
+// importRecord загружает одну запись выгрузки
+func importRecord(ctx context.Context, dst Sink, rec record) error {
+	switch rec.Kind {
+	case kindApp:
+		var app appRecord
+		if err := json.Unmarshal(rec.Data, &app); err != nil {
+			return err
+		}
+		if app.Secret == "" || app.RedirectURIs == nil || app.PublicClient == nil {
+			// Без секрета можно только переименовать существующее приложение
+			current, err := dst.App(ctx, app.ID)
+			switch {
+			case errors.Is(err, storage.ErrAppNotFound) && app.Secret == "":
+				return ErrMissingSecret
+			case errors.Is(err, storage.ErrAppNotFound): // Новое приложение из старой выгрузки - без адресов возврата
+			case err != nil:
+				return err
+			}
+			if app.Secret == "" {
+				app.Secret = current.Secret
+			}
+			if app.RedirectURIs == nil {
+				app.RedirectURIs = current.RedirectURIs
+			}
+			if app.PublicClient == nil {
+				app.PublicClient = &current.PublicClient
+			}
+		}
+		_, err := dst.UpsertApp(ctx, models.App{
+			ID:           app.ID,
+			Name:         app.Name,
+			Secret:       app.Secret,
+			RedirectURIs: app.RedirectURIs,
+			PublicClient: *app.PublicClient,
+		})
+		return err
+
+	case kindRole:
+		var role roleRecord
+		if err := json.Unmarshal(rec.Data, &role); err != nil {
+			return err
+		}
+		_, err := dst.UpsertRole(ctx, models.Role{Name: role.Name, Description: role.Description})
+		return err
+
+	case kindUser:
+		var user userRecord
+		if err := json.Unmarshal(rec.Data, &user); err != nil {
+			return err
+		}
+		if len(user.PassHash) == 0 {
+			// Без хэша пароля можно только обновить существующего пользователя
+			if _, err := dst.User(ctx, user.Email); err != nil {
+				if errors.Is(err, storage.ErrUserNotFound) {
+					return ErrMissingSecret
+				}
+				return err
+			}
+		}
+		status := models.UserStatus(user.Status)
+		if user.Status != "" && (!status.Valid() || status == models.UserDeleted) {
+			return fmt.Errorf("%w %q", ErrInvalidStatus, user.Status)
+		}
+		if user.PassAlgo == "" {
+			user.PassAlgo = passhash.Bcrypt
+		}
+		id, _, err := dst.UpsertUser(ctx, user.Email, user.PassHash, user.PassAlgo, user.IsAdmin)
+		if err != nil {
+			return err
+		}
+		if status != "" {
+			// Новый пользователь создаётся активным: без восстановления состояния
+			// приостановленные и заблокированные учётные записи снова открылись бы для входа
+			current, err := dst.User(ctx, user.Email)
+			if err != nil {
+				return err
+			}
+			// Удалённую учётную запись импорт не восстанавливает: для этого есть RestoreAccount
+			if current.Status != status && current.Status != models.UserDeleted {
+				if err := dst.SetUserStatus(ctx, id, current.Status, status); err != nil {
+					return err
+				}
+			}
+		}
+		for _, role := range user.Roles {
+			if _, err := dst.GrantRole(ctx, id, role); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown record kind %q", rec.Kind)
+	}
+}
+
+// readRecord читает запись ожидаемого типа
+func readRecord(dec *json.Decoder, kind string, v any) error {
+	var rec record
+	if err := dec.Decode(&rec); err != nil {
+		return err
+	}
+	if rec.Kind != kind {
+		return fmt.Errorf("expected %q record, got %q", kind, rec.Kind)
+	}
+	return json.Unmarshal(rec.Data, v)
+}
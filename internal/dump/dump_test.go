@@ -0,0 +1,175 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const bcryptHash = "$2a$10$BP6w4VsYR1ytYwx2q8UypunWx8aIYCY4NKgkzvvg4enK9tEiQyMPq"
+
+// newStorage создаёт базу во временном каталоге и применяет к ней миграции из migrations
+func newStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sso.db")
+	st, err := sqlite.NewStorage(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = st.Close() })
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "migrations", "*.up.sql"))
+	require.NoError(t, err)
+	version := func(file string) int {
+		n, err := strconv.Atoi(strings.SplitN(filepath.Base(file), "_", 2)[0])
+		require.NoError(t, err)
+		return n
+	}
+	sort.Slice(files, func(i, j int) bool { return version(files[i]) < version(files[j]) })
+
+	// Отдельное соединение: Storage не выполняет произвольный SQL. Драйвер регистрирует пакет sqlite
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	for _, file := range files {
+		query, err := os.ReadFile(file)
+		require.NoError(t, err)
+		_, err = db.Exec(string(query))
+		require.NoError(t, err, file)
+	}
+	return st
+}
+
+// fill наполняет базу приложениями, ролью и пользователями в разных состояниях
+func fill(t *testing.T, st *sqlite.Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := st.UpsertApp(ctx, models.App{ID: 10, Name: "web", Secret: "web-secret", RedirectURIs: []string{"https://web.example.com/cb"}, PublicClient: true})
+	require.NoError(t, err)
+	_, err = st.UpsertApp(ctx, models.App{ID: 11, Name: "backend", Secret: "backend-secret"})
+	require.NoError(t, err)
+	_, err = st.UpsertRole(ctx, models.Role{Name: "support", Description: "Поддержка"})
+	require.NoError(t, err)
+
+	id, _, err := st.UpsertUser(ctx, "admin@example.com", []byte(bcryptHash), "bcrypt", true)
+	require.NoError(t, err)
+	_, err = st.GrantRole(ctx, id, "support")
+	require.NoError(t, err)
+
+	id, _, err = st.UpsertUser(ctx, "suspended@example.com", []byte("$6$saltstring$hash"), "sha512-crypt", false)
+	require.NoError(t, err)
+	require.NoError(t, st.SetUserStatus(ctx, id, models.UserActive, models.UserSuspended))
+}
+
+// export выгружает базу и возвращает строки без заголовка: в нём время выгрузки
+func export(t *testing.T, st *sqlite.Storage, includeSecrets bool) (string, []string) {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := Export(context.Background(), st, &buf, includeSecrets)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	return buf.String(), lines[1:]
+}
+
+// importDump загружает выгрузку в одной транзакции, как ssoctl import
+func importDump(st *sqlite.Storage, data string) (Stats, error) {
+	var stats Stats
+	err := st.WithTx(context.Background(), func(tx *sqlite.Storage) error {
+		var err error
+		stats, err = Import(context.Background(), tx, strings.NewReader(data))
+		return err
+	})
+	return stats, err
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	src := newStorage(t)
+	fill(t, src)
+	data, want := export(t, src, true)
+
+	dst := newStorage(t)
+	stats, err := importDump(dst, data)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats[kindApp])
+	assert.Equal(t, 2, stats[kindUser])
+
+	_, got := export(t, dst, true)
+	assert.Equal(t, want, got)
+
+	// Повторная загрузка ничего не меняет
+	_, err = importDump(dst, data)
+	require.NoError(t, err)
+	_, got = export(t, dst, true)
+	assert.Equal(t, want, got)
+
+	user, err := dst.User(context.Background(), "suspended@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, models.UserSuspended, user.Status)
+	assert.Equal(t, "sha512-crypt", user.PassAlgo)
+}
+
+func TestExport_WithoutSecrets(t *testing.T) {
+	src := newStorage(t)
+	fill(t, src)
+	data, lines := export(t, src, false)
+	assert.NotContains(t, data, "web-secret")
+	assert.NotContains(t, data, "pass_hash")
+
+	var header Header
+	require.NoError(t, readRecord(json.NewDecoder(strings.NewReader(data)), kindHeader, &header))
+	assert.False(t, header.IncludeSecrets)
+	assert.NotEmpty(t, lines)
+
+	// В пустую базу выгрузку без секретов не загрузить: новым записям нужен секрет
+	_, err := importDump(newStorage(t), data)
+	assert.ErrorIs(t, err, ErrMissingSecret)
+
+	// В базу, где записи уже есть, загрузка сохраняет их секреты
+	_, err = importDump(src, data)
+	require.NoError(t, err)
+	app, err := src.App(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, "web-secret", app.Secret)
+	user, err := src.User(context.Background(), "admin@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, bcryptHash, string(user.PassHash))
+}
+
+func TestImport_Header_FailCases(t *testing.T) {
+	header := func(format string, version int) string {
+		raw, _ := json.Marshal(Header{Format: format, Version: version})
+		rec, _ := json.Marshal(record{Kind: kindHeader, Data: raw})
+		return string(rec) + "\n"
+	}
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "newer version", data: header(Format, Version+1), wantErr: "unsupported format"},
+		{name: "zero version", data: header(Format, 0), wantErr: "unsupported format"},
+		{name: "other format", data: header("other", Version), wantErr: "unsupported format"},
+		{name: "no header", data: `{"kind":"role","data":{"name":"support"}}` + "\n", wantErr: `expected "header" record`},
+		{name: "not json", data: "not json\n", wantErr: "header"},
+		{name: "empty", data: "", wantErr: "EOF"},
+		{name: "unknown record", data: header(Format, Version) + `{"kind":"secret","data":{}}` + "\n", wantErr: `unknown record kind "secret"`},
+		{name: "truncated record", data: header(Format, Version) + `{"kind":"role","data":{"name":`, wantErr: "line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Import(context.Background(), newStorage(t), strings.NewReader(tt.data))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"github.com/linemk/gRPC_auth/internal/domain/models"       // Модели предметной области
+	"github.com/linemk/gRPC_auth/internal/grpc/chunk"          // Отправка данных потоком сообщений
+	"github.com/linemk/gRPC_auth/internal/grpc/grpcerr"        // Преобразование ошибок в статусы gRPC
+	"github.com/linemk/gRPC_auth/internal/lib/actor"           // Администратор, выполняющий запрос
+	"github.com/linemk/gRPC_auth/internal/lib/bearer"          // Извлечение токена из метаданных
+	"github.com/linemk/gRPC_auth/internal/services/auth"       // Ошибки сервиса авторизации
+	"github.com/linemk/gRPC_auth/internal/services/dataexport" // Выгрузка персональных данных
+	"github.com/linemk/gRPC_auth/internal/services/events"     // Подписка на события предметной области
+	"github.com/linemk/gRPC_auth/internal/services/userimport" // Импорт пользователей
+	"github.com/linemk/gRPC_auth/internal/storage"             // Ошибки хранилища
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"             // Сгенерированные protobuf файлы
+	"google.golang.org/grpc"                                   // gRPC библиотека
+	"google.golang.org/grpc/codes"                             // Коды статусов gRPC
+	"google.golang.org/grpc/status"                            // Статусы gRPC
+	"io"
+	"strings"
+	"time"
+)
+
+// Authorizer проверяет, что вызывающий является администратором
+type Authorizer interface {
+	AuthorizeAdmin(ctx context.Context, token string) (userID int64, err error)
+}
+
+// UserManager управляет учётными записями пользователей
+type UserManager interface {
+	SetUserStatus(ctx context.Context, userID int64, to models.UserStatus) (models.User, error)
+	RestoreAccount(ctx context.Context, userID int64) (models.User, error)
+}
+
+// Importer импортирует пользователей построчно
+type Importer interface {
+	Import(ctx context.Context, row userimport.Row, p *userimport.Progress) error
+}
+
+// DataExporter выгружает персональные данные пользователя
+type DataExporter interface {
+	Export(ctx context.Context, userID int64, w io.Writer) error
+}
+
+// AuditLog читает журнал аудита
+type AuditLog interface {
+	Query(ctx context.Context, filter models.AuditFilter) (events []models.AuditEvent, nextAfterSeq int64, err error)
+}
+
+// DeadLetters читает события, которые не удалось доставить на вебхуки
+type DeadLetters interface {
+	DeadLetters(ctx context.Context, filter models.DeliveryFilter) (deliveries []models.WebhookDelivery, nextAfterID int64, err error)
+}
+
+// EventWatcher передаёт события предметной области из outbox по мере записи
+type EventWatcher interface {
+	Watch(ctx context.Context, filter models.EventFilter, send func(events []models.DomainEvent, cursor int64) error) error
+}
+
+const (
+	emptyValue    = 0   // Константа для обозначения пустого значения
+	progressEvery = 100 // Как часто сервер отправляет прогресс импорта
+)
+
+// ServerApi реализует административный gRPC API
+type ServerApi struct {
+	ssov1.UnimplementedAdminServer              // Встраиваем несгенерированные методы сервера
+	authz                          Authorizer   // Проверка прав администратора
+	users                          UserManager  // Управление учётными записями
+	importer                       Importer     // Импорт пользователей
+	exporter                       DataExporter // Выгрузка персональных данных
+	audit                          AuditLog     // Журнал аудита
+	deadLetters                    DeadLetters  // Недоставленные события вебхуков
+	watcher                        EventWatcher // Подписка на события
+}
+
+// Register регистрирует административный сервис на gRPC сервере
+func Register(gRPC *grpc.Server, authz Authorizer, users UserManager, importer Importer, exporter DataExporter, audit AuditLog, deadLetters DeadLetters, watcher EventWatcher) {
+	ssov1.RegisterAdminServer(gRPC, &ServerApi{
+		authz:       authz,
+		users:       users,
+		importer:    importer,
+		exporter:    exporter,
+		audit:       audit,
+		deadLetters: deadLetters,
+		watcher:     watcher,
+	})
+}
+
+// SetUserStatus переводит учётную запись в новое состояние жизненного цикла
+func (s *ServerApi) SetUserStatus(ctx context.Context, req *ssov1.SetUserStatusRequest) (*ssov1.SetUserStatusResponse, error) {
+	ctx, err := s.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetUserId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "UserId is required")
+	}
+
+	user, err := s.users.SetUserStatus(ctx, req.GetUserId(), models.UserStatus(req.GetStatus()))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidStatus) {
+			return nil, status.Error(codes.InvalidArgument, "unknown status")
+		}
+		return nil, userStatusError(err)
+	}
+
+	return &ssov1.SetUserStatusResponse{
+		UserId: user.ID,
+		Status: string(user.Status),
+	}, nil
+}
+
+// RestoreAccount восстанавливает удалённую учётную запись, пока не истёк льготный период
+func (s *ServerApi) RestoreAccount(ctx context.Context, req *ssov1.RestoreAccountRequest) (*ssov1.RestoreAccountResponse, error) {
+	ctx, err := s.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetUserId() == emptyValue {
+		return nil, status.Error(codes.InvalidArgument, "UserId is required")
+	}
+
+	user, err := s.users.RestoreAccount(ctx, req.GetUserId())
+	if err != nil {
+		return nil, userStatusError(err)
+	}
+
+	return &ssov1.RestoreAccountResponse{
+		UserId: user.ID,
+		Status: string(user.Status),
+	}, nil
+}
+
+// ExportUserData выгружает персональные данные пользователя в виде подписанного архива
+func (s *ServerApi) ExportUserData(req *ssov1.ExportUserDataRequest, stream ssov1.Admin_ExportUserDataServer) error {
+	ctx, err := s.requireAdmin(stream.Context())
+	if err != nil {
+		return err
+	}
+	if req.GetUserId() == emptyValue {
+		return status.Error(codes.InvalidArgument, "UserId is required")
+	}
+
+	w := chunk.NewWriter(func(data []byte) error {
+		return stream.Send(&ssov1.DataChunk{Data: data})
+	})
+	if err := s.exporter.Export(ctx, req.GetUserId(), w); err != nil {
+		if errors.Is(err, dataexport.ErrUserNotFound) { // Пользователь не найден до отправки первых данных
+			return status.Error(codes.NotFound, "user not found")
+		}
+		return status.Error(codes.Internal, "internal server error")
+	}
+	return w.Flush()
+}
+
+// QueryAuditLog возвращает записи журнала аудита по фильтрам в порядке номеров.
+// Следующая страница запрашивается с after_seq из ответа; 0 в next_after_seq - записей больше нет.
+func (s *ServerApi) QueryAuditLog(ctx context.Context, req *ssov1.QueryAuditLogRequest) (*ssov1.QueryAuditLogResponse, error) {
+	ctx, err := s.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetLimit() < 0 || req.GetAfterSeq() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and after_seq must not be negative")
+	}
+	if req.GetSince() != emptyValue && req.GetUntil() != emptyValue && req.GetSince() >= req.GetUntil() {
+		return nil, status.Error(codes.InvalidArgument, "since must be before until")
+	}
+
+	filter := models.AuditFilter{
+		Outcome:   models.AuditOutcome(req.GetOutcome()),
+		ActorID:   req.GetActorId(),
+		SubjectID: req.GetSubjectId(),
+		AfterSeq:  req.GetAfterSeq(),
+		Limit:     int(req.GetLimit()),
+	}
+	for _, t := range req.GetTypes() {
+		filter.Types = append(filter.Types, models.AuditEventType(t))
+	}
+	if req.GetSince() != emptyValue {
+		filter.Since = time.Unix(req.GetSince(), 0)
+	}
+	if req.GetUntil() != emptyValue {
+		filter.Until = time.Unix(req.GetUntil(), 0)
+	}
+
+	events, next, err := s.audit.Query(ctx, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	resp := &ssov1.QueryAuditLogResponse{Events: make([]*ssov1.AuditEvent, 0, len(events)), NextAfterSeq: next}
+	for _, e := range events {
+		resp.Events = append(resp.Events, &ssov1.AuditEvent{
+			Seq:       e.Seq,
+			Time:      e.Time.Unix(),
+			Type:      string(e.Type),
+			Outcome:   string(e.Outcome),
+			ActorId:   e.ActorID,
+			SubjectId: e.SubjectID,
+			AppId:     int64(e.AppID),
+			RequestId: e.RequestID,
+			Details:   e.Details,
+			PrevHash:  e.PrevHash,
+			Hash:      e.Hash,
+		})
+	}
+	return resp, nil
+}
+
+// ListDeadLetters возвращает события, которые не удалось доставить на вебхуки приложений за все попытки,
+// в порядке номеров доставок. Следующая страница запрашивается с after_id из ответа; 0 в next_after_id - записей больше нет.
+func (s *ServerApi) ListDeadLetters(ctx context.Context, req *ssov1.ListDeadLettersRequest) (*ssov1.ListDeadLettersResponse, error) {
+	ctx, err := s.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetLimit() < 0 || req.GetAfterId() < 0 || req.GetAppId() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id, after_id and limit must not be negative")
+	}
+
+	deliveries, next, err := s.deadLetters.DeadLetters(ctx, models.DeliveryFilter{
+		AppID:   int(req.GetAppId()),
+		AfterID: req.GetAfterId(),
+		Limit:   int(req.GetLimit()),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	resp := &ssov1.ListDeadLettersResponse{DeadLetters: make([]*ssov1.DeadLetter, 0, len(deliveries)), NextAfterId: next}
+	for _, d := range deliveries {
+		resp.DeadLetters = append(resp.DeadLetters, &ssov1.DeadLetter{
+			DeliveryId: d.ID,
+			EventId:    d.Event.ID,
+			EventType:  string(d.Event.Type),
+			UserId:     d.Event.UserID,
+			AppId:      int64(d.AppID),
+			Data:       d.Event.Data,
+			OccurredAt: d.Event.CreatedAt.Unix(),
+			Attempts:   int32(d.Attempts),
+			LastError:  d.LastError,
+			FailedAt:   d.UpdatedAt.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+// WatchEvents передаёт события предметной области в порядке записи, пока клиент не отключится.
+// Каждое событие приходит с курсором - своим номером; сообщение без события только сдвигает курсор
+// за события, не прошедшие фильтр. После обрыва клиент переподключается с after_id, равным последнему
+// полученному курсору, и не теряет событий. OUT_OF_RANGE - события после курсора удалены по сроку
+// хранения, подписку нужно начать заново с after_id = 0.
+func (s *ServerApi) WatchEvents(req *ssov1.WatchEventsRequest, stream ssov1.Admin_WatchEventsServer) error {
+	ctx, err := s.requireAdmin(stream.Context())
+	if err != nil {
+		return err
+	}
+	if req.GetAppId() < 0 || req.GetAfterId() < 0 {
+		return status.Error(codes.InvalidArgument, "app_id and after_id must not be negative")
+	}
+	for _, t := range req.GetTypes() {
+		if t == "" || strings.Contains(strings.TrimSuffix(t, "*"), "*") {
+			return status.Errorf(codes.InvalidArgument, "%q is not an event type, want a type like user.registered or a prefix like user.*", t)
+		}
+	}
+
+	var sendErr error // Ошибка отправки клиенту возвращается как есть
+	err = s.watcher.Watch(ctx, models.EventFilter{
+		Types:   req.GetTypes(),
+		AppID:   int(req.GetAppId()),
+		AfterID: req.GetAfterId(),
+	}, func(batch []models.DomainEvent, cursor int64) error {
+		for _, e := range batch {
+			sendErr = stream.Send(&ssov1.WatchEventsResponse{
+				Event: &ssov1.Event{
+					Id:         e.ID,
+					Type:       string(e.Type),
+					UserId:     e.UserID,
+					AppId:      int64(e.AppID),
+					Data:       e.Data,
+					OccurredAt: e.CreatedAt.Unix(),
+				},
+				Cursor: e.ID,
+			})
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+		if len(batch) == 0 || batch[len(batch)-1].ID < cursor {
+			sendErr = stream.Send(&ssov1.WatchEventsResponse{Cursor: cursor})
+		}
+		return sendErr
+	})
+	switch {
+	case sendErr != nil:
+		return sendErr
+	case errors.Is(err, events.ErrCursorExpired):
+		return status.Error(codes.OutOfRange, "events after cursor have been pruned, restart with after_id = 0")
+	case errors.Is(err, events.ErrTooManyWatchers):
+		return status.Error(codes.ResourceExhausted, "too many event watchers")
+	case errors.Is(err, events.ErrWatcherStopped):
+		return status.Error(codes.Unavailable, "server is shutting down, reconnect with the last cursor")
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// userStatusError переводит ошибку смены состояния учётной записи в статус gRPC
+func userStatusError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, auth.ErrInvalidTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, auth.ErrGracePeriodOver):
+		return status.Error(codes.FailedPrecondition, "deletion grace period is over")
+	case errors.Is(err, storage.ErrStatusChanged):
+		return status.Error(codes.Aborted, "status changed concurrently, retry")
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// ImportUsers принимает поток пользователей и периодически отправляет прогресс.
+// Checkpoint в ответе - номер последней обработанной строки: после обрыва
+// клиент продолжает импорт со следующей строки.
+func (s *ServerApi) ImportUsers(stream ssov1.Admin_ImportUsersServer) error {
+	ctx, err := s.requireAdmin(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	var (
+		p      userimport.Progress
+		errs   []*ssov1.ImportRowError // Ошибки строк с момента последней отправки
+		report = func(done bool) error {
+			msg := &ssov1.ImportUsersProgress{
+				Processed:  p.Processed,
+				Imported:   p.Imported,
+				Skipped:    p.Skipped,
+				Failed:     p.Failed,
+				Checkpoint: p.Checkpoint,
+				Errors:     errs,
+				Done:       done,
+			}
+			errs = nil
+			return stream.Send(msg)
+		}
+	)
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return report(true) // Клиент закончил передачу
+		}
+		if err != nil {
+			return err
+		}
+
+		row := userimport.Row{
+			Line:         req.GetRow(),
+			Email:        req.GetEmail(),
+			PasswordHash: req.GetPasswordHash(),
+			Algorithm:    req.GetAlgorithm(),
+			IsAdmin:      req.GetIsAdmin(),
+		}
+		if err := s.importer.Import(ctx, row, &p); err != nil {
+			var rowErr *userimport.RowError
+			if !errors.As(err, &rowErr) {
+				return status.Error(codes.Internal, "internal server error")
+			}
+			errs = append(errs, &ssov1.ImportRowError{Row: rowErr.Line, Email: rowErr.Email, Message: rowErr.Err.Error()})
+		}
+
+		if p.Processed%progressEvery == 0 {
+			if err := report(false); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// requireAdmin проверяет токен администратора из метаданных запроса и возвращает контекст
+// с идентификатором администратора для журнала аудита
+func (s *ServerApi) requireAdmin(ctx context.Context) (context.Context, error) {
+	token, ok := bearer.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "bearer token is required")
+	}
+	adminID, err := s.authz.AuthorizeAdmin(ctx, token)
+	if err != nil {
+		if st, ok := grpcerr.AccountStatus(err); ok { // Учётная запись администратора неактивна
+			return ctx, st
+		}
+		switch {
+		case errors.Is(err, auth.ErrInvalidToken):
+			return ctx, status.Error(codes.Unauthenticated, "invalid token")
+		case errors.Is(err, auth.ErrNotAdmin):
+			return ctx, status.Error(codes.PermissionDenied, "admin role required")
+		}
+		return ctx, status.Error(codes.Internal, "internal server error")
+	}
+	return actor.NewContext(ctx, adminID), nil
+}
@@ -3,12 +3,21 @@ package auth
 import (
 	"context"
 	"errors"
+	"github.com/linemk/gRPC_auth/internal/domain/models" // Импортируем модели предметной области
+	"github.com/linemk/gRPC_auth/internal/grpc/chunk"    // Импортируем отправку данных потоком сообщений
+	"github.com/linemk/gRPC_auth/internal/grpc/grpcerr"  // Импортируем преобразование ошибок в статусы gRPC
+	"github.com/linemk/gRPC_auth/internal/lib/actor"     // Импортируем пользователя, выполняющего запрос
+	"github.com/linemk/gRPC_auth/internal/lib/bearer"    // Импортируем извлечение токена из метаданных
+	"github.com/linemk/gRPC_auth/internal/lib/clientapp" // Импортируем приложение из клиентского сертификата
+	"github.com/linemk/gRPC_auth/internal/lib/jwt"       // Импортируем данные проверенного токена
 	"github.com/linemk/gRPC_auth/internal/services/auth" // Импортируем сервисы для авторизации
 	"github.com/linemk/gRPC_auth/internal/storage"       // Импортируем хранилище
 	ssov1 "github.com/linemk/proto_buf/gen/go/sso"       // Импортируем сгенерированные protobuf файлы
 	"google.golang.org/grpc"                             // Импортируем gRPC библиотеку
 	"google.golang.org/grpc/codes"                       // Импортируем коды статусов gRPC
 	"google.golang.org/grpc/status"                      // Импортируем статус gRPC
+	"io"                                                 // Импортируем интерфейсы ввода-вывода
+	"time"                                               // Импортируем пакет для работы со временем
 )
 
 // Интерфейс для работы с авторизацией
@@ -19,17 +28,30 @@ type Auth interface {
 	RegisterNewUser(ctx context.Context, email string, password string) (userID int64, err error)
 	// Метод проверки, является ли пользователь администратором
 	IsAdmin(ctx context.Context, userID int64) (bool, error)
+	// Метод проверки токена, выпущенного сервисом
+	VerifyToken(ctx context.Context, token string) (jwt.Claims, error)
+	// Метод мягкого удаления учётной записи с отзывом сеансов
+	DeleteAccount(ctx context.Context, userID int64) (models.User, error)
+	// Метод расчёта момента очистки удалённой учётной записи
+	PurgeAfter(user models.User) time.Time
+}
+
+// Интерфейс для выгрузки персональных данных пользователя
+type DataExporter interface {
+	// Метод записи подписанного архива с данными пользователя
+	Export(ctx context.Context, userID int64, w io.Writer) error
 }
 
 // gRPC сервер для работы с API авторизации
 type ServerApi struct {
-	ssov1.UnimplementedAuthServer      // Встраиваем несгенерированные методы сервера
-	auth                          Auth // Включаем интерфейс для авторизации
+	ssov1.UnimplementedAuthServer              // Встраиваем несгенерированные методы сервера
+	auth                          Auth         // Включаем интерфейс для авторизации
+	exporter                      DataExporter // Включаем интерфейс для выгрузки персональных данных
 }
 
-// Регистрируем сервис авторизации на gRPC сервере
-func Register(gRPC *grpc.Server, auth Auth) {
-	ssov1.RegisterAuthServer(gRPC, &ServerApi{auth: auth}) // Регистрируем AuthServer на gRPC
+// Регистрируем сервис авторизации на gRPC сервере или HTTP шлюзе
+func Register(gRPC grpc.ServiceRegistrar, auth Auth, exporter DataExporter) {
+	ssov1.RegisterAuthServer(gRPC, &ServerApi{auth: auth, exporter: exporter}) // Регистрируем AuthServer на gRPC
 }
 
 const (
@@ -41,11 +63,18 @@ func (s *ServerApi) Login(ctx context.Context, req *ssov1.LoginRequest) (*ssov1.
 	if err := validateLogin(req); err != nil { // Валидируем запрос
 		return nil, err // Возвращаем ошибку, если валидация не прошла
 	}
-	token, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword(), int(req.GetAppId())) // Пытаемся залогинить пользователя
+	appID, err := loginAppID(ctx, req) // Определяем приложение по запросу или по сертификату сервиса
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword(), appID) // Пытаемся залогинить пользователя
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidCredentials) { // Проверяем, является ли ошибка ошибкой неверных данных
 			return nil, status.Error(codes.Unauthenticated, err.Error()) // Возвращаем ошибку авторизации
 		}
+		if st, ok := grpcerr.AccountStatus(err); ok { // Проверяем, не заблокирована ли учётная запись
+			return nil, st // Возвращаем код, соответствующий состоянию учётной записи
+		}
 		return nil, status.Error(codes.Internal, "internal server error") // Возвращаем внутреннюю ошибку
 	}
 
@@ -92,19 +121,101 @@ func (s *ServerApi) IsAdmin(ctx context.Context, req *ssov1.IsAdminRequest) (*ss
 	}, nil
 }
 
+// Метод удаления учётной записи владельцем токена
+func (s *ServerApi) DeleteAccount(ctx context.Context, req *ssov1.DeleteAccountRequest) (*ssov1.DeleteAccountResponse, error) {
+	claims, err := s.authenticate(ctx) // Удалить можно только собственную учётную запись
+	if err != nil {
+		return nil, err
+	}
+	ctx = actor.NewContext(ctx, claims.UserID) // Удаление записывается в журнал аудита от имени владельца
+
+	user, err := s.auth.DeleteAccount(ctx, claims.UserID) // Помечаем учётную запись удалённой и отзываем сеансы
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, auth.ErrInvalidTransition):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		case errors.Is(err, storage.ErrStatusChanged):
+			return nil, status.Error(codes.Aborted, "status changed concurrently, retry")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	return &ssov1.DeleteAccountResponse{
+		UserId:     user.ID,                        // Возвращаем идентификатор удалённого пользователя
+		DeletedAt:  user.DeletedAt.Unix(),          // Возвращаем время удаления
+		PurgeAfter: s.auth.PurgeAfter(user).Unix(), // Возвращаем момент, до которого возможно восстановление
+	}, nil
+}
+
+// Метод выгрузки персональных данных владельца токена в виде подписанного архива
+func (s *ServerApi) ExportMyData(req *ssov1.ExportMyDataRequest, stream ssov1.Auth_ExportMyDataServer) error {
+	ctx := stream.Context()
+	claims, err := s.authenticate(ctx) // Выгрузить можно только собственные данные
+	if err != nil {
+		return err
+	}
+
+	w := chunk.NewWriter(func(data []byte) error { // Архив отправляется частями по мере чтения из хранилища
+		return stream.Send(&ssov1.DataChunk{Data: data})
+	})
+	if err := s.exporter.Export(ctx, claims.UserID, w); err != nil {
+		return status.Error(codes.Internal, "internal server error") // Возвращаем внутреннюю ошибку
+	}
+	return w.Flush() // Отправляем остаток архива
+}
+
+// authenticate проверяет токен из метаданных и возвращает данные его владельца
+func (s *ServerApi) authenticate(ctx context.Context) (jwt.Claims, error) {
+	token, ok := bearer.FromIncomingContext(ctx)
+	if !ok {
+		return jwt.Claims{}, status.Error(codes.Unauthenticated, "bearer token is required")
+	}
+	claims, err := s.auth.VerifyToken(ctx, token) // Проверяем токен и состояние учётной записи
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return jwt.Claims{}, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if st, ok := grpcerr.AccountStatus(err); ok {
+			return jwt.Claims{}, st
+		}
+		return jwt.Claims{}, status.Error(codes.Internal, "internal server error")
+	}
+	return claims, nil
+}
+
 // Валидатор для входа пользователя
 func validateLogin(req *ssov1.LoginRequest) error {
 	if req.GetEmail() == "" || req.GetPassword() == "" { // Проверяем, заполнены ли email и пароль
 		return status.Error(codes.InvalidArgument, "Email or password is required") // Возвращаем ошибку, если они пусты
 	}
 
-	if req.GetAppId() == emptyValue { // Проверяем, заполнен ли AppId
-		return status.Error(codes.InvalidArgument, "AppId is required") // Возвращаем ошибку, если он пуст
-	}
-
 	return nil // Возвращаем nil при успешной валидации
 }
 
+// loginAppID возвращает приложение для входа. Сервис, предъявивший сопоставленный приложению
+// клиентский сертификат, может не передавать AppId, но не может войти в чужое приложение.
+// Запрос браузера с Origin, сопоставленного приложению, тоже не может войти в чужое приложение,
+// но AppId обязан передать: Origin не подтверждает приложение.
+func loginAppID(ctx context.Context, req *ssov1.LoginRequest) (int, error) {
+	if originAppID, ok := clientapp.OriginFromContext(ctx); ok && req.GetAppId() != emptyValue && int(req.GetAppId()) != originAppID {
+		return 0, status.Error(codes.PermissionDenied, "AppId does not match request origin")
+	}
+	certAppID, ok := clientapp.FromContext(ctx)
+	switch {
+	case !ok && req.GetAppId() == emptyValue: // Проверяем, заполнен ли AppId
+		return 0, status.Error(codes.InvalidArgument, "AppId is required")
+	case !ok:
+		return int(req.GetAppId()), nil
+	case req.GetAppId() == emptyValue:
+		return certAppID, nil
+	case int(req.GetAppId()) != certAppID:
+		return 0, status.Error(codes.PermissionDenied, "AppId does not match client certificate")
+	}
+	return certAppID, nil
+}
+
 // Валидатор для регистрации нового пользователя
 func validateRegister(req *ssov1.RegisterRequest) error {
 	if req.GetEmail() == "" || req.GetPassword() == "" { // Проверяем, заполнены ли email и пароль
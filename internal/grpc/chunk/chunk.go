@@ -0,0 +1,32 @@
+package chunk
+
+import (
+	"bufio"
+)
+
+// Size - размер одного сообщения потока с данными
+const Size = 32 << 10
+
+// sender передаёт данные одним сообщением потока
+type sender func(data []byte) error
+
+// Write отправляет p сообщениями не больше Size
+func (s sender) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), Size)
+		// gRPC может удерживать срез до отправки, поэтому передаётся копия
+		if err := s(append([]byte(nil), p[:n]...)); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// NewWriter возвращает буферизованный writer, отправляющий данные сообщениями не больше Size.
+// После записи нужно вызвать Flush, чтобы отправить остаток.
+func NewWriter(send func(data []byte) error) *bufio.Writer {
+	return bufio.NewWriterSize(sender(send), Size)
+}
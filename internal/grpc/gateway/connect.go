@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// connectError - ошибка в формате протокола Connect
+type connectError struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message,omitempty"`
+	Details []connectDetail `json:"details,omitempty"`
+}
+
+// connectDetail - подробность ошибки: полное имя типа и сообщение в base64 без выравнивания
+type connectDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// newConnectError переводит статус gRPC в ошибку Connect
+func newConnectError(st *status.Status) connectError {
+	e := connectError{Code: snake(st.Code().String()), Message: st.Message()}
+	for _, d := range st.Proto().GetDetails() {
+		e.Details = append(e.Details, connectDetail{
+			Type:  d.GetTypeUrl()[strings.LastIndex(d.GetTypeUrl(), "/")+1:],
+			Value: base64.RawStdEncoding.EncodeToString(d.GetValue()),
+		})
+	}
+	return e
+}
+
+// unaryExchange - unary метод по протоколу Connect: тело запроса и ответа - сообщение без конверта,
+// ошибка передаётся JSON с кодом HTTP, соответствующим коду gRPC, трейлеры - заголовками Trailer-*
+type unaryExchange struct {
+	w           http.ResponseWriter
+	req         *http.Request
+	ts          *transportStream
+	codec       codec
+	contentType string
+	sent        bool // Ответ уже отправлен
+}
+
+// connectUnaryExchange создаёт обмен Connect для unary метода с кодированием сообщений c
+func connectUnaryExchange(c codec) newExchange {
+	return func(w http.ResponseWriter, req *http.Request, ts *transportStream, _ route) exchange {
+		return &unaryExchange{w: w, req: req, ts: ts, codec: c, contentType: req.Header.Get("Content-Type")}
+	}
+}
+
+func (e *unaryExchange) recv(m proto.Message) error {
+	if enc := e.req.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return status.Errorf(codes.Unimplemented, "content encoding %q is not supported", enc)
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(nil, e.req.Body, maxBodySize))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "failed to read request message")
+	}
+	if len(body) == 0 { // Пустое тело означает сообщение без полей и для JSON
+		return nil
+	}
+	if err := e.codec.Unmarshal(body, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request message: %v", err)
+	}
+	return nil
+}
+
+func (e *unaryExchange) send(m proto.Message) error {
+	data, err := e.codec.Marshal(m)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to encode response")
+	}
+	e.sent = true
+	e.writeMetadata()
+	e.w.Header().Set("Content-Type", e.contentType)
+	e.w.WriteHeader(http.StatusOK)
+	_, _ = e.w.Write(data)
+	return nil
+}
+
+func (e *unaryExchange) finish(err error) {
+	if err == nil || e.sent {
+		return
+	}
+	st := status.Convert(err)
+	data, mErr := json.Marshal(newConnectError(st))
+	if mErr != nil {
+		data = []byte(`{"code":"internal","message":"internal server error"}`)
+	}
+	e.writeMetadata()
+	e.w.Header().Set("Content-Type", "application/json")
+	e.w.WriteHeader(HTTPStatus(st.Code()))
+	_, _ = e.w.Write(data)
+}
+
+// writeMetadata переносит заголовки вызова в заголовки ответа, а трейлеры - в заголовки с префиксом Trailer-
+func (e *unaryExchange) writeMetadata() {
+	header, trailer := e.ts.metadata()
+	setHeaders(e.w.Header(), header)
+	for key, values := range trailer {
+		for _, v := range values {
+			e.w.Header().Add("Trailer-"+key, v)
+		}
+	}
+}
+
+// streamExchange - потоковый метод по протоколу Connect: сообщения в конвертах,
+// ошибка и трейлеры передаются последним конвертом с флагом конца потока
+type streamExchange struct {
+	w           http.ResponseWriter
+	body        io.Reader
+	ts          *transportStream
+	codec       codec
+	contentType string
+	started     bool // Заголовки ответа уже отправлены
+}
+
+// connectStreamExchange создаёт обмен Connect для потокового метода с кодированием сообщений c
+func connectStreamExchange(c codec) newExchange {
+	return func(w http.ResponseWriter, req *http.Request, ts *transportStream, _ route) exchange {
+		return &streamExchange{
+			w:           w,
+			body:        http.MaxBytesReader(w, req.Body, envelopeHeaderSize+maxBodySize),
+			ts:          ts,
+			codec:       c,
+			contentType: req.Header.Get("Content-Type"),
+		}
+	}
+}
+
+func (e *streamExchange) recv(m proto.Message) error {
+	return recvEnvelope(e.body, e.codec, m)
+}
+
+func (e *streamExchange) send(m proto.Message) error {
+	data, err := e.codec.Marshal(m)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to encode response")
+	}
+	return e.write(envelope(0, data))
+}
+
+// finish отправляет конверт конца потока с ошибкой и трейлерами
+func (e *streamExchange) finish(err error) {
+	var end struct {
+		Error    *connectError `json:"error,omitempty"`
+		Metadata metadata.MD   `json:"metadata,omitempty"`
+	}
+	if err != nil {
+		ce := newConnectError(status.Convert(err))
+		end.Error = &ce
+	}
+	_, end.Metadata = e.ts.metadata()
+	data, mErr := json.Marshal(end)
+	if mErr != nil {
+		data = []byte(`{"error":{"code":"internal","message":"internal server error"}}`)
+	}
+	_ = e.write(envelope(flagEndStream, data))
+}
+
+// write отправляет конверт, при первом вызове - вместе с заголовками ответа
+func (e *streamExchange) write(frame []byte) error {
+	if !e.started {
+		e.started = true
+		header, _ := e.ts.metadata()
+		setHeaders(e.w.Header(), header)
+		e.w.Header().Set("Content-Type", e.contentType)
+		e.w.WriteHeader(http.StatusOK)
+	}
+	return write(e.w, frame)
+}
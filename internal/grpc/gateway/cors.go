@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strconv"
+)
+
+// withCORS разрешает запросы браузерных приложений с Origin из конфигурации и отвечает на предварительные запросы.
+// Запросы с другим Origin отклоняются: без этого страница чужого сайта могла бы вызывать методы,
+// не требующие предварительного запроса, даже не имея доступа к ответу.
+func (g *Gateway) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if origin == "" { // Не браузер или запрос со своего адреса
+			next.ServeHTTP(w, req)
+			return
+		}
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if _, ok := g.cors.Origins[origin]; !ok {
+			writeError(w, status.Errorf(codes.PermissionDenied, "origin %s is not allowed", origin))
+			return
+		}
+		h.Set("Access-Control-Allow-Origin", origin)
+
+		if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", "GET, POST")
+			if headers := req.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers) // Origin уже проверен, поэтому разрешаются любые заголовки
+			}
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(g.cors.MaxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.Set("Access-Control-Expose-Headers", "*") // grpc-status, grpc-message и метаданные ответа
+		next.ServeHTTP(w, req)
+	})
+}
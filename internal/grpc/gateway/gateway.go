@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/lib/clientapp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxBodySize ограничивает тело запроса: сообщения API небольшие
+const maxBodySize = 1 << 20
+
+// OpenAPIPath - путь, по которому отдаётся описание API
+const OpenAPIPath = "/v1/openapi.json"
+
+// Gateway отдаёт unary и серверные потоковые методы зарегистрированных gRPC сервисов по HTTP:
+// как JSON API, по протоколу gRPC-Web и по протоколу Connect.
+// Вызовы выполняются в процессе через те же перехватчики, что и у gRPC сервера,
+// поэтому журнал, метрики, идентификатор запроса и проверка токенов работают одинаково.
+//
+// Метод Login сервиса auth.Auth доступен как POST /v1/auth/login в формате JSON
+// и как POST /auth.Auth/Login для клиентов gRPC-Web и Connect.
+// Методы с потоком от клиента не поддерживаются.
+type Gateway struct {
+	log    *slog.Logger
+	unary  grpc.UnaryServerInterceptor  // Общая цепочка unary перехватчиков
+	stream grpc.StreamServerInterceptor // Общая цепочка stream перехватчиков
+	cors   CORS
+	mux    *http.ServeMux
+	routes []route // Зарегистрированные методы для описания OpenAPI
+}
+
+// CORS описывает браузерные приложения, которым разрешены запросы к шлюзу
+type CORS struct {
+	Origins map[string]int // Origin браузерного приложения -> идентификатор приложения
+	MaxAge  time.Duration  // Сколько браузер может хранить ответ на предварительный запрос
+}
+
+// route описывает метод gRPC, доступный по HTTP
+type route struct {
+	path       string             // Путь JSON API
+	service    string             // Полное имя сервиса, например auth.Auth
+	method     string             // Имя метода, например Login
+	streaming  bool               // Поток сообщений от сервера
+	fullMethod string             // Имя метода в формате gRPC: /auth.Auth/Login, он же путь gRPC-Web и Connect
+	impl       any                // Реализация сервиса
+	unary      grpc.MethodHandler // Обработчик unary метода
+	handler    grpc.StreamHandler // Обработчик потокового метода
+}
+
+// exchange - обмен сообщениями одного вызова в формате протокола шлюза
+type exchange interface {
+	recv(m proto.Message) error // Читает сообщение запроса
+	send(m proto.Message) error // Отправляет сообщение ответа
+	finish(err error)           // Завершает ответ: передаёт ошибку вызова или признак успешного конца
+}
+
+// newExchange создаёт обмен сообщениями для запроса
+type newExchange func(w http.ResponseWriter, req *http.Request, ts *transportStream, r route) exchange
+
+// New создаёт шлюз с общими перехватчиками gRPC сервера
+func New(log *slog.Logger, unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor, cors CORS) *Gateway {
+	return &Gateway{
+		log:    log,
+		unary:  unary,
+		stream: stream,
+		cors:   cors,
+		mux:    http.NewServeMux(),
+	}
+}
+
+// RegisterService реализует grpc.ServiceRegistrar: каждый метод сервиса получает путь JSON API
+// POST /v1/<сервис>/<метод> и путь gRPC-Web и Connect POST /<полное имя сервиса>/<метод>
+func (g *Gateway) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	for _, m := range desc.Methods {
+		g.addRoute(route{service: desc.ServiceName, method: m.MethodName, impl: impl, unary: m.Handler})
+	}
+	for _, s := range desc.Streams {
+		if s.ClientStreams {
+			g.log.Warn("client streaming method is not exposed over http",
+				slog.String("method", "/"+desc.ServiceName+"/"+s.StreamName))
+			continue
+		}
+		g.addRoute(route{service: desc.ServiceName, method: s.StreamName, streaming: true, impl: impl, handler: s.Handler})
+	}
+}
+
+// Handler возвращает обработчик HTTP со всеми зарегистрированными методами и описанием OpenAPI
+func (g *Gateway) Handler() (http.Handler, error) {
+	const op = "gateway.Handler"
+
+	doc, err := g.OpenAPI()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	g.mux.HandleFunc("GET "+OpenAPIPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(doc)
+	})
+	g.mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, status.Errorf(codes.Unimplemented, "unknown path %s %s", r.Method, r.URL.Path))
+	}))
+	return g.withCORS(g.mux), nil
+}
+
+// addRoute запоминает метод и регистрирует его пути
+func (g *Gateway) addRoute(r route) {
+	short := r.service[strings.LastIndex(r.service, ".")+1:]
+	r.path = "/v1/" + strings.ToLower(short) + "/" + kebab(r.method)
+	r.fullMethod = "/" + r.service + "/" + r.method
+	g.routes = append(g.routes, r)
+
+	g.mux.HandleFunc("POST "+r.path, func(w http.ResponseWriter, req *http.Request) {
+		g.serve(w, req, r, newJSONExchange)
+	})
+	g.mux.HandleFunc("POST "+r.fullMethod, func(w http.ResponseWriter, req *http.Request) {
+		newEx, ok := rpcExchange(req, r)
+		if !ok {
+			http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		g.serve(w, req, r, newEx)
+	})
+}
+
+// serve выполняет вызов метода r и передаёт результат в формате протокола
+func (g *Gateway) serve(w http.ResponseWriter, req *http.Request, r route, newEx newExchange) {
+	ctx, ts, end := g.callContext(req, r)
+	ex := newEx(w, req, ts, r)
+	err := g.call(ctx, req, r, ts, ex)
+	ex.finish(err)
+	end(err)
+}
+
+// call вызывает обработчик метода через общую цепочку перехватчиков
+func (g *Gateway) call(ctx context.Context, req *http.Request, r route, ts *transportStream, ex exchange) error {
+	timeout, err := requestTimeout(req)
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	recv := func(m any) error { return ex.recv(m.(proto.Message)) }
+	if !r.streaming {
+		resp, err := r.unary(r.impl, ctx, recv, g.unary)
+		if err != nil {
+			return err
+		}
+		return ex.send(resp.(proto.Message))
+	}
+	ss := &serverStream{ctx: ctx, ts: ts, recv: recv, send: ex.send}
+	return g.stream(r.impl, ss, &grpc.StreamServerInfo{
+		FullMethod:     r.fullMethod,
+		IsServerStream: true,
+	}, r.handler)
+}
+
+// callContext готовит контекст вызова; результат end завершает спан вызова.
+// Запрос с разрешённого Origin может войти только в приложение, которому сопоставлен этот Origin;
+// Origin не подтверждает приложение, поэтому кладётся в контекст отдельно от клиентского сертификата.
+func (g *Gateway) callContext(req *http.Request, r route) (context.Context, *transportStream, func(error)) {
+	ts := &transportStream{method: r.fullMethod}
+	ctx := grpc.NewContextWithServerTransportStream(incomingContext(req), ts)
+	if appID, ok := g.cors.Origins[req.Header.Get("Origin")]; ok {
+		ctx = clientapp.NewOriginContext(ctx, appID)
+	}
+	ctx, end := startSpan(ctx, req, r)
+	return ctx, ts, end
+}
+
+// kebab переводит имя метода в сегмент пути: DeleteAccount -> delete-account
+func kebab(name string) string {
+	return splitWords(name, '-')
+}
+
+// snake переводит имя кода в формат Connect: InvalidArgument -> invalid_argument
+func snake(name string) string {
+	return splitWords(name, '_')
+}
+
+// splitWords разделяет слова имени в стиле CamelCase разделителем sep и приводит к нижнему регистру
+func splitWords(name string, sep byte) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte(sep)
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
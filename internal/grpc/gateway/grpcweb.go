@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// webExchange - протокол gRPC-Web: сообщения в конвертах, статус вызова передаётся
+// последним конвертом с трейлерами. В режиме -text конверты закодированы в base64.
+type webExchange struct {
+	w           http.ResponseWriter
+	body        io.Reader
+	ts          *transportStream
+	codec       codec
+	text        bool
+	contentType string
+	started     bool // Заголовки ответа уже отправлены
+}
+
+// grpcWebExchange создаёт обмен gRPC-Web с кодированием сообщений c
+func grpcWebExchange(c codec, text bool) newExchange {
+	return func(w http.ResponseWriter, req *http.Request, ts *transportStream, _ route) exchange {
+		e := &webExchange{
+			w:           w,
+			body:        http.MaxBytesReader(w, req.Body, envelopeHeaderSize+maxBodySize),
+			ts:          ts,
+			codec:       c,
+			text:        text,
+			contentType: req.Header.Get("Content-Type"),
+		}
+		if text {
+			e.body = base64.NewDecoder(base64.StdEncoding, e.body)
+		}
+		return e
+	}
+}
+
+func (e *webExchange) recv(m proto.Message) error {
+	return recvEnvelope(e.body, e.codec, m)
+}
+
+func (e *webExchange) send(m proto.Message) error {
+	data, err := e.codec.Marshal(m)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to encode response")
+	}
+	return e.write(envelope(0, data))
+}
+
+// finish отправляет трейлеры с кодом и сообщением статуса
+func (e *webExchange) finish(err error) {
+	st := status.Convert(err)
+	_, trailer := e.ts.metadata()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "grpc-status: %d\r\n", st.Code())
+	if st.Message() != "" {
+		fmt.Fprintf(&b, "grpc-message: %s\r\n", encodeMessage(st.Message()))
+	}
+	for key, values := range trailer {
+		for _, v := range values {
+			fmt.Fprintf(&b, "%s: %s\r\n", key, v)
+		}
+	}
+	_ = e.write(envelope(flagTrailer, []byte(b.String())))
+}
+
+// write отправляет конверт, при первом вызове - вместе с заголовками ответа
+func (e *webExchange) write(frame []byte) error {
+	if !e.started {
+		e.started = true
+		header, _ := e.ts.metadata()
+		setHeaders(e.w.Header(), header)
+		e.w.Header().Set("Content-Type", e.contentType)
+		e.w.WriteHeader(http.StatusOK)
+	}
+	if e.text {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	return write(e.w, frame)
+}
+
+// encodeMessage кодирует сообщение статуса для grpc-message: непечатные символы и % передаются как %XX
+func encodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"encoding/json"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// statusSchema - имя схемы ошибки google.rpc.Status
+const statusSchema = "google.rpc.Status"
+
+// OpenAPI строит описание OpenAPI 3.0 зарегистрированных методов по дескрипторам protobuf.
+// Описание совпадает с кодом, поэтому новые методы попадают в него без ручной правки.
+func (g *Gateway) OpenAPI() ([]byte, error) {
+	schemas := map[string]any{
+		statusSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code":    map[string]any{"type": "integer", "format": "int32"},
+				"message": map[string]any{"type": "string"},
+				"details": map[string]any{"type": "array", "items": map[string]any{"type": "object"}},
+			},
+		},
+	}
+	paths := map[string]any{}
+	for _, r := range g.routes {
+		in, out := methodMessages(r)
+		response := map[string]any{"description": "OK"}
+		if r.streaming {
+			response["content"] = map[string]any{
+				"application/x-ndjson": map[string]any{"schema": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"result": schemaRef(out, schemas), "error": ref(statusSchema)},
+				}},
+			}
+		} else {
+			response["content"] = map[string]any{"application/json": map[string]any{"schema": schemaRef(out, schemas)}}
+		}
+		paths[r.path] = map[string]any{
+			"post": map[string]any{
+				"operationId": r.service + "." + r.method,
+				"tags":        []string{r.service},
+				"requestBody": map[string]any{
+					"content": map[string]any{"application/json": map[string]any{"schema": schemaRef(in, schemas)}},
+				},
+				"responses": map[string]any{
+					"200": response,
+					"default": map[string]any{
+						"description": "Ошибка: HTTP статус соответствует коду gRPC",
+						"content":     map[string]any{"application/json": map[string]any{"schema": ref(statusSchema)}},
+					},
+				},
+			},
+		}
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "SSO HTTP API",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}, "", "  ")
+}
+
+// methodMessages находит дескрипторы сообщений запроса и ответа метода; nil, если сервис не зарегистрирован в protoregistry
+func methodMessages(r route) (in, out protoreflect.MessageDescriptor) {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(r.service))
+	if err != nil {
+		return nil, nil
+	}
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, nil
+	}
+	method := service.Methods().ByName(protoreflect.Name(r.method))
+	if method == nil {
+		return nil, nil
+	}
+	return method.Input(), method.Output()
+}
+
+// schemaRef добавляет схему сообщения в schemas и возвращает ссылку на неё
+func schemaRef(md protoreflect.MessageDescriptor, schemas map[string]any) map[string]any {
+	if md == nil {
+		return map[string]any{"type": "object"}
+	}
+	if schema, ok := wellKnown(md); ok {
+		return schema
+	}
+	name := string(md.FullName())
+	if _, ok := schemas[name]; ok {
+		return ref(name)
+	}
+	properties := map[string]any{}
+	schemas[name] = map[string]any{"type": "object", "properties": properties} // До полей: сообщение может ссылаться на себя
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		properties[f.JSONName()] = fieldSchema(f, schemas)
+	}
+	return ref(name)
+}
+
+// fieldSchema описывает поле так, как его кодирует protojson
+func fieldSchema(f protoreflect.FieldDescriptor, schemas map[string]any) map[string]any {
+	switch {
+	case f.IsMap():
+		return map[string]any{"type": "object", "additionalProperties": valueSchema(f.MapValue(), schemas)}
+	case f.IsList():
+		return map[string]any{"type": "array", "items": valueSchema(f, schemas)}
+	}
+	return valueSchema(f, schemas)
+}
+
+// valueSchema описывает одно значение поля
+func valueSchema(f protoreflect.FieldDescriptor, schemas map[string]any) map[string]any {
+	switch f.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"} // protojson кодирует 64-битные числа строками
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "string", "format": "uint64"}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := f.Enum().Values()
+		names := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return schemaRef(f.Message(), schemas)
+	}
+	return map[string]any{"type": "string"}
+}
+
+// wellKnown описывает стандартные типы, у которых в protojson особое представление
+func wellKnown(md protoreflect.MessageDescriptor) (map[string]any, bool) {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return map[string]any{"type": "string", "format": "date-time"}, true
+	case "google.protobuf.Duration":
+		return map[string]any{"type": "string", "example": "1.5s"}, true
+	case "google.protobuf.Empty", "google.protobuf.Struct", "google.protobuf.Any":
+		return map[string]any{"type": "object"}, true
+	}
+	return nil, false
+}
+
+// ref возвращает ссылку на схему из components
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
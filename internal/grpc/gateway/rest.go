@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+)
+
+var (
+	marshaler   = protojson.MarshalOptions{EmitUnpopulated: true}
+	unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// jsonExchange - JSON API: тело запроса - JSON сообщения запроса, ответ unary метода - JSON сообщения ответа.
+// Метод с потоком от сервера отвечает строками {"result": ...} в формате NDJSON,
+// ошибка после начала ответа передаётся последней строкой {"error": ...}.
+type jsonExchange struct {
+	w         http.ResponseWriter
+	req       *http.Request
+	ts        *transportStream
+	streaming bool
+	started   bool // Заголовки ответа уже отправлены
+}
+
+func newJSONExchange(w http.ResponseWriter, req *http.Request, ts *transportStream, r route) exchange {
+	return &jsonExchange{w: w, req: req, ts: ts, streaming: r.streaming}
+}
+
+// recv читает тело запроса в сообщение; пустое тело означает сообщение без полей
+func (e *jsonExchange) recv(m proto.Message) error {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, e.req.Body, maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return status.Error(codes.InvalidArgument, "request body is too large")
+		}
+		return status.Error(codes.InvalidArgument, "failed to read request body")
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	if err := unmarshaler.Unmarshal(body, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+func (e *jsonExchange) send(m proto.Message) error {
+	if e.streaming {
+		return e.writeLine("result", m)
+	}
+	data, err := marshaler.Marshal(m)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to encode response")
+	}
+	e.started = true
+	header, trailer := e.ts.metadata()
+	setHeaders(e.w.Header(), header, trailer)
+	e.w.Header().Set("Content-Type", "application/json")
+	e.w.WriteHeader(http.StatusOK)
+	_, _ = e.w.Write(data)
+	return nil
+}
+
+func (e *jsonExchange) finish(err error) {
+	switch {
+	case err != nil && !e.started:
+		header, trailer := e.ts.metadata()
+		setHeaders(e.w.Header(), header, trailer)
+		writeError(e.w, err)
+	case err != nil:
+		_ = e.writeLine("error", status.Convert(err).Proto())
+	case !e.started: // Поток без сообщений
+		e.start()
+	}
+}
+
+// start отправляет заголовки ответа потока
+func (e *jsonExchange) start() {
+	e.started = true
+	header, _ := e.ts.metadata()
+	setHeaders(e.w.Header(), header)
+	e.w.Header().Set("Content-Type", "application/x-ndjson")
+	e.w.WriteHeader(http.StatusOK)
+}
+
+// writeLine записывает строку NDJSON с сообщением m в поле key и сразу отправляет её клиенту
+func (e *jsonExchange) writeLine(key string, m proto.Message) error {
+	data, err := marshaler.Marshal(m)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to encode response")
+	}
+	line, err := json.Marshal(map[string]json.RawMessage{key: data})
+	if err != nil {
+		return status.Error(codes.Internal, "failed to encode response")
+	}
+	if !e.started {
+		e.start()
+	}
+	return write(e.w, append(line, '\n'))
+}
+
+// writeError записывает статус gRPC как google.rpc.Status в JSON с соответствующим кодом HTTP
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	data, mErr := marshaler.Marshal(st.Proto())
+	if mErr != nil {
+		data = []byte(`{"code":13,"message":"internal server error"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatus(st.Code()))
+	_, _ = w.Write(data)
+}
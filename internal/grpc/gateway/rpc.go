@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Флаги конверта сообщения gRPC-Web и Connect
+const (
+	flagCompressed = 0x01 // Сообщение сжато
+	flagEndStream  = 0x02 // Connect: последний конверт с ошибкой и трейлерами
+	flagTrailer    = 0x80 // gRPC-Web: конверт с трейлерами
+)
+
+// envelopeHeaderSize - флаги и длина сообщения перед каждым сообщением
+const envelopeHeaderSize = 5
+
+// codec кодирует сообщения в формате, указанном в Content-Type
+type codec interface {
+	Marshal(m proto.Message) ([]byte, error)
+	Unmarshal(data []byte, m proto.Message) error
+}
+
+type protoCodec struct{}
+
+func (protoCodec) Marshal(m proto.Message) ([]byte, error)      { return proto.Marshal(m) }
+func (protoCodec) Unmarshal(data []byte, m proto.Message) error { return proto.Unmarshal(data, m) }
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(m proto.Message) ([]byte, error)      { return marshaler.Marshal(m) }
+func (jsonCodec) Unmarshal(data []byte, m proto.Message) error { return unmarshaler.Unmarshal(data, m) }
+
+// codecs - поддерживаемые форматы сообщений по суффиксу Content-Type
+var codecs = map[string]codec{
+	"proto": protoCodec{},
+	"json":  jsonCodec{},
+}
+
+// rpcExchange выбирает протокол по Content-Type запроса:
+//   - application/grpc-web[+proto|+json] и application/grpc-web-text[+proto] - gRPC-Web;
+//   - application/proto и application/json - unary метод по протоколу Connect;
+//   - application/connect+proto и application/connect+json - потоковый метод по протоколу Connect.
+func rpcExchange(req *http.Request, r route) (newExchange, bool) {
+	mediaType, _, _ := strings.Cut(req.Header.Get("Content-Type"), ";")
+	base, name, _ := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "+")
+	if name == "" {
+		name = "proto"
+	}
+
+	switch base {
+	case "application/grpc-web", "application/grpc-web-text":
+		c, ok := codecs[name]
+		if !ok {
+			return nil, false
+		}
+		return grpcWebExchange(c, base == "application/grpc-web-text"), true
+	case "application/connect":
+		c, ok := codecs[name]
+		if !ok || !r.streaming {
+			return nil, false
+		}
+		return connectStreamExchange(c), true
+	}
+	c, ok := codecs[strings.TrimPrefix(base, "application/")]
+	if !ok || r.streaming || !strings.HasPrefix(base, "application/") {
+		return nil, false
+	}
+	return connectUnaryExchange(c), true
+}
+
+// readEnvelope читает одно сообщение в конверте: флаги, длина и само сообщение
+func readEnvelope(r io.Reader) (byte, []byte, error) {
+	var header [envelopeHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, status.Error(codes.InvalidArgument, "request message is missing")
+		}
+		return 0, nil, status.Error(codes.InvalidArgument, "failed to read request message")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxBodySize {
+		return 0, nil, status.Errorf(codes.ResourceExhausted, "request message is larger than %d bytes", maxBodySize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, status.Error(codes.InvalidArgument, "failed to read request message")
+	}
+	return header[0], data, nil
+}
+
+// recvEnvelope читает сообщение запроса в конверте и декодирует его
+func recvEnvelope(r io.Reader, c codec, m proto.Message) error {
+	flags, data, err := readEnvelope(r)
+	if err != nil {
+		return err
+	}
+	if flags&flagCompressed != 0 {
+		return status.Error(codes.Unimplemented, "message compression is not supported")
+	}
+	if err := c.Unmarshal(data, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request message: %v", err)
+	}
+	return nil
+}
+
+// envelope упаковывает сообщение в конверт с флагами
+func envelope(flags byte, data []byte) []byte {
+	out := make([]byte, envelopeHeaderSize, envelopeHeaderSize+len(data))
+	out[0] = flags
+	binary.BigEndian.PutUint32(out[1:], uint32(len(data)))
+	return append(out, data...)
+}
+
+// requestTimeout возвращает срок вызова из заголовка Connect-Timeout-Ms или grpc-timeout; 0 - срок не задан
+func requestTimeout(req *http.Request) (time.Duration, error) {
+	if v := req.Header.Get("Connect-Timeout-Ms"); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms <= 0 || len(v) > 10 {
+			return 0, status.Errorf(codes.InvalidArgument, "invalid Connect-Timeout-Ms %q", v)
+		}
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	v := req.Header.Get("Grpc-Timeout")
+	if v == "" {
+		return 0, nil
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[v[len(v)-1]]
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if !ok || err != nil || n <= 0 || len(v) > 9 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid grpc-timeout %q", v)
+	}
+	if n > math.MaxInt64/int64(unit) { // Срок больше сотен лет считается отсутствующим
+		return 0, nil
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,192 @@
+package gateway
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// tracer создаёт серверные спаны вызовов через шлюз
+var tracer = otel.Tracer("github.com/linemk/gRPC_auth/internal/grpc/gateway")
+
+// skipHeaders - заголовки транспорта HTTP, которые не передаются в метаданные вызова.
+// Заголовки протоколов gRPC и Connect тоже не передаются.
+var skipHeaders = map[string]bool{
+	"connection":        true,
+	"content-length":    true,
+	"content-type":      true,
+	"host":              true,
+	"keep-alive":        true,
+	"proxy-connection":  true,
+	"te":                true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+}
+
+// HTTPStatus сопоставляет код gRPC статусу HTTP
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Клиент закрыл соединение, как в nginx
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// incomingContext переносит заголовки HTTP в метаданные, а адрес и TLS клиента - в peer
+func incomingContext(req *http.Request) context.Context {
+	md := metadata.MD{}
+	for name, values := range req.Header {
+		key := strings.ToLower(name)
+		if skipHeaders[key] || strings.HasPrefix(key, "grpc-") || strings.HasPrefix(key, "connect-") {
+			continue
+		}
+		md.Append(key, values...)
+	}
+	ctx := metadata.NewIncomingContext(req.Context(), md)
+
+	p := &peer.Peer{Addr: httpAddr(req.RemoteAddr)}
+	if req.TLS != nil { // Клиентский сертификат сопоставляется приложению так же, как в gRPC
+		p.AuthInfo = credentials.TLSInfo{
+			State:          *req.TLS,
+			CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+		}
+	}
+	return peer.NewContext(ctx, p)
+}
+
+// startSpan открывает серверный спан вызова с контекстом трассы из заголовков HTTP
+func startSpan(ctx context.Context, req *http.Request, r route) (context.Context, func(error)) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
+	ctx, span := tracer.Start(ctx, strings.TrimPrefix(r.fullMethod, "/"), trace.WithSpanKind(trace.SpanKindServer))
+	return ctx, func(err error) {
+		if err != nil {
+			span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+		}
+		span.End()
+	}
+}
+
+// httpAddr - адрес клиента HTTP для peer
+type httpAddr string
+
+func (a httpAddr) Network() string { return "tcp" }
+func (a httpAddr) String() string  { return string(a) }
+
+// transportStream принимает заголовки и трейлеры, которые обработчик и перехватчики задают через grpc.SetHeader
+type transportStream struct {
+	method  string
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+var _ grpc.ServerTransportStream = (*transportStream)(nil)
+
+func (s *transportStream) Method() string { return s.method }
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *transportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// metadata возвращает копии заголовков и трейлеров вызова
+func (s *transportStream) metadata() (header, trailer metadata.MD) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.header.Copy(), s.trailer.Copy()
+}
+
+// setHeaders переносит метаданные вызова в заголовки ответа HTTP
+func setHeaders(h http.Header, mds ...metadata.MD) {
+	for _, md := range mds {
+		for key, values := range md {
+			for _, v := range values {
+				h.Add(key, v)
+			}
+		}
+	}
+}
+
+// write отправляет часть потокового ответа клиенту сразу, не дожидаясь заполнения буфера
+func write(w http.ResponseWriter, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		return status.Error(codes.Unavailable, "client connection is closed")
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// serverStream реализует grpc.ServerStream поверх обмена сообщениями протокола шлюза
+type serverStream struct {
+	ctx  context.Context
+	ts   *transportStream
+	recv func(any) error
+	send func(proto.Message) error
+	read bool // Сообщение запроса уже прочитано
+}
+
+func (s *serverStream) Context() context.Context        { return s.ctx }
+func (s *serverStream) SetHeader(md metadata.MD) error  { return s.ts.SetHeader(md) }
+func (s *serverStream) SendHeader(md metadata.MD) error { return s.ts.SendHeader(md) }
+func (s *serverStream) SetTrailer(md metadata.MD)       { _ = s.ts.SetTrailer(md) }
+
+// RecvMsg читает единственное сообщение запроса
+func (s *serverStream) RecvMsg(m any) error {
+	if s.read {
+		return status.Error(codes.InvalidArgument, "stream request has a single message")
+	}
+	s.read = true
+	return s.recv(m)
+}
+
+// SendMsg отправляет сообщение ответа в формате протокола
+func (s *serverStream) SendMsg(m any) error {
+	return s.send(m.(proto.Message))
+}
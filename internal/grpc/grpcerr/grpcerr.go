@@ -0,0 +1,24 @@
+package grpcerr
+
+import (
+	"errors"
+	"github.com/linemk/gRPC_auth/internal/services/auth" // Ошибки сервиса авторизации
+	"google.golang.org/grpc/codes"                       // Коды статусов gRPC
+	"google.golang.org/grpc/status"                      // Статусы gRPC
+)
+
+// AccountStatus переводит ошибку неактивной учётной записи в статус gRPC.
+// Каждое состояние получает собственный код, чтобы клиент мог показать понятное сообщение.
+func AccountStatus(err error) (error, bool) {
+	switch {
+	case errors.Is(err, auth.ErrUserPendingVerification):
+		return status.Error(codes.FailedPrecondition, "account is pending verification"), true
+	case errors.Is(err, auth.ErrUserSuspended):
+		return status.Error(codes.PermissionDenied, "account is suspended"), true
+	case errors.Is(err, auth.ErrUserLocked):
+		return status.Error(codes.ResourceExhausted, "account is locked"), true
+	case errors.Is(err, auth.ErrUserDeleted):
+		return status.Error(codes.NotFound, "account is deleted"), true
+	}
+	return nil, false
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Probe проверяет зависимости, без которых сервис не может обслуживать вызовы
+type Probe interface {
+	Ping(ctx context.Context) error        // Доступность хранилища
+	CheckSchema(ctx context.Context) error // Применены ли нужные миграции
+}
+
+// Server отвечает по протоколу grpc.health.v1.
+// Статус каждого зарегистрированного сервиса и сервера в целом ("") совпадает с результатом последней проверки.
+type Server struct {
+	*health.Server
+	log      *slog.Logger
+	probe    Probe
+	interval time.Duration // Период проверок готовности
+	services []string      // Имена сервисов, статус которых ведётся
+	ready    bool          // Результат последней проверки, чтобы журналировать только смену статуса
+	checked  bool          // Была ли хотя бы одна проверка
+}
+
+// New создаёт сервер проверки состояния; до первой проверки все сервисы в статусе NOT_SERVING
+func New(log *slog.Logger, probe Probe, interval time.Duration) *Server {
+	srv := &Server{
+		Server:   health.NewServer(),
+		log:      log,
+		probe:    probe,
+		interval: interval,
+	}
+	srv.Server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return srv
+}
+
+// Register регистрирует сервис проверки состояния и запоминает уже зарегистрированные сервисы,
+// поэтому вызывается после регистрации остальных. Один Server можно зарегистрировать на нескольких gRPC серверах.
+func (s *Server) Register(gRPCServer *grpc.Server) {
+	for name := range gRPCServer.GetServiceInfo() {
+		if slices.Contains(s.services, name) {
+			continue
+		}
+		s.services = append(s.services, name)
+		s.Server.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	healthpb.RegisterHealthServer(gRPCServer, s)
+}
+
+// Run проверяет готовность сразу и затем с периодом interval, пока не отменён ctx
+func (s *Server) Run(ctx context.Context) {
+	s.check(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx)
+		}
+	}
+}
+
+// check выполняет одну проверку готовности и обновляет статусы
+func (s *Server) check(ctx context.Context) {
+	const op = "health.check"
+
+	checkCtx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	err := errors.Join(s.probe.Ping(checkCtx), s.probe.CheckSchema(checkCtx))
+	if ctx.Err() != nil {
+		return // Сервер останавливается, статус уже выставлен Shutdown
+	}
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if err != nil {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	if ready := err == nil; ready != s.ready || !s.checked {
+		log := s.log.With(slog.String("op", op))
+		if ready {
+			log.Info("server is ready")
+		} else {
+			log.Warn("server is not ready", slog.String("error", err.Error()))
+		}
+		s.ready, s.checked = ready, true
+	}
+
+	s.Server.SetServingStatus("", status)
+	for _, name := range s.services {
+		s.Server.SetServingStatus(name, status)
+	}
+}
@@ -0,0 +1,57 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/x509"
+	"github.com/linemk/gRPC_auth/internal/lib/clientapp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// UnaryClientCert сопоставляет проверенный клиентский сертификат приложению из apps
+// и кладёт идентификатор приложения в контекст. Без TLS или без сопоставления контекст не меняется.
+func UnaryClientCert(apps map[string]int) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withClientApp(ctx, apps), req)
+	}
+}
+
+// StreamClientCert - UnaryClientCert для потоковых вызовов
+func StreamClientCert(apps map[string]int) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: withClientApp(ss.Context(), apps)})
+	}
+}
+
+// withClientApp добавляет в контекст приложение, которому сопоставлен сертификат клиента
+func withClientApp(ctx context.Context, apps map[string]int) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 { // Учитываются только сертификаты, проверенные по CA
+		return ctx
+	}
+	for _, identity := range certIdentities(info.State.VerifiedChains[0][0]) {
+		if appID, ok := apps[identity]; ok {
+			return clientapp.NewContext(ctx, appID)
+		}
+	}
+	return ctx
+}
+
+// certIdentities возвращает идентичности сертификата в порядке приоритета:
+// URI из SAN (например, SPIFFE ID), DNS имена из SAN, затем Common Name
+func certIdentities(cert *x509.Certificate) []string {
+	var identities []string
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	identities = append(identities, cert.DNSNames...)
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	return identities
+}
@@ -0,0 +1,140 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/linemk/gRPC_auth/internal/lib/clientapp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// newCert выпускает самоподписанный сертификат клиента с указанными идентичностями
+func newCert(t *testing.T, cn string, dnsNames []string, uris ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		DNSNames:     dnsNames,
+	}
+	for _, raw := range uris {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		tmpl.URIs = append(tmpl.URIs, u)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// tlsPeer возвращает контекст с TLS соединением; verified - цепочка клиента проверена по CA
+func tlsPeer(cert *x509.Certificate, verified bool) context.Context {
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if verified {
+		state.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func TestCertIdentities(t *testing.T) {
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want []string
+	}{
+		{
+			name: "uri, dns and cn in priority order",
+			cert: newCert(t, "billing", []string{"billing.internal", "billing.svc"}, "spiffe://example.org/billing"),
+			want: []string{"spiffe://example.org/billing", "billing.internal", "billing.svc", "billing"},
+		},
+		{name: "only cn", cert: newCert(t, "billing", nil), want: []string{"billing"}},
+		{name: "no cn", cert: newCert(t, "", []string{"billing.internal"}), want: []string{"billing.internal"}},
+		{name: "nothing", cert: newCert(t, "", nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, certIdentities(tt.cert))
+		})
+	}
+}
+
+func TestWithClientApp(t *testing.T) {
+	cert := newCert(t, "billing", []string{"billing.internal"}, "spiffe://example.org/billing")
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		apps   map[string]int
+		wantID int
+		wantOK bool
+	}{
+		{
+			name:   "uri wins over dns and cn",
+			ctx:    tlsPeer(cert, true),
+			apps:   map[string]int{"billing": 3, "billing.internal": 2, "spiffe://example.org/billing": 1},
+			wantID: 1, wantOK: true,
+		},
+		{
+			name:   "dns wins over cn",
+			ctx:    tlsPeer(cert, true),
+			apps:   map[string]int{"billing": 3, "billing.internal": 2},
+			wantID: 2, wantOK: true,
+		},
+		{name: "cn", ctx: tlsPeer(cert, true), apps: map[string]int{"billing": 3}, wantID: 3, wantOK: true},
+		{name: "no mapping", ctx: tlsPeer(cert, true), apps: map[string]int{"other": 3}},
+		{name: "unverified chain is ignored", ctx: tlsPeer(cert, false), apps: map[string]int{"billing": 3}},
+		{
+			name: "plaintext connection",
+			ctx:  peer.NewContext(context.Background(), &peer.Peer{}),
+			apps: map[string]int{"billing": 3},
+		},
+		{name: "no peer", ctx: context.Background(), apps: map[string]int{"billing": 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appID, ok := clientapp.FromContext(withClientApp(tt.ctx, tt.apps))
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantID, appID)
+		})
+	}
+}
+
+func TestClientCertInterceptors(t *testing.T) {
+	cert := newCert(t, "billing", nil)
+	apps := map[string]int{"billing": 7}
+
+	_, err := UnaryClientCert(apps)(tlsPeer(cert, true), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		appID, ok := clientapp.FromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, 7, appID)
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	stream := &serverStream{ctx: tlsPeer(cert, true)}
+	err = StreamClientCert(apps)(nil, stream, &grpc.StreamServerInfo{}, func(_ any, ss grpc.ServerStream) error {
+		appID, ok := clientapp.FromContext(ss.Context())
+		assert.True(t, ok)
+		assert.Equal(t, 7, appID)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,88 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync/atomic"
+	"time"
+)
+
+// Deadlines описывает серверные сроки выполнения вызовов
+type Deadlines struct {
+	Default time.Duration            // Срок для методов без своего значения; 0 - без срока
+	Methods map[string]time.Duration // Полное имя метода (/auth.Auth/Login) -> срок; 0 - без срока
+}
+
+// timeout возвращает срок для метода
+func (d Deadlines) timeout(method string) time.Duration {
+	if t, ok := d.Methods[method]; ok {
+		return t
+	}
+	return d.Default
+}
+
+// DeadlineStore хранит текущие сроки вызовов. Store заменяет их без перезапуска сервера:
+// новые сроки действуют для вызовов, начатых после замены.
+type DeadlineStore struct {
+	current atomic.Pointer[Deadlines]
+}
+
+// NewDeadlineStore создает хранилище со сроками d
+func NewDeadlineStore(d Deadlines) *DeadlineStore {
+	s := &DeadlineStore{}
+	s.Store(d)
+	return s
+}
+
+// Store заменяет сроки вызовов
+func (s *DeadlineStore) Store(d Deadlines) {
+	s.current.Store(&d)
+}
+
+// timeout возвращает текущий срок для метода
+func (s *DeadlineStore) timeout(method string) time.Duration {
+	return s.current.Load().timeout(method)
+}
+
+// UnaryDeadline ограничивает время выполнения обработчика сроком из настроек.
+// Более ранний срок клиента сохраняется. Если обработчик не уложился в срок,
+// клиент получает codes.DeadlineExceeded вместо внутренней ошибки хранилища.
+func UnaryDeadline(d *DeadlineStore) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := withDeadline(ctx, d.timeout(info.FullMethod))
+		defer cancel()
+		resp, err := handler(ctx, req)
+		return resp, deadlineError(ctx, err)
+	}
+}
+
+// StreamDeadline - UnaryDeadline для потоковых вызовов
+func StreamDeadline(d *DeadlineStore) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := withDeadline(ss.Context(), d.timeout(info.FullMethod))
+		defer cancel()
+		return deadlineError(ctx, handler(srv, &serverStream{ServerStream: ss, ctx: ctx}))
+	}
+}
+
+// withDeadline добавляет срок, если он задан
+func withDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// deadlineError заменяет ошибку обработчика на codes.DeadlineExceeded, если срок вызова истёк
+func deadlineError(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	if status.Code(err) == codes.DeadlineExceeded {
+		return err
+	}
+	return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+}
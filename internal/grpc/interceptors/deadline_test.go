@@ -0,0 +1,179 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+const loginMethod = "/auth.Auth/Login"
+
+// remaining возвращает срок, оставшийся у контекста обработчика; ok == false - срока нет
+func remaining(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	return time.Until(deadline), ok
+}
+
+func TestUnaryDeadline_Timeout(t *testing.T) {
+	tests := []struct {
+		name      string
+		deadlines Deadlines
+		method    string
+		client    time.Duration // Срок клиента; 0 - без срока
+		want      time.Duration // Ожидаемый срок обработчика; 0 - без срока
+	}{
+		{name: "default", deadlines: Deadlines{Default: 5 * time.Second}, method: loginMethod, want: 5 * time.Second},
+		{
+			name:      "method override",
+			deadlines: Deadlines{Default: 5 * time.Second, Methods: map[string]time.Duration{loginMethod: time.Minute}},
+			method:    loginMethod,
+			want:      time.Minute,
+		},
+		{
+			name:      "override of other method",
+			deadlines: Deadlines{Default: 5 * time.Second, Methods: map[string]time.Duration{"/auth.Admin/ImportUsers": time.Minute}},
+			method:    loginMethod,
+			want:      5 * time.Second,
+		},
+		{
+			name:      "zero override disables default",
+			deadlines: Deadlines{Default: 5 * time.Second, Methods: map[string]time.Duration{loginMethod: 0}},
+			method:    loginMethod,
+		},
+		{name: "zero default", deadlines: Deadlines{}, method: loginMethod},
+		{
+			name:      "earlier client deadline is kept",
+			deadlines: Deadlines{Default: time.Minute},
+			method:    loginMethod,
+			client:    time.Second,
+			want:      time.Second,
+		},
+		{
+			name:      "client deadline without server deadline",
+			deadlines: Deadlines{Methods: map[string]time.Duration{loginMethod: 0}},
+			method:    loginMethod,
+			client:    time.Second,
+			want:      time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.client > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.client)
+				defer cancel()
+			}
+
+			interceptor := UnaryDeadline(NewDeadlineStore(tt.deadlines))
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, _ any) (any, error) {
+				left, ok := remaining(ctx)
+				if tt.want == 0 {
+					assert.False(t, ok, "handler must have no deadline")
+					return nil, nil
+				}
+				require.True(t, ok)
+				assert.InDelta(t, tt.want, left, float64(100*time.Millisecond))
+				return nil, nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDeadlineStore_Store(t *testing.T) {
+	store := NewDeadlineStore(Deadlines{Default: time.Minute})
+	interceptor := UnaryDeadline(store)
+	info := &grpc.UnaryServerInfo{FullMethod: loginMethod}
+
+	// Новые сроки действуют для следующих вызовов без пересоздания перехватчика
+	store.Store(Deadlines{Default: time.Minute, Methods: map[string]time.Duration{loginMethod: 0}})
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, _ any) (any, error) {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		return nil, nil
+	})
+	require.NoError(t, err)
+}
+
+func TestUnaryDeadline_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  grpc.UnaryHandler
+		wantCode codes.Code
+	}{
+		{
+			name: "storage error after deadline",
+			handler: func(ctx context.Context, _ any) (any, error) {
+				<-ctx.Done()
+				return nil, errors.New("storage.sqlite.User: context deadline exceeded")
+			},
+			wantCode: codes.DeadlineExceeded,
+		},
+		{
+			name: "status from handler is kept",
+			handler: func(ctx context.Context, _ any) (any, error) {
+				<-ctx.Done()
+				return nil, status.Error(codes.DeadlineExceeded, "login timed out")
+			},
+			wantCode: codes.DeadlineExceeded,
+		},
+		{
+			name: "error before deadline is kept",
+			handler: func(context.Context, any) (any, error) {
+				return nil, status.Error(codes.NotFound, "user not found")
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:    "success",
+			handler: func(context.Context, any) (any, error) { return "ok", nil },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := UnaryDeadline(NewDeadlineStore(Deadlines{Default: 20 * time.Millisecond}))
+			_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: loginMethod}, tt.handler)
+			if tt.wantCode == codes.OK {
+				require.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+
+	// Сообщение обработчика со статусом DeadlineExceeded не подменяется
+	interceptor := UnaryDeadline(NewDeadlineStore(Deadlines{Default: 20 * time.Millisecond}))
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: loginMethod}, tests[1].handler)
+	assert.Equal(t, "login timed out", status.Convert(err).Message())
+}
+
+func TestStreamDeadline(t *testing.T) {
+	deadlines := Deadlines{
+		Default: 20 * time.Millisecond,
+		Methods: map[string]time.Duration{"/auth.Admin/WatchEvents": 0},
+	}
+	interceptor := StreamDeadline(NewDeadlineStore(deadlines))
+	stream := &serverStream{ctx: context.Background()}
+
+	// Поток с нулевым сроком в настройках работает без срока
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/auth.Admin/WatchEvents"}, func(_ any, ss grpc.ServerStream) error {
+		_, ok := ss.Context().Deadline()
+		assert.False(t, ok)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Остальные потоки получают срок по умолчанию и DeadlineExceeded по его истечении
+	err = interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/auth.Admin/ImportUsers"}, func(_ any, ss grpc.ServerStream) error {
+		<-ss.Context().Done()
+		return ss.Context().Err()
+	})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
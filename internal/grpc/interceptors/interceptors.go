@@ -0,0 +1,124 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"log/slog"
+	"slices"
+)
+
+// Имена перехватчиков в конфигурации
+const (
+	NameRequestID  = "request_id"  // Идентификатор запроса из метаданных или новый
+	NameAccessLog  = "access_log"  // Одна строка журнала на вызов
+	NameMetrics    = "metrics"     // Длительность вызовов по методу и коду ответа
+	NameRecovery   = "recovery"    // Паника в обработчике превращается в codes.Internal
+	NameDeadline   = "deadline"    // Серверный срок выполнения вызова
+	NameClientCert = "client_cert" // Приложение вызывающего сервиса по клиентскому сертификату
+)
+
+// order - порядок перехватчиков в цепочке независимо от порядка в конфигурации:
+// идентификатор нужен журналу, а журнал и метрики должны видеть код ошибки после восстановления от паники
+// и после истечения срока
+var order = []string{NameRequestID, NameAccessLog, NameMetrics, NameRecovery, NameDeadline, NameClientCert}
+
+// ErrUnknownInterceptor возвращается для неизвестного имени в конфигурации
+var ErrUnknownInterceptor = errors.New("unknown interceptor")
+
+// Config описывает включённые перехватчики
+type Config struct {
+	Enabled         []string       // Имена включённых перехватчиков
+	RequestIDHeader string         // Ключ метаданных с идентификатором запроса
+	Observer        CallObserver   // Получатель метрик вызовов; без него перехватчик metrics не включается
+	ClientApps      map[string]int // Идентичность клиентского сертификата (URI, DNS или CN) -> идентификатор приложения
+	Deadlines       *DeadlineStore // Серверные сроки вызовов для перехватчика deadline; nil - без сроков
+}
+
+// Chains собирает включённые перехватчики в один unary и один stream перехватчик.
+// Их используют и gRPC сервер, и HTTP шлюз, чтобы вызовы через оба входа обрабатывались одинаково.
+func Chains(log *slog.Logger, cfg Config) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor, error) {
+	for _, name := range cfg.Enabled {
+		if !slices.Contains(order, name) {
+			return nil, nil, fmt.Errorf("%w: %q", ErrUnknownInterceptor, name)
+		}
+	}
+
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
+	)
+	for _, name := range order {
+		if !slices.Contains(cfg.Enabled, name) {
+			continue
+		}
+		switch name {
+		case NameRequestID:
+			unary = append(unary, UnaryRequestID(cfg.RequestIDHeader))
+			stream = append(stream, StreamRequestID(cfg.RequestIDHeader))
+		case NameAccessLog:
+			unary = append(unary, UnaryAccessLog(log))
+			stream = append(stream, StreamAccessLog(log))
+		case NameMetrics:
+			if cfg.Observer == nil {
+				continue
+			}
+			unary = append(unary, UnaryMetrics(cfg.Observer))
+			stream = append(stream, StreamMetrics(cfg.Observer))
+		case NameRecovery:
+			unary = append(unary, UnaryRecovery(log))
+			stream = append(stream, StreamRecovery(log))
+		case NameDeadline:
+			deadlines := cfg.Deadlines
+			if deadlines == nil {
+				deadlines = NewDeadlineStore(Deadlines{})
+			}
+			unary = append(unary, UnaryDeadline(deadlines))
+			stream = append(stream, StreamDeadline(deadlines))
+		case NameClientCert:
+			unary = append(unary, UnaryClientCert(cfg.ClientApps))
+			stream = append(stream, StreamClientCert(cfg.ClientApps))
+		}
+	}
+	return chainUnary(unary), chainStream(stream), nil
+}
+
+// chainUnary объединяет перехватчики: первый в списке вызывается первым
+func chainUnary(chain []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(chain) - 1; i >= 0; i-- {
+			interceptor, inner := chain[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// chainStream - chainUnary для потоковых перехватчиков
+func chainStream(chain []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		next := handler
+		for i := len(chain) - 1; i >= 0; i-- {
+			interceptor, inner := chain[i], next
+			next = func(srv any, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, inner)
+			}
+		}
+		return next(srv, ss)
+	}
+}
+
+// serverStream подменяет контекст потока
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает подменённый контекст
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,61 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/linemk/gRPC_auth/internal/lib/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"time"
+)
+
+// UnaryAccessLog пишет одну строку журнала на каждый вызов
+func UnaryAccessLog(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		accessLog(ctx, log, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamAccessLog пишет одну строку журнала на каждый потоковый вызов после его завершения
+func StreamAccessLog(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		accessLog(ss.Context(), log, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// accessLog записывает метод, код ответа, длительность и адрес клиента
+func accessLog(ctx context.Context, log *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	requestid.Logger(ctx, log).LogAttrs(ctx, codeLevel(code), "grpc call", attrs...)
+}
+
+// codeLevel выбирает уровень журнала: ошибки сервера отличаются от ошибок клиента
+func codeLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.Unauthenticated, codes.PermissionDenied, codes.FailedPrecondition, codes.ResourceExhausted:
+		return slog.LevelInfo
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		return slog.LevelError
+	}
+	return slog.LevelWarn
+}
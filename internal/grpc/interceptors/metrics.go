@@ -0,0 +1,34 @@
+package interceptors
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// CallObserver получает длительность и код завершения каждого вызова
+type CallObserver interface {
+	ObserveCall(method string, code codes.Code, duration time.Duration)
+}
+
+// UnaryMetrics передаёт длительность вызова наблюдателю
+func UnaryMetrics(observer CallObserver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observer.ObserveCall(info.FullMethod, status.Code(err), time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamMetrics передаёт длительность потокового вызова наблюдателю
+func StreamMetrics(observer CallObserver) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observer.ObserveCall(info.FullMethod, status.Code(err), time.Since(start))
+		return err
+	}
+}
@@ -0,0 +1,46 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/lib/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"runtime/debug"
+)
+
+// UnaryRecovery перехватывает панику в обработчике и возвращает codes.Internal вместо падения процесса
+func UnaryRecovery(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ctx, log, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery - UnaryRecovery для потоковых вызовов
+func StreamRecovery(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ss.Context(), log, info.FullMethod, p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// recovered записывает панику со стеком; клиент получает только общий текст ошибки
+func recovered(ctx context.Context, log *slog.Logger, method string, p any) error {
+	requestid.Logger(ctx, log).Error("panic in grpc handler",
+		slog.String("method", method),
+		slog.String("panic", fmt.Sprint(p)),
+		slog.String("stack", string(debug.Stack())),
+	)
+	return status.Error(codes.Internal, "internal server error")
+}
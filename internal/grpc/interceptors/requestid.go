@@ -0,0 +1,42 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/linemk/gRPC_auth/internal/lib/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"regexp"
+)
+
+// validID ограничивает идентификатор из метаданных, чтобы клиент не мог засорить журнал
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// UnaryRequestID берёт идентификатор запроса из метаданных или создаёт новый,
+// кладёт его в контекст и возвращает клиенту в заголовке ответа
+func UnaryRequestID(header string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withRequestID(ctx, header), req)
+	}
+}
+
+// StreamRequestID - UnaryRequestID для потоковых вызовов
+func StreamRequestID(header string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: withRequestID(ss.Context(), header)})
+	}
+}
+
+// withRequestID добавляет идентификатор запроса в контекст и заголовок ответа
+func withRequestID(ctx context.Context, header string) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(header); len(values) > 0 && validID.MatchString(values[0]) {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = requestid.New()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(header, id)) // Ошибка возможна, только если заголовки уже отправлены
+	return requestid.NewContext(ctx, id)
+}
@@ -0,0 +1,343 @@
+package oauthhttp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/requestid"
+	"github.com/linemk/gRPC_auth/internal/services/auth"
+	"github.com/linemk/gRPC_auth/internal/services/oauth"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Пути сервера авторизации
+const (
+	Prefix        = "/oauth/"          // Общий префикс путей, под которым обработчик подключается к шлюзу
+	AuthorizePath = "/oauth/authorize" // Страница входа и согласия
+	TokenPath     = "/oauth/token"     // Обмен кода авторизации на токен
+)
+
+// csrfCookie - cookie с токеном CSRF формы входа (double submit)
+const csrfCookie = "sso_oauth_csrf"
+
+// maxFormSize ограничивает тело формы
+const maxFormSize = 64 << 10
+
+// Service - сервер авторизации OAuth 2.0
+type Service interface {
+	Client(ctx context.Context, clientID int, redirectURI string) (models.App, error)
+	Authorize(ctx context.Context, req oauth.AuthorizeRequest, email, password string) (string, error)
+	Deny(ctx context.Context, req oauth.AuthorizeRequest)
+	Exchange(ctx context.Context, req oauth.TokenRequest) (oauth.Token, error)
+}
+
+// Handler отдаёт конечные точки OAuth 2.0 по HTTP: страницу входа и согласия для кода авторизации
+// с PKCE (RFC 6749, раздел 4.1; RFC 7636) и обмен кода на токен. Токен выдаётся тем же кодом,
+// что и при входе по паролю, и принимается всеми методами, проверяющими токены.
+type Handler struct {
+	log *slog.Logger
+	svc Service
+	mux *http.ServeMux
+}
+
+// authorizeParams - параметры запроса авторизации из строки запроса или скрытых полей формы
+type authorizeParams struct {
+	req   oauth.AuthorizeRequest
+	state string // Возвращается приложению без изменений
+}
+
+// tokenResponse - успешный ответ конечной точки токена
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// errorResponse - ошибка конечной точки токена (RFC 6749, раздел 5.2)
+type errorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// New создаёт обработчик конечных точек OAuth 2.0
+func New(log *slog.Logger, svc Service) *Handler {
+	h := &Handler{log: log, svc: svc, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET "+AuthorizePath, h.authorizeForm)
+	h.mux.HandleFunc("POST "+AuthorizePath, h.authorize)
+	h.mux.HandleFunc("POST "+TokenPath, h.token)
+	return h
+}
+
+// ServeHTTP реализует http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(requestid.NewContext(r.Context(), requestid.New()))
+	h.mux.ServeHTTP(w, r)
+}
+
+// authorizeForm показывает страницу входа и согласия
+func (h *Handler) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	params, app, ok := h.authorizeParams(w, r, r.URL.Query(), http.StatusFound)
+	if !ok {
+		return
+	}
+	token, err := newCSRFToken()
+	if err != nil {
+		h.fail(w, http.StatusInternalServerError, "Internal error, please try again later.")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     AuthorizePath,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	h.render(w, http.StatusOK, h.form(app, params, token, "", ""))
+}
+
+// authorize проверяет учётные данные из формы и возвращает пользователя в приложение с кодом
+// авторизации или с ошибкой access_denied
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		h.fail(w, http.StatusBadRequest, "Malformed request.")
+		return
+	}
+	cookie, err := r.Cookie(csrfCookie)
+	token := r.PostForm.Get("csrf_token")
+	if err != nil || token == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(token)) != 1 {
+		h.fail(w, http.StatusForbidden, "The sign-in form has expired. Return to the application and try again.")
+		return
+	}
+	params, app, ok := h.authorizeParams(w, r, r.PostForm, http.StatusSeeOther)
+	if !ok {
+		return
+	}
+
+	if r.PostForm.Get("action") != "allow" {
+		h.svc.Deny(r.Context(), params.req)
+		h.redirect(w, r, params, http.StatusSeeOther, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	email := r.PostForm.Get("email")
+	code, err := h.svc.Authorize(r.Context(), params.req, email, r.PostForm.Get("password"))
+	if err != nil {
+		var message string
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			message = "Invalid email or password."
+		case errors.Is(err, auth.ErrUserPendingVerification),
+			errors.Is(err, auth.ErrUserSuspended),
+			errors.Is(err, auth.ErrUserLocked),
+			errors.Is(err, auth.ErrUserDeleted):
+			message = "This account cannot sign in."
+		default:
+			requestid.Logger(r.Context(), h.log).Error("authorization failed", slog.String("error", err.Error()))
+			h.redirect(w, r, params, http.StatusSeeOther, url.Values{"error": {"server_error"}})
+			return
+		}
+		h.render(w, http.StatusOK, h.form(app, params, token, email, message))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: csrfCookie, Path: AuthorizePath, MaxAge: -1, HttpOnly: true, Secure: r.TLS != nil})
+	h.redirect(w, r, params, http.StatusSeeOther, url.Values{"code": {code}})
+}
+
+// authorizeParams читает и проверяет параметры запроса авторизации. Пока client_id и redirect_uri
+// не проверены, ошибка показывается страницей; после этого - возвращается приложению на redirect_uri.
+// false - ответ уже отправлен.
+func (h *Handler) authorizeParams(w http.ResponseWriter, r *http.Request, values url.Values, redirectCode int) (authorizeParams, models.App, bool) {
+	params := authorizeParams{
+		req: oauth.AuthorizeRequest{
+			RedirectURI:         values.Get("redirect_uri"),
+			CodeChallenge:       values.Get("code_challenge"),
+			CodeChallengeMethod: values.Get("code_challenge_method"),
+		},
+		state: values.Get("state"),
+	}
+	clientID, err := strconv.Atoi(values.Get("client_id"))
+	if err != nil {
+		h.fail(w, http.StatusBadRequest, "Unknown application.")
+		return authorizeParams{}, models.App{}, false
+	}
+	params.req.ClientID = clientID
+
+	app, err := h.svc.Client(r.Context(), clientID, params.req.RedirectURI)
+	switch {
+	case errors.Is(err, oauth.ErrUnknownClient):
+		h.fail(w, http.StatusBadRequest, "Unknown application.")
+		return authorizeParams{}, models.App{}, false
+	case errors.Is(err, oauth.ErrInvalidRedirectURI):
+		h.fail(w, http.StatusBadRequest, "The redirect address is not registered for this application.")
+		return authorizeParams{}, models.App{}, false
+	case err != nil:
+		requestid.Logger(r.Context(), h.log).Error("failed to load oauth client", slog.String("error", err.Error()))
+		h.fail(w, http.StatusInternalServerError, "Internal error, please try again later.")
+		return authorizeParams{}, models.App{}, false
+	}
+
+	if values.Get("response_type") != "code" {
+		h.redirect(w, r, params, redirectCode, url.Values{
+			"error":             {"unsupported_response_type"},
+			"error_description": {"only response_type=code is supported"},
+		})
+		return authorizeParams{}, models.App{}, false
+	}
+	if err := oauth.CheckChallenge(params.req.CodeChallenge, params.req.CodeChallengeMethod); err != nil {
+		h.redirect(w, r, params, redirectCode, url.Values{
+			"error":             {"invalid_request"},
+			"error_description": {err.Error()},
+		})
+		return authorizeParams{}, models.App{}, false
+	}
+	return params, app, true
+}
+
+// form собирает страницу входа для запроса params
+func (h *Handler) form(app models.App, params authorizeParams, token, email, message string) page {
+	return page{
+		AppName: app.Name,
+		Action:  AuthorizePath,
+		Hidden: []field{
+			{Name: "response_type", Value: "code"},
+			{Name: "client_id", Value: strconv.Itoa(params.req.ClientID)},
+			{Name: "redirect_uri", Value: params.req.RedirectURI},
+			{Name: "state", Value: params.state},
+			{Name: "code_challenge", Value: params.req.CodeChallenge},
+			{Name: "code_challenge_method", Value: params.req.CodeChallengeMethod},
+			{Name: "csrf_token", Value: token},
+		},
+		Email: email,
+		Error: message,
+	}
+}
+
+// redirect возвращает пользователя на проверенный redirect_uri с параметрами ответа и state
+func (h *Handler) redirect(w http.ResponseWriter, r *http.Request, params authorizeParams, code int, values url.Values) {
+	target, err := url.Parse(params.req.RedirectURI)
+	if err != nil { // Адрес проверен при регистрации приложения
+		h.fail(w, http.StatusInternalServerError, "Internal error, please try again later.")
+		return
+	}
+	query := target.Query()
+	for k, v := range values {
+		query[k] = v
+	}
+	if params.state != "" {
+		query.Set("state", params.state)
+	}
+	target.RawQuery = query.Encode()
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target.String(), code)
+}
+
+// token обменивает код авторизации на токен. Конфиденциальное приложение передаёт секрет в заголовке
+// Authorization (Basic) или в форме; публичные клиенты секрет не передают и защищены PKCE.
+func (h *Handler) token(w http.ResponseWriter, r *http.Request) {
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/x-www-form-urlencoded" {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "content type must be application/x-www-form-urlencoded")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "malformed form")
+		return
+	}
+
+	clientID, secret := r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	basicID, basicSecret, basic := r.BasicAuth()
+	if basic {
+		id, errID := url.QueryUnescape(basicID) // Учётные данные Basic кодируются как в форме (RFC 6749, раздел 2.3.1)
+		sec, errSecret := url.QueryUnescape(basicSecret)
+		if errID != nil || errSecret != nil || (clientID != "" && clientID != id) || secret != "" {
+			writeTokenError(w, http.StatusBadRequest, "invalid_request", "conflicting client credentials")
+			return
+		}
+		clientID, secret = id, sec
+	}
+
+	if grant := r.PostForm.Get("grant_type"); grant != oauth.GrantAuthorizationCode {
+		writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+	id, err := strconv.Atoi(clientID)
+	if err != nil {
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client", "client_id is required")
+		return
+	}
+	req := oauth.TokenRequest{
+		ClientID:     id,
+		ClientSecret: secret,
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	}
+	if req.Code == "" || req.RedirectURI == "" || req.CodeVerifier == "" {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "code, redirect_uri and code_verifier are required")
+		return
+	}
+
+	tok, err := h.svc.Exchange(r.Context(), req)
+	switch {
+	case errors.Is(err, oauth.ErrUnknownClient), errors.Is(err, oauth.ErrInvalidClient):
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	case errors.Is(err, oauth.ErrInvalidGrant),
+		errors.Is(err, storage.ErrUserNotFound),
+		errors.Is(err, auth.ErrUserPendingVerification),
+		errors.Is(err, auth.ErrUserSuspended),
+		errors.Is(err, auth.ErrUserLocked),
+		errors.Is(err, auth.ErrUserDeleted):
+		writeTokenError(w, http.StatusBadRequest, "invalid_grant", "authorization code is invalid, expired or reused")
+		return
+	case err != nil:
+		requestid.Logger(r.Context(), h.log).Error("token exchange failed", slog.String("error", err.Error()))
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	writeTokenJSON(w, http.StatusOK, tokenResponse{
+		AccessToken: tok.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(tok.ExpiresIn / time.Second),
+	})
+}
+
+// writeTokenError отдаёт ошибку конечной точки токена
+func writeTokenError(w http.ResponseWriter, code int, kind, description string) {
+	writeTokenJSON(w, code, errorResponse{Error: kind, Description: description})
+}
+
+// writeTokenJSON отдаёт ответ конечной точки токена; ответы с токенами не кэшируются (RFC 6749, раздел 5.1)
+func writeTokenJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newCSRFToken создаёт случайный токен CSRF
+func newCSRFToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,78 @@
+package oauthhttp
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+)
+
+// pageTemplate - страница входа и согласия. Стили встроены, скрипты не используются:
+// политика безопасности содержимого запрещает всё остальное.
+var pageTemplate = template.Must(template.New("authorize").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .Fatal}}Authorization error{{else}}Sign in to {{.AppName}}{{end}}</title>
+<style>
+body{font-family:system-ui,sans-serif;max-width:22rem;margin:4rem auto;padding:0 1rem;color:#222}
+label{display:block;margin:.75rem 0}
+input{display:block;width:100%;box-sizing:border-box;padding:.5rem;margin-top:.25rem}
+button{width:100%;padding:.6rem;margin-top:.5rem}
+.error{color:#b00020}
+</style>
+</head>
+<body>
+{{if .Fatal}}
+<h1>Authorization error</h1>
+<p class="error">{{.Fatal}}</p>
+{{else}}
+<h1>Sign in</h1>
+<p><strong>{{.AppName}}</strong> is requesting access to your account.</p>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+<form method="post" action="{{.Action}}">
+{{range .Hidden}}<input type="hidden" name="{{.Name}}" value="{{.Value}}">
+{{end}}<label>Email<input type="email" name="email" value="{{.Email}}" autocomplete="username" required autofocus></label>
+<label>Password<input type="password" name="password" autocomplete="current-password" required></label>
+<button type="submit" name="action" value="allow">Sign in and allow</button>
+<button type="submit" name="action" value="deny" formnovalidate>Deny</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// field - скрытое поле формы
+type field struct {
+	Name  string
+	Value string
+}
+
+// page - данные страницы входа и согласия
+type page struct {
+	AppName string  // Название приложения, запросившего доступ
+	Action  string  // Адрес отправки формы
+	Hidden  []field // Параметры запроса авторизации и токен CSRF
+	Email   string  // Введённый адрес при повторном показе
+	Error   string  // Сообщение об ошибке входа
+	Fatal   string  // Ошибка, при которой форма не показывается
+}
+
+// render отдаёт страницу. Страницу нельзя встроить в чужой сайт и сохранить в кэше:
+// иначе её можно использовать для кликджекинга, а токен CSRF останется в кэше.
+func (h *Handler) render(w http.ResponseWriter, code int, p page) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(code)
+	if err := pageTemplate.Execute(w, p); err != nil {
+		h.log.Error("failed to render authorization page", slog.String("error", err.Error()))
+	}
+}
+
+// fail отдаёт страницу ошибки, не возвращая пользователя в приложение
+func (h *Handler) fail(w http.ResponseWriter, code int, message string) {
+	h.render(w, code, page{Fatal: message})
+}
@@ -0,0 +1,17 @@
+package actor
+
+import "context"
+
+// key - ключ идентификатора пользователя, выполняющего запрос, в контексте
+type key struct{}
+
+// NewContext возвращает контекст с идентификатором пользователя, от имени которого выполняется запрос
+func NewContext(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, key{}, userID)
+}
+
+// FromContext возвращает идентификатор пользователя, выполняющего запрос
+func FromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(key{}).(int64)
+	return id, ok
+}
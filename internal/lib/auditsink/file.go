@@ -0,0 +1,48 @@
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/logger"
+)
+
+// File пишет события в файл JSONL, по записи на строку, с ротацией по размеру
+type File struct {
+	file *logger.RotatingFile
+}
+
+// NewFile открывает файл на дозапись; maxSize и maxBackups - как у logger.OpenRotatingFile
+func NewFile(path string, maxSize int64, maxBackups int) (*File, error) {
+	const op = "auditsink.NewFile"
+
+	f, err := logger.OpenRotatingFile(path, maxSize, maxBackups)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &File{file: f}, nil
+}
+
+// Send дописывает события. Каждая строка пишется одним вызовом, чтобы ротация не разрезала запись.
+func (f *File) Send(_ context.Context, events []models.AuditEvent) error {
+	const op = "auditsink.File.Send"
+
+	var line bytes.Buffer
+	for _, r := range records(events) {
+		line.Reset()
+		if err := json.NewEncoder(&line).Encode(r); err != nil { // Encode добавляет перевод строки
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if _, err := f.file.Write(line.Bytes()); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
+}
+
+// Close закрывает файл
+func (f *File) Close() error {
+	return f.file.Close()
+}
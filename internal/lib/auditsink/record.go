@@ -0,0 +1,65 @@
+package auditsink
+
+import (
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"time"
+)
+
+// Record - событие аудита в том виде, в каком оно уходит во внешние системы и в очередь на диске.
+// Получатель может отбросить повторы по seq: доставка гарантируется «хотя бы один раз».
+type Record struct {
+	Seq       int64             `json:"seq"`
+	Time      time.Time         `json:"time"`
+	Type      string            `json:"type"`
+	Outcome   string            `json:"outcome"`
+	ActorID   int64             `json:"actor_id"`
+	SubjectID int64             `json:"subject_id"`
+	AppID     int               `json:"app_id"`
+	RequestID string            `json:"request_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
+
+// NewRecord переводит событие в формат для отправки
+func NewRecord(e models.AuditEvent) Record {
+	return Record{
+		Seq:       e.Seq,
+		Time:      e.Time.UTC(),
+		Type:      string(e.Type),
+		Outcome:   string(e.Outcome),
+		ActorID:   e.ActorID,
+		SubjectID: e.SubjectID,
+		AppID:     e.AppID,
+		RequestID: e.RequestID,
+		Details:   e.Details,
+		PrevHash:  e.PrevHash,
+		Hash:      e.Hash,
+	}
+}
+
+// Event возвращает событие, из которого получена запись
+func (r Record) Event() models.AuditEvent {
+	return models.AuditEvent{
+		Seq:       r.Seq,
+		Time:      r.Time,
+		Type:      models.AuditEventType(r.Type),
+		Outcome:   models.AuditOutcome(r.Outcome),
+		ActorID:   r.ActorID,
+		SubjectID: r.SubjectID,
+		AppID:     r.AppID,
+		RequestID: r.RequestID,
+		Details:   r.Details,
+		PrevHash:  r.PrevHash,
+		Hash:      r.Hash,
+	}
+}
+
+// records переводит события в формат для отправки
+func records(events []models.AuditEvent) []Record {
+	out := make([]Record, len(events))
+	for i, e := range events {
+		out[i] = NewRecord(e)
+	}
+	return out
+}
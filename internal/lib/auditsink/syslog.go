@@ -0,0 +1,198 @@
+package auditsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Коды средств (facility) syslog по RFC 5424
+var facilities = map[string]int{
+	"auth":     4,
+	"authpriv": 10,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// Уровни важности сообщений: отказы и ошибки заметнее успешных действий
+const (
+	severityWarning = 4
+	severityNotice  = 5
+)
+
+// sdID - идентификатор структурированных данных; 32473 - номер предприятия для примеров из RFC 5612
+const sdID = "sso@32473"
+
+// dialTimeout ограничивает подключение к серверу syslog
+const dialTimeout = 5 * time.Second
+
+var (
+	ErrUnknownFacility = errors.New("unknown syslog facility") // Средство не из списка facilities
+	ErrUnknownNetwork  = errors.New("unknown syslog network")  // Сеть не udp, tcp или unix
+)
+
+// maxDatagram ограничивает сообщение в датаграмме: более длинные серверы syslog обрезают или отбрасывают
+const maxDatagram = 8192
+
+// Syslog отправляет события на сервер syslog в формате RFC 5424. Ключевые поля события передаются
+// структурированными данными, всё событие - JSON в тексте сообщения.
+// По tcp сообщения разделяются счётчиком октетов (RFC 6587), по udp и unix - по одному в датаграмме.
+type Syslog struct {
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn // Открывается при первой отправке и после ошибки
+}
+
+// NewSyslog создаёт отправителя; network - udp, tcp или unix, facility - имя из facilities
+func NewSyslog(network, address, facility, appName string) (*Syslog, error) {
+	const op = "auditsink.NewSyslog"
+
+	code, ok := facilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownFacility, facility)
+	}
+	switch network {
+	case "udp", "tcp", "unix":
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownNetwork, network)
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-" // NILVALUE по RFC 5424
+	}
+	return &Syslog{network: network, address: address, facility: code, appName: appName, hostname: hostname}, nil
+}
+
+// Send отправляет события по одному сообщению. При ошибке соединение закрывается
+// и открывается заново при следующей отправке.
+func (s *Syslog) Send(ctx context.Context, events []models.AuditEvent) error {
+	const op = "auditsink.Syslog.Send"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		s.conn = conn
+	}
+	deadline, _ := ctx.Deadline() // Без срока у ctx нулевое значение снимает прежний срок
+	_ = s.conn.SetWriteDeadline(deadline)
+	for _, e := range events {
+		msg, err := s.format(e, true)
+		if err != nil {
+			return fmt.Errorf("%s: seq %d: %w", op, e.Seq, err)
+		}
+		if s.network == "tcp" {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		} else if len(msg) > maxDatagram {
+			msg, _ = s.format(e, false) // Ключевые поля остаются в структурированных данных
+		}
+		if _, err := s.conn.Write(msg); err != nil {
+			_ = s.conn.Close()
+			s.conn = nil
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
+}
+
+// Close закрывает соединение
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// dial подключается к серверу syslog; для unix - к датаграммному сокету, как /dev/log
+func (s *Syslog) dial(ctx context.Context) (net.Conn, error) {
+	network := s.network
+	if network == "unix" {
+		network = "unixgram"
+	}
+	d := net.Dialer{Timeout: dialTimeout}
+	return d.DialContext(ctx, network, s.address)
+}
+
+// format собирает сообщение RFC 5424:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [sso@32473 ...] JSON.
+// Без withBody событие в JSON не добавляется.
+func (s *Syslog) format(e models.AuditEvent, withBody bool) ([]byte, error) {
+	var body []byte
+	if withBody {
+		var err error
+		if body, err = json.Marshal(NewRecord(e)); err != nil {
+			return nil, err
+		}
+	}
+	severity := severityNotice
+	if e.Outcome != models.AuditSuccess {
+		severity = severityWarning
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d %s ",
+		s.facility*8+severity,
+		e.Time.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		header(s.appName, 48),
+		os.Getpid(),
+		header(string(e.Type), 32),
+	)
+	fmt.Fprintf(&b, `[%s seq="%d" outcome="%s" actor_id="%d" subject_id="%d" app_id="%d" request_id="%s" hash="%s"]`,
+		sdID, e.Seq, sdEscape(string(e.Outcome)), e.ActorID, e.SubjectID, e.AppID, sdEscape(e.RequestID), e.Hash)
+	if len(body) > 0 {
+		b.WriteByte(' ')
+		b.Write(body)
+	}
+	return []byte(b.String()), nil
+}
+
+// header приводит значение поля заголовка к печатным ASCII символам без пробелов и ограничивает длину
+func header(s string, limit int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	if len(s) > limit {
+		s = s[:limit]
+	}
+	return s
+}
+
+// sdEscape экранирует значение параметра структурированных данных
+func sdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
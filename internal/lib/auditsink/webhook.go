@@ -0,0 +1,47 @@
+package auditsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/webhook"
+	"net/http"
+	"time"
+)
+
+// Webhook отправляет события POST запросом с телом {"events": [...]}, подписанным секретом,
+// как описано в пакете webhook
+type Webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhook создаёт отправителя; timeout ограничивает один запрос
+func NewWebhook(url string, secret string, timeout time.Duration) *Webhook {
+	return &Webhook{url: url, secret: []byte(secret), client: &http.Client{Timeout: timeout}}
+}
+
+// Send отправляет события одним запросом. Любой ответ, кроме 2xx, считается неудачей:
+// события будут отправлены повторно.
+func (w *Webhook) Send(ctx context.Context, events []models.AuditEvent) error {
+	const op = "auditsink.Webhook.Send"
+
+	body, err := json.Marshal(struct {
+		Events []Record `json:"events"`
+	}{Events: records(events)})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := webhook.Post(ctx, w.client, w.url, w.secret, body, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Close закрывает простаивающие соединения
+func (w *Webhook) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,27 @@
+package bearer
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+const (
+	header = "authorization" // Ключ метаданных с токеном
+	scheme = "bearer"        // Схема авторизации
+)
+
+// FromIncomingContext извлекает токен из метаданных вида "authorization: Bearer <token>"
+func FromIncomingContext(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, value := range md.Get(header) {
+		prefix, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(prefix, scheme) && token != "" {
+			return strings.TrimSpace(token), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,33 @@
+package clientapp
+
+import "context"
+
+type (
+	key       struct{} // Ключ приложения, подтверждённого клиентским сертификатом
+	originKey struct{} // Ключ приложения, названного заголовком Origin
+)
+
+// NewContext возвращает контекст с идентификатором приложения, подтверждённым клиентским сертификатом
+func NewContext(ctx context.Context, appID int) context.Context {
+	return context.WithValue(ctx, key{}, appID)
+}
+
+// FromContext возвращает идентификатор приложения, если вызывающий сервис предъявил сопоставленный ему сертификат
+func FromContext(ctx context.Context) (int, bool) {
+	appID, ok := ctx.Value(key{}).(int)
+	return appID, ok
+}
+
+// NewOriginContext возвращает контекст с идентификатором приложения, которому сопоставлен Origin запроса.
+// Origin не подтверждён: его подделывает любой клиент, кроме браузера.
+func NewOriginContext(ctx context.Context, appID int) context.Context {
+	return context.WithValue(ctx, originKey{}, appID)
+}
+
+// OriginFromContext возвращает идентификатор приложения, которому сопоставлен Origin запроса.
+// Значение годится только для ограничений, защищающих пользователей браузера (чужая страница
+// не войдёт от их имени в другое приложение), и не подтверждает, кто вызывает сервис.
+func OriginFromContext(ctx context.Context) (int, bool) {
+	appID, ok := ctx.Value(originKey{}).(int)
+	return appID, ok
+}
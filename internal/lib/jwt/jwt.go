@@ -1,12 +1,25 @@
 package jwt
 
 import (
+	"errors"                                             // Пакет для работы с ошибками
+	"fmt"                                                // Пакет для форматирования ошибок
 	"github.com/golang-jwt/jwt/v5"                       // Подключение библиотеки для работы с JWT токенами
 	"github.com/linemk/gRPC_auth/internal/domain/models" // Подключение моделей приложения
 	"time"                                               // Подключение пакета для работы с временем
 )
 
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
+// ErrInvalidToken возвращается, если токен не прошёл проверку
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims содержит данные проверенного токена
+type Claims struct {
+	UserID    int64  // Идентификатор пользователя
+	Email     string // Электронная почта пользователя
+	AppID     int    // Идентификатор приложения, выпустившего токен
+	SessionID string // Идентификатор сеанса, к которому привязан токен
+}
+
+func NewToken(user models.User, app models.App, sessionID string, duration time.Duration) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256) // Создание нового JWT токена с алгоритмом подписи HS256
 	claims := token.Claims.(jwt.MapClaims)   // Инициализация claims (данных, содержащихся в токене) как MapClaims
 
@@ -14,6 +27,7 @@ func NewToken(user models.User, app models.App, duration time.Duration) (string,
 	claims["email"] = user.Email                    // Установка электронной почты пользователя в claims
 	claims["exp"] = time.Now().Add(duration).Unix() // Установка времени истечения срока действия токена
 	claims["app_id"] = app.ID                       // Установка идентификатора приложения в claims
+	claims["jti"] = sessionID                       // Установка идентификатора сеанса в claims
 
 	// Подписание токена с использованием секрета приложения
 	tokenString, err := token.SignedString([]byte(app.Secret))
@@ -22,3 +36,35 @@ func NewToken(user models.User, app models.App, duration time.Duration) (string,
 	}
 	return tokenString, nil // Возвращение подписанного токена
 }
+
+// ParseToken проверяет подпись и срок действия токена.
+// Секрет для проверки подписи запрашивается у secret по app_id из токена.
+func ParseToken(tokenString string, secret func(appID int) (string, error)) (Claims, error) {
+	var claims jwt.MapClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
+		appID, ok := claims["app_id"].(float64) // Числа в JSON декодируются как float64
+		if !ok {
+			return nil, errors.New("app_id claim is missing")
+		}
+		key, err := secret(int(appID))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(key), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), // Принимаем только алгоритм, которым подписываем
+		jwt.WithExpirationRequired(),                                 // Токен без срока действия недействителен
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	uid, okUID := claims["uid"].(float64)
+	appID, okApp := claims["app_id"].(float64)
+	sessionID, _ := claims["jti"].(string)
+	email, _ := claims["email"].(string)
+	if !okUID || !okApp || sessionID == "" {
+		return Claims{}, fmt.Errorf("%w: required claims are missing", ErrInvalidToken)
+	}
+	return Claims{UserID: int64(uid), Email: email, AppID: int(appID), SessionID: sessionID}, nil
+}
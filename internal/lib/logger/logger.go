@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Форматы журнала
+const (
+	FormatText = "text" // Текст key=value для разработки
+	FormatJSON = "json" // JSON по строке на запись для сборщиков журналов
+)
+
+// Выходы журнала
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file" // Файл с ротацией по размеру
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown log format") // Формат не text и не json
+	ErrUnknownOutput = errors.New("unknown log output") // Выход не stdout, stderr или file
+)
+
+// Config описывает журнал приложения
+type Config struct {
+	Format     string   // text или json
+	Output     string   // stdout, stderr или file
+	File       string   // Путь к файлу журнала для выхода file
+	MaxSize    int64    // Размер файла в байтах, после которого он ротируется; 0 - без ротации
+	MaxBackups int      // Сколько ротированных файлов хранить
+	Sampling   Sampling // Ограничение частых записей; Initial 0 - без ограничения
+	Redact     Policy   // Что скрывать в записях
+}
+
+// New создает логгер с уровнем level. Возвращаемый io.Closer закрывает файл журнала;
+// для stdout и stderr он ничего не делает.
+func New(cfg Config, level slog.Leveler) (*slog.Logger, io.Closer, error) {
+	const op = "logger.New"
+
+	var (
+		w      io.Writer
+		closer io.Closer = nopCloser{}
+	)
+	switch cfg.Output {
+	case OutputStdout, "":
+		w = os.Stdout
+	case OutputStderr:
+		w = os.Stderr
+	case OutputFile:
+		f, err := OpenRotatingFile(cfg.File, cfg.MaxSize, cfg.MaxBackups)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		w, closer = f, f
+	default:
+		return nil, nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownOutput, cfg.Output)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch cfg.Format {
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON, "":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		_ = closer.Close()
+		return nil, nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownFormat, cfg.Format)
+	}
+
+	if cfg.Sampling.Initial > 0 {
+		h = NewSampleHandler(h, cfg.Sampling)
+	}
+	if cfg.Redact.enabled() {
+		h = NewRedactHandler(h, cfg.Redact) // Снаружи, чтобы атрибуты скрывались и в With
+	}
+	return slog.New(h), closer, nil
+}
+
+// nopCloser - io.Closer для стандартных потоков, которые не закрываются
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Способы скрытия email
+const (
+	EmailNone = "none" // Email пишется как есть
+	EmailMask = "mask" // j***@example.com: домен остаётся для разбора проблем с почтовыми провайдерами
+	EmailHash = "hash" // sha256:<12 hex>: записи одного пользователя можно связать, не зная адреса
+)
+
+// Способы скрытия токенов
+const (
+	TokensRedact = "redact" // Токены, пароли и заголовки авторизации заменяются на [REDACTED]
+	TokensNone   = "none"   // Токены пишутся как есть; только для локальной отладки
+)
+
+// redacted заменяет скрытое значение
+const redacted = "[REDACTED]"
+
+// secretKeys - ключи атрибутов, значения которых скрываются целиком
+var secretKeys = map[string]bool{
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"password":      true,
+	"authorization": true,
+	"secret":        true,
+	"client_secret": true,
+}
+
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	tokenPattern  = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`) // JWT
+	bearerPattern = regexp.MustCompile(`(?i)bearer\s+\S+`)
+)
+
+// Policy задаёт, как скрываются персональные данные и секреты в записях журнала.
+// Email ищутся в сообщении и в значениях атрибутов, включая ошибки, Stringer и составные значения;
+// токены - по ключам атрибутов и по виду значения (JWT, Bearer).
+type Policy struct {
+	Emails string // none, mask или hash
+	Tokens string // redact или none
+}
+
+// enabled сообщает, нужно ли что-то скрывать
+func (p Policy) enabled() bool {
+	return (p.Emails != "" && p.Emails != EmailNone) || p.Tokens == TokensRedact
+}
+
+// redactHandler скрывает значения по Policy перед передачей записи следующему обработчику
+type redactHandler struct {
+	next   slog.Handler
+	policy Policy
+}
+
+// NewRedactHandler оборачивает next скрытием email и токенов
+func NewRedactHandler(next slog.Handler, policy Policy) slog.Handler {
+	return &redactHandler{next: next, policy: policy}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, h.redactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redactedAttrs[i] = h.redactAttr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redactedAttrs), policy: h.policy}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), policy: h.policy}
+}
+
+// redactAttr скрывает значение атрибута; группы обходятся рекурсивно
+func (h *redactHandler) redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if h.policy.Tokens == TokensRedact && secretKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			attrs[i] = h.redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}
+	case slog.KindString:
+		return slog.String(a.Key, h.redactString(a.Value.String()))
+	case slog.KindAny:
+		// Ошибки, Stringer, структуры, срезы и карты приводятся к тексту; если в тексте есть что скрыть,
+		// атрибут пишется скрытой строкой. Ключи вложенных полей не проверяются, только вид значений.
+		text := formatAny(a.Value.Any())
+		if redactedText := h.redactString(text); redactedText != text {
+			return slog.String(a.Key, redactedText)
+		}
+		if _, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, text)
+		}
+	}
+	return a
+}
+
+// formatAny возвращает текст значения атрибута так, как его увидит читатель журнала
+func formatAny(v any) string {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	case []byte:
+		return string(v)
+	}
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+// redactString скрывает email и токены внутри строки
+func (h *redactHandler) redactString(s string) string {
+	if h.policy.Tokens == TokensRedact {
+		s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+		s = tokenPattern.ReplaceAllString(s, redacted)
+	}
+	switch h.policy.Emails {
+	case EmailMask:
+		s = emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+	case EmailHash:
+		s = emailPattern.ReplaceAllStringFunc(s, HashEmail)
+	}
+	return s
+}
+
+// MaskEmail оставляет первый символ имени и домен: j***@example.com
+func MaskEmail(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 {
+		return redacted
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// HashEmail заменяет email коротким хэшем адреса без учёта регистра
+func HashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
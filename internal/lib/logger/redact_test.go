@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+const testJWT = "eyJhbGciOiJIUzI1NiJ9.eyJ1aWQiOjF9.c2lnbmF0dXJl"
+
+// profile - составное значение атрибута с email внутри
+type profile struct {
+	ID    int64
+	Email string
+}
+
+// contact - Stringer с email внутри
+type contact string
+
+func (c contact) String() string { return "contact <" + string(c) + ">" }
+
+func TestRedactHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		log     func(l *slog.Logger)
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "mask email in message and string attr",
+			policy:  Policy{Emails: EmailMask},
+			log:     func(l *slog.Logger) { l.Info("login john@example.com", slog.String("email", "john@example.com")) },
+			want:    []string{"j***@example.com"},
+			notWant: []string{"john@example.com"},
+		},
+		{
+			name:    "hash email",
+			policy:  Policy{Emails: EmailHash},
+			log:     func(l *slog.Logger) { l.Info("login", slog.String("email", "John@Example.com")) },
+			want:    []string{HashEmail("john@example.com")},
+			notWant: []string{"Example.com"},
+		},
+		{
+			name:    "email in error",
+			policy:  Policy{Emails: EmailMask},
+			log:     func(l *slog.Logger) { l.Error("failed", slog.Any("error", errors.New("user john@example.com exists"))) },
+			want:    []string{"user j***@example.com exists"},
+			notWant: []string{"john@example.com"},
+		},
+		{
+			name:    "email in struct",
+			policy:  Policy{Emails: EmailMask},
+			log:     func(l *slog.Logger) { l.Info("user", slog.Any("user", profile{ID: 1, Email: "john@example.com"})) },
+			want:    []string{"j***@example.com"},
+			notWant: []string{"john@example.com"},
+		},
+		{
+			name:    "email in slice",
+			policy:  Policy{Emails: EmailHash},
+			log:     func(l *slog.Logger) { l.Info("users", slog.Any("emails", []string{"a@example.com", "b@example.com"})) },
+			want:    []string{HashEmail("a@example.com"), HashEmail("b@example.com")},
+			notWant: []string{"a@example.com", "b@example.com"},
+		},
+		{
+			name:    "email in stringer",
+			policy:  Policy{Emails: EmailMask},
+			log:     func(l *slog.Logger) { l.Info("mail", slog.Any("to", contact("john@example.com"))) },
+			want:    []string{"contact <j***@example.com>"},
+			notWant: []string{"john@example.com"},
+		},
+		{
+			name:   "struct without secrets is kept",
+			policy: Policy{Emails: EmailMask, Tokens: TokensRedact},
+			log:    func(l *slog.Logger) { l.Info("user", slog.Any("user", profile{ID: 7})) },
+			want:   []string{`user="{ID:7 Email:}"`},
+		},
+		{
+			name:   "email in group and With",
+			policy: Policy{Emails: EmailMask},
+			log: func(l *slog.Logger) {
+				l.With("email", "john@example.com").Info("x", slog.Group("g", "to", "ann@example.com"))
+			},
+			want:    []string{"j***@example.com", "a***@example.com"},
+			notWant: []string{"john@example.com", "ann@example.com"},
+		},
+		{
+			name:    "secret keys",
+			policy:  Policy{Tokens: TokensRedact},
+			log:     func(l *slog.Logger) { l.Info("x", slog.String("password", "p4ss"), slog.Any("Client_Secret", 42)) },
+			want:    []string{"password=" + redacted, "Client_Secret=" + redacted},
+			notWant: []string{"p4ss", "42"},
+		},
+		{
+			name:   "jwt and bearer in values",
+			policy: Policy{Tokens: TokensRedact},
+			log: func(l *slog.Logger) {
+				l.Info("x", slog.String("header", "Bearer abc"), slog.Any("raw", []byte(testJWT)))
+			},
+			want:    []string{"Bearer " + redacted},
+			notWant: []string{"abc", testJWT},
+		},
+		{
+			name:   "tokens none",
+			policy: Policy{Emails: EmailNone, Tokens: TokensNone},
+			log: func(l *slog.Logger) {
+				l.Info("x", slog.String("token", testJWT), slog.String("email", "john@example.com"))
+			},
+			want: []string{testJWT, "john@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewRedactHandler(slog.NewTextHandler(&buf, nil), tt.policy)))
+			out := buf.String()
+			for _, s := range tt.want {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tt.notWant {
+				assert.NotContains(t, out, s)
+			}
+		})
+	}
+}
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"john@example.com", "j***@example.com"},
+		{"a@b.io", "a***@b.io"},
+		{"@example.com", redacted},
+		{"no-at-sign", redacted},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MaskEmail(tt.in), tt.in)
+	}
+}
+
+func TestHashEmail(t *testing.T) {
+	h := HashEmail("John@Example.com")
+	require.True(t, strings.HasPrefix(h, "sha256:"))
+	assert.Len(t, h, len("sha256:")+12)
+	assert.Equal(t, h, HashEmail("john@example.com"))
+	assert.NotEqual(t, h, HashEmail("jane@example.com"))
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// RotatingFile - файл журнала с ротацией по размеру. Когда очередная запись превысила бы maxSize,
+// файл переименовывается в <path>.1, прежний <path>.1 - в <path>.2 и так далее до maxBackups;
+// более старые файлы удаляются.
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// OpenRotatingFile открывает файл журнала на дозапись, создавая каталог при необходимости
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	const op = "logger.OpenRotatingFile"
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	f := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return f, nil
+}
+
+// Write дописывает запись, предварительно ротируя файл, если он переполнится
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		// Если ротация не удалась, но прежний файл снова открыт, запись не теряется: ротация повторится при следующей записи
+		if err := f.rotate(); err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close закрывает файл; последующие записи возвращают os.ErrClosed
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open открывает файл журнала и запоминает его размер
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate сдвигает ротированные файлы и начинает новый файл журнала. Если сдвинуть файлы
+// не удалось, снова открывается прежний файл, чтобы журнал не перестал писаться.
+func (f *RotatingFile) rotate() error {
+	closeErr := f.file.Close()
+	f.file = nil
+	if closeErr == nil {
+		if err := f.shift(); err != nil {
+			return errors.Join(err, f.open())
+		}
+	}
+	return errors.Join(closeErr, f.open())
+}
+
+// shift вытесняет самый старый файл и сдвигает номера остальных; текущий файл становится <path>.1
+func (f *RotatingFile) shift() error {
+	if f.maxBackups <= 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	_ = os.Remove(f.backup(f.maxBackups)) // Самый старый файл вытесняется
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(f.backup(i), f.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(f.path, f.backup(1))
+}
+
+// backup возвращает имя ротированного файла с номером i
+func (f *RotatingFile) backup(i int) string {
+	return f.path + "." + strconv.Itoa(i)
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sso.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	for file, want := range map[string]string{path: "third\n", path + ".1": "second\n", path + ".2": "first\n"} {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data), file)
+	}
+}
+
+func TestRotatingFile_RotateFailure_KeepsWriting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sso.log")
+	// Каталог на месте <path>.1 не даёт переименовать файл журнала
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "busy"), 0o755))
+
+	f, err := OpenRotatingFile(path, 10, 1)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\nthird\n", string(data))
+
+	// Когда причина устранена, ротация выполняется при следующей записи
+	require.NoError(t, os.RemoveAll(path+".1"))
+	_, err = f.Write([]byte("fourth\n"))
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(data))
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Sampling ограничивает частые записи уровней debug и info, например журнал каждого вызова:
+// за каждый интервал Tick пишутся первые Initial записей с одним сообщением, затем каждая Thereafter-я.
+// Предупреждения и ошибки пишутся всегда.
+type Sampling struct {
+	Initial    int           // Сколько одинаковых записей пишется за интервал без ограничения
+	Thereafter int           // После Initial пишется каждая Thereafter-я запись; 0 - ни одной
+	Tick       time.Duration // Интервал, после которого счётчики сбрасываются
+}
+
+// sampleHandler пропускает записи по правилам Sampling
+type sampleHandler struct {
+	next    slog.Handler
+	cfg     Sampling
+	counter *sampleCounter // Общий для производных обработчиков With и WithGroup
+}
+
+// sampleCounter считает записи с одним уровнем и сообщением в текущем интервале
+type sampleCounter struct {
+	mu     sync.Mutex
+	window time.Time      // Начало текущего интервала
+	counts map[string]int // Уровень и сообщение -> число записей в интервале
+}
+
+// NewSampleHandler оборачивает next ограничением частых записей
+func NewSampleHandler(next slog.Handler, cfg Sampling) slog.Handler {
+	return &sampleHandler{next: next, cfg: cfg, counter: &sampleCounter{counts: make(map[string]int)}}
+}
+
+func (h *sampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *sampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn || h.counter.allow(r.Level.String()+"\x00"+r.Message, r.Time, h.cfg) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (h *sampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampleHandler{next: h.next.WithAttrs(attrs), cfg: h.cfg, counter: h.counter}
+}
+
+func (h *sampleHandler) WithGroup(name string) slog.Handler {
+	return &sampleHandler{next: h.next.WithGroup(name), cfg: h.cfg, counter: h.counter}
+}
+
+// allow решает, писать ли очередную запись с ключом key
+func (c *sampleCounter) allow(key string, now time.Time, cfg Sampling) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.window) >= cfg.Tick { // Новый интервал: счётчики прошлого больше не нужны
+		c.window = now
+		clear(c.counts)
+	}
+	c.counts[key]++
+	n := c.counts[key]
+	if n <= cfg.Initial {
+		return true
+	}
+	return cfg.Thereafter > 0 && (n-cfg.Initial)%cfg.Thereafter == 0
+}
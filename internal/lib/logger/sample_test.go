@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampleHandler(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// entry - запись журнала со сдвигом времени от start
+	type entry struct {
+		level slog.Level
+		msg   string
+		at    time.Duration
+	}
+	repeat := func(n int, e entry) []entry {
+		out := make([]entry, n)
+		for i := range out {
+			out[i] = e
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Sampling
+		entries []entry
+		want    int // Сколько записей дошло до следующего обработчика
+	}{
+		{
+			name:    "initial only",
+			cfg:     Sampling{Initial: 3, Tick: time.Second},
+			entries: repeat(10, entry{level: slog.LevelInfo, msg: "call"}),
+			want:    3,
+		},
+		{
+			name:    "every thereafter",
+			cfg:     Sampling{Initial: 2, Thereafter: 3, Tick: time.Second},
+			entries: repeat(11, entry{level: slog.LevelInfo, msg: "call"}), // 1, 2, 5, 8, 11
+			want:    5,
+		},
+		{
+			name:    "warnings always pass",
+			cfg:     Sampling{Initial: 1, Tick: time.Second},
+			entries: repeat(5, entry{level: slog.LevelWarn, msg: "slow"}),
+			want:    5,
+		},
+		{
+			name: "messages counted separately",
+			cfg:  Sampling{Initial: 1, Tick: time.Second},
+			entries: []entry{
+				{level: slog.LevelInfo, msg: "a"},
+				{level: slog.LevelInfo, msg: "a"},
+				{level: slog.LevelInfo, msg: "b"},
+				{level: slog.LevelDebug, msg: "a"},
+			},
+			want: 3,
+		},
+		{
+			name: "counters reset after tick",
+			cfg:  Sampling{Initial: 1, Tick: time.Second},
+			entries: []entry{
+				{level: slog.LevelInfo, msg: "call"},
+				{level: slog.LevelInfo, msg: "call", at: 500 * time.Millisecond},
+				{level: slog.LevelInfo, msg: "call", at: time.Second},
+				{level: slog.LevelInfo, msg: "call", at: 1500 * time.Millisecond},
+			},
+			want: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := NewSampleHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), tt.cfg)
+			for _, e := range tt.entries {
+				require.NoError(t, h.Handle(context.Background(), slog.NewRecord(start.Add(e.at), e.level, e.msg, 0)))
+			}
+			assert.Equal(t, tt.want, strings.Count(buf.String(), "\n"))
+		})
+	}
+}
+
+func TestSampleHandler_SharedCounter(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewSampleHandler(slog.NewTextHandler(&buf, nil), Sampling{Initial: 2, Tick: time.Minute}))
+
+	l.Info("call")
+	l.With("op", "a").Info("call")
+	l.WithGroup("grpc").Info("call")
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+}
@@ -0,0 +1,144 @@
+package passhash
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// Поддерживаемые алгоритмы хэширования паролей
+const (
+	Bcrypt       = "bcrypt"        // Текущая схема сервиса
+	PBKDF2SHA256 = "pbkdf2-sha256" // pbkdf2_sha256$<iterations>$<salt>$<base64 hash>
+	PBKDF2SHA512 = "pbkdf2-sha512" // pbkdf2_sha512$<iterations>$<salt>$<base64 hash>
+	SHA512Crypt  = "sha512-crypt"  // $6$[rounds=N$]<salt>$<hash>
+)
+
+// Current - алгоритм, которым хэшируются новые пароли
+const Current = Bcrypt
+
+// MaxPBKDF2Iterations - верхняя граница числа итераций PBKDF2. Стоимость проверки задаёт сам хэш,
+// поэтому без границы один импортированный хэш мог бы занять процессор на минуты при каждом входе.
+const MaxPBKDF2Iterations = 2_000_000
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")  // Алгоритм не поддерживается
+	ErrMalformedHash    = errors.New("malformed hash")          // Хэш не соответствует формату алгоритма
+	ErrMismatch         = errors.New("password does not match") // Пароль не совпадает с хэшем
+	ErrCostTooHigh      = errors.New("hash cost exceeds limit") // Число итераций хэша выше допустимого
+)
+
+// Hash хэширует пароль текущей схемой
+func Hash(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+// Detect определяет алгоритм по формату хэша
+func Detect(hash string) (string, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return Bcrypt, nil
+	case strings.HasPrefix(hash, "$6$"):
+		return SHA512Crypt, nil
+	case strings.HasPrefix(hash, "pbkdf2_sha256$"):
+		return PBKDF2SHA256, nil
+	case strings.HasPrefix(hash, "pbkdf2_sha512$"):
+		return PBKDF2SHA512, nil
+	}
+	return "", ErrUnknownAlgorithm
+}
+
+// Validate проверяет, что хэш соответствует формату алгоритма
+func Validate(algo, hash string) error {
+	switch algo {
+	case Bcrypt:
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedHash, err)
+		}
+		return nil
+	case PBKDF2SHA256, PBKDF2SHA512:
+		_, err := parsePBKDF2(algo, hash)
+		return err
+	case SHA512Crypt:
+		_, err := parseSHA512Crypt(hash)
+		return err
+	}
+	return ErrUnknownAlgorithm
+}
+
+// Verify сравнивает пароль с хэшем указанного алгоритма.
+// Возвращает nil при совпадении и ErrMismatch, если пароль неверный.
+func Verify(algo string, hashed []byte, password string) error {
+	switch algo {
+	case Bcrypt, "":
+		if err := bcrypt.CompareHashAndPassword(hashed, []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return ErrMismatch
+			}
+			return fmt.Errorf("%w: %v", ErrMalformedHash, err)
+		}
+		return nil
+	case PBKDF2SHA256, PBKDF2SHA512:
+		p, err := parsePBKDF2(algo, string(hashed))
+		if err != nil {
+			return err
+		}
+		return compare(pbkdf2.Key([]byte(password), []byte(p.salt), p.iterations, len(p.sum), p.hash), p.sum)
+	case SHA512Crypt:
+		p, err := parseSHA512Crypt(string(hashed))
+		if err != nil {
+			return err
+		}
+		return compare([]byte(sha512Crypt([]byte(password), p)), []byte(p.sum))
+	}
+	return ErrUnknownAlgorithm
+}
+
+// compare сравнивает значения за постоянное время
+func compare(got, want []byte) error {
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// pbkdf2Params - разобранный хэш PBKDF2 в формате Django
+type pbkdf2Params struct {
+	hash       func() hash.Hash
+	iterations int
+	salt       string
+	sum        []byte
+}
+
+// parsePBKDF2 разбирает хэш вида pbkdf2_sha256$<iterations>$<salt>$<base64 hash>
+func parsePBKDF2(algo, hashed string) (pbkdf2Params, error) {
+	prefix, h := "pbkdf2_sha256", sha256.New
+	if algo == PBKDF2SHA512 {
+		prefix, h = "pbkdf2_sha512", sha512.New
+	}
+
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 4 || parts[0] != prefix {
+		return pbkdf2Params{}, ErrMalformedHash
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return pbkdf2Params{}, ErrMalformedHash
+	}
+	if iterations > MaxPBKDF2Iterations {
+		return pbkdf2Params{}, fmt.Errorf("%w: %d iterations, max %d", ErrCostTooHigh, iterations, MaxPBKDF2Iterations)
+	}
+	sum, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil || len(sum) == 0 || parts[2] == "" {
+		return pbkdf2Params{}, ErrMalformedHash
+	}
+	return pbkdf2Params{hash: h, iterations: iterations, salt: parts[2], sum: sum}, nil
+}
@@ -0,0 +1,202 @@
+package passhash
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// django возвращает хэш PBKDF2 в формате Django для известного ответа в hex
+func django(t *testing.T, prefix string, iterations, salt, sumHex string) string {
+	t.Helper()
+	sum, err := hex.DecodeString(sumHex)
+	require.NoError(t, err)
+	return prefix + "$" + iterations + "$" + salt + "$" + base64.StdEncoding.EncodeToString(sum)
+}
+
+// Известные ответы PBKDF2-HMAC-SHA256 и PBKDF2-HMAC-SHA512 для пароля "password" с солью "salt"
+func TestVerify_PBKDF2(t *testing.T) {
+	tests := []struct {
+		name     string
+		algo     string
+		hash     string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "sha256 1 iteration",
+			algo:     PBKDF2SHA256,
+			hash:     django(t, "pbkdf2_sha256", "1", "salt", "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"),
+			password: "password",
+		},
+		{
+			name:     "sha256 4096 iterations",
+			algo:     PBKDF2SHA256,
+			hash:     django(t, "pbkdf2_sha256", "4096", "salt", "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"),
+			password: "password",
+		},
+		{
+			name: "sha512 1 iteration",
+			algo: PBKDF2SHA512,
+			hash: django(t, "pbkdf2_sha512", "1", "salt",
+				"867f70cf1ade02cff3752599a3a53dc4af34c7a669815ae5d513554e1c8cf252c02d470a285a0501bad999bfe943c08f050235d7d68b1da55e63f73b60a57fce"),
+			password: "password",
+		},
+		{
+			name:     "wrong password",
+			algo:     PBKDF2SHA256,
+			hash:     django(t, "pbkdf2_sha256", "1", "salt", "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"),
+			password: "Password",
+			wantErr:  ErrMismatch,
+		},
+		{
+			name:     "prefix of other algorithm",
+			algo:     PBKDF2SHA512,
+			hash:     django(t, "pbkdf2_sha256", "1", "salt", "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"),
+			password: "password",
+			wantErr:  ErrMalformedHash,
+		},
+		{name: "no iterations", algo: PBKDF2SHA256, hash: "pbkdf2_sha256$0$salt$AAAA", password: "password", wantErr: ErrMalformedHash},
+		{name: "no salt", algo: PBKDF2SHA256, hash: "pbkdf2_sha256$1$$AAAA", password: "password", wantErr: ErrMalformedHash},
+		{name: "bad base64", algo: PBKDF2SHA256, hash: "pbkdf2_sha256$1$salt$***", password: "password", wantErr: ErrMalformedHash},
+		{name: "missing part", algo: PBKDF2SHA256, hash: "pbkdf2_sha256$1$salt", password: "password", wantErr: ErrMalformedHash},
+		{name: "iterations above limit", algo: PBKDF2SHA512, hash: "pbkdf2_sha512$2000001$salt$AAAA", password: "password", wantErr: ErrCostTooHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.algo, []byte(tt.hash), tt.password)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+// Примеры из спецификации SHA-512-crypt Ульриха Дреппера; хэш приведён так, как его пишет crypt(3)
+func TestVerify_SHA512Crypt(t *testing.T) {
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "default rounds",
+			hash:     "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+			password: "Hello world!",
+		},
+		{
+			name:     "explicit rounds",
+			hash:     "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+			password: "Hello world!",
+		},
+		{
+			name:     "long password",
+			hash:     "$6$rounds=1400$anotherlongsalts$POfYwTEok97VWcjxIiSOjiykti.o/pQs.wPvMxQ6Fm7I6IoYN3CmLs66x9t0oSwbtEW7o7UmJEiDwGqd8p4ur1",
+			password: "a very much longer text to encrypt.  This one even stretches over morethan one line.",
+		},
+		{
+			name:     "short salt",
+			hash:     "$6$rounds=77777$short$WuQyW2YR.hBNpjjRhpYD/ifIw05xdfeEyQoMxIXbkvr0gge1a1x3yRULJ5CCaUeOxFmtlcGZelFl5CxtgfiAc0",
+			password: "we have a short salt string but not a short password",
+		},
+		{
+			name:     "salt is cut to 16 characters",
+			hash:     "$6$rounds=5000$toolongsaltstring$lQ8jolhgVRVhY4b5pZKaysCLi0QBxGoNeKQzQ3glMhwllF7oGDZxUhx1yxdYcz/e1JSbq3y6JMxxl8audkUEm0",
+			password: "This is just a test",
+		},
+		{
+			name:     "rounds below minimum are raised",
+			hash:     "$6$rounds=10$roundstoolow$kUMsbe306n21p9R.FRkW3IGn.S9NPN0x50YhH1xhLsPuWGsUSklZt58jaTfF4ZEQpyUNGc0dqbpBYYBaHHrsX.",
+			password: "the minimum number is still observed",
+		},
+		{
+			name:     "wrong password",
+			hash:     "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+			password: "Hello world",
+			wantErr:  ErrMismatch,
+		},
+		{name: "no hash part", hash: "$6$saltstring", password: "x", wantErr: ErrMalformedHash},
+		{name: "bad rounds", hash: "$6$rounds=abc$salt$hash", password: "x", wantErr: ErrMalformedHash},
+		{name: "other scheme", hash: "$5$salt$hash", password: "x", wantErr: ErrMalformedHash},
+		{name: "rounds above limit", hash: "$6$rounds=1000001$salt$hash", password: "x", wantErr: ErrCostTooHigh},
+		{name: "rounds at crypt(3) maximum", hash: "$6$rounds=999999999$salt$hash", password: "x", wantErr: ErrCostTooHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(SHA512Crypt, []byte(tt.hash), tt.password)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestVerify_Bcrypt(t *testing.T) {
+	hash, err := Hash("secret")
+	require.NoError(t, err)
+
+	assert.NoError(t, Verify(Bcrypt, hash, "secret"))
+	assert.NoError(t, Verify("", hash, "secret"), "empty algorithm means bcrypt")
+	assert.ErrorIs(t, Verify(Bcrypt, hash, "Secret"), ErrMismatch)
+	assert.ErrorIs(t, Verify(Bcrypt, []byte("not a hash"), "secret"), ErrMalformedHash)
+	assert.ErrorIs(t, Verify("md5", hash, "secret"), ErrUnknownAlgorithm)
+}
+
+func TestValidate_CostLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		algo    string
+		hash    string
+		wantErr error
+	}{
+		{name: "pbkdf2 at limit", algo: PBKDF2SHA256, hash: "pbkdf2_sha256$2000000$salt$AAAA"},
+		{name: "pbkdf2 above limit", algo: PBKDF2SHA256, hash: "pbkdf2_sha256$2000001$salt$AAAA", wantErr: ErrCostTooHigh},
+		{name: "sha512-crypt at limit", algo: SHA512Crypt, hash: "$6$rounds=1000000$salt$hash"},
+		{name: "sha512-crypt above limit", algo: SHA512Crypt, hash: "$6$rounds=5000000$salt$hash", wantErr: ErrCostTooHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.algo, tt.hash)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		hash    string
+		want    string
+		wantErr error
+	}{
+		{hash: "$2a$10$BP6w4VsYR1ytYwx2q8UypunWx8aIYCY4NKgkzvvg4enK9tEiQyMPq", want: Bcrypt},
+		{hash: "$2b$12$abc", want: Bcrypt},
+		{hash: "$2y$12$abc", want: Bcrypt},
+		{hash: "$6$salt$hash", want: SHA512Crypt},
+		{hash: "pbkdf2_sha256$1$salt$AAAA", want: PBKDF2SHA256},
+		{hash: "pbkdf2_sha512$1$salt$AAAA", want: PBKDF2SHA512},
+		{hash: "$1$salt$hash", wantErr: ErrUnknownAlgorithm},
+		{hash: "plain", wantErr: ErrUnknownAlgorithm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			got, err := Detect(tt.hash)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package passhash
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Параметры SHA-512-crypt из спецификации Ульриха Дреппера
+const (
+	sha512CryptPrefix        = "$6$"
+	sha512CryptRoundsPrefix  = "rounds="
+	sha512CryptDefaultRounds = 5000
+	sha512CryptMinRounds     = 1000
+	sha512CryptMaxSalt       = 16
+)
+
+// MaxSHA512CryptRounds - верхняя граница rounds= для SHA-512-crypt. crypt(3) допускает до 999999999,
+// но такой хэш проверялся бы минутами, поэтому хэши дороже границы не принимаются.
+const MaxSHA512CryptRounds = 1_000_000
+
+// cryptAlphabet - алфавит base64, используемый crypt(3)
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// sha512CryptParams - разобранный хэш SHA-512-crypt
+type sha512CryptParams struct {
+	rounds         int
+	explicitRounds bool // rounds= присутствовал в исходном хэше
+	salt           string
+	sum            string // закодированная часть хэша
+}
+
+// parseSHA512Crypt разбирает хэш вида $6$[rounds=N$]<salt>$<hash>
+func parseSHA512Crypt(hashed string) (sha512CryptParams, error) {
+	if !strings.HasPrefix(hashed, sha512CryptPrefix) {
+		return sha512CryptParams{}, ErrMalformedHash
+	}
+	rest := strings.TrimPrefix(hashed, sha512CryptPrefix)
+	p := sha512CryptParams{rounds: sha512CryptDefaultRounds}
+
+	if strings.HasPrefix(rest, sha512CryptRoundsPrefix) {
+		value, tail, ok := strings.Cut(strings.TrimPrefix(rest, sha512CryptRoundsPrefix), "$")
+		rounds, err := strconv.Atoi(value)
+		if !ok || err != nil {
+			return sha512CryptParams{}, ErrMalformedHash
+		}
+		if rounds > MaxSHA512CryptRounds {
+			return sha512CryptParams{}, fmt.Errorf("%w: rounds=%d, max %d", ErrCostTooHigh, rounds, MaxSHA512CryptRounds)
+		}
+		// Значения ниже минимума crypt(3) приводит к границе
+		p.rounds = max(rounds, sha512CryptMinRounds)
+		p.explicitRounds = true
+		rest = tail
+	}
+
+	salt, sum, ok := strings.Cut(rest, "$")
+	if !ok || sum == "" || strings.Contains(sum, "$") {
+		return sha512CryptParams{}, ErrMalformedHash
+	}
+	if len(salt) > sha512CryptMaxSalt {
+		salt = salt[:sha512CryptMaxSalt]
+	}
+	p.salt, p.sum = salt, sum
+	return p, nil
+}
+
+// sha512Crypt вычисляет закодированную часть хэша SHA-512-crypt для пароля
+func sha512Crypt(password []byte, p sha512CryptParams) string {
+	salt := []byte(p.salt)
+
+	// Альтернативная сумма B = SHA512(пароль + соль + пароль)
+	alt := sha512.New()
+	alt.Write(password)
+	alt.Write(salt)
+	alt.Write(password)
+	altSum := alt.Sum(nil)
+
+	// Промежуточная сумма A
+	a := sha512.New()
+	a.Write(password)
+	a.Write(salt)
+	for i := len(password); i > 0; i -= sha512.Size {
+		a.Write(altSum[:min(i, sha512.Size)])
+	}
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(altSum)
+		} else {
+			a.Write(password)
+		}
+	}
+	aSum := a.Sum(nil)
+
+	// Последовательность P из повторённого пароля
+	dp := sha512.New()
+	for range password {
+		dp.Write(password)
+	}
+	pSeq := repeatTo(dp.Sum(nil), len(password))
+
+	// Последовательность S из повторённой соли
+	ds := sha512.New()
+	for i := 0; i < 16+int(aSum[0]); i++ {
+		ds.Write(salt)
+	}
+	sSeq := repeatTo(ds.Sum(nil), len(salt))
+
+	// Основной цикл усиления
+	c := aSum
+	for i := 0; i < p.rounds; i++ {
+		h := sha512.New()
+		if i&1 != 0 {
+			h.Write(pSeq)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(sSeq)
+		}
+		if i%7 != 0 {
+			h.Write(pSeq)
+		}
+		if i&1 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(pSeq)
+		}
+		c = h.Sum(nil)
+	}
+
+	return encodeSHA512Crypt(c)
+}
+
+// sha512CryptOrder - порядок байтов суммы при кодировании
+var sha512CryptOrder = [...][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48},
+	{28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13},
+	{56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+}
+
+// encodeSHA512Crypt кодирует сумму в алфавите crypt(3)
+func encodeSHA512Crypt(sum []byte) string {
+	var b strings.Builder
+	write := func(w uint, n int) {
+		for ; n > 0; n-- {
+			b.WriteByte(cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	for _, o := range sha512CryptOrder {
+		write(uint(sum[o[0]])<<16|uint(sum[o[1]])<<8|uint(sum[o[2]]), 4)
+	}
+	write(uint(sum[63]), 2)
+	return b.String()
+}
+
+// repeatTo повторяет сумму до длины n
+func repeatTo(sum []byte, n int) []byte {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		out = append(out, sum[:min(n-len(out), len(sum))]...)
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// key - ключ идентификатора запроса в контексте
+type key struct{}
+
+// New создаёт случайный идентификатор запроса
+func New() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id) // crypto/rand не возвращает ошибок
+	return hex.EncodeToString(id)
+}
+
+// NewContext возвращает контекст с идентификатором запроса
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// FromContext возвращает идентификатор запроса из контекста
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(key{}).(string)
+	return id, ok
+}
+
+// Logger возвращает log с идентификатором запроса из контекста, если он есть
+func Logger(ctx context.Context, log *slog.Logger) *slog.Logger {
+	if id, ok := FromContext(ctx); ok {
+		return log.With(slog.String("request_id", id))
+	}
+	return log
+}
@@ -0,0 +1,234 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Режимы проверки клиентских сертификатов
+const (
+	ClientAuthNone     = "none"     // Сертификат клиента не запрашивается
+	ClientAuthOptional = "optional" // Проверяется, если клиент его предъявил
+	ClientAuthRequire  = "require"  // Без действительного сертификата соединение не устанавливается
+)
+
+var (
+	ErrUnknownVersion     = errors.New("unknown tls version")        // Неизвестная минимальная версия TLS
+	ErrUnknownCipherSuite = errors.New("unknown cipher suite")       // Неизвестный или небезопасный набор шифров
+	ErrUnknownClientAuth  = errors.New("unknown client auth mode")   // Неизвестный режим проверки клиентов
+	ErrClientCARequired   = errors.New("client ca file is required") // Проверка клиентов включена без CA
+)
+
+// Config описывает TLS сервера
+type Config struct {
+	CertFile       string        // Сертификат сервера (PEM, может содержать цепочку)
+	KeyFile        string        // Закрытый ключ сервера (PEM)
+	MinVersion     string        // Минимальная версия: 1.2 или 1.3
+	CipherSuites   []string      // Наборы шифров для TLS 1.2 по именам IANA; пустой - наборы Go по умолчанию
+	ClientCAFile   string        // CA для проверки клиентских сертификатов
+	ClientAuth     string        // Режим проверки клиентов: none, optional или require
+	ReloadInterval time.Duration // Как часто проверять изменение файлов при новых соединениях
+}
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Reloader отдаёт TLS конфигурацию с текущими сертификатами и перечитывает файлы после их изменения.
+// Проверка времени изменения выполняется при установке соединений, не чаще ReloadInterval,
+// поэтому обновлённые сертификаты подхватываются без перезапуска и без фоновых задач.
+type Reloader struct {
+	log  *slog.Logger
+	cfg  Config
+	base *tls.Config // Неизменяемые параметры: версии, шифры, режим проверки клиентов
+
+	mu        sync.Mutex
+	current   *tls.Config // Конфигурация с загруженными сертификатами
+	modTimes  []time.Time // Время изменения CertFile, KeyFile и ClientCAFile при последней загрузке
+	checkedAt time.Time   // Когда файлы проверялись в последний раз
+}
+
+// New проверяет параметры и загружает сертификаты
+func New(log *slog.Logger, cfg Config) (*Reloader, error) {
+	const op = "tlsconfig.New"
+
+	base, err := baseConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	r := &Reloader{log: log, cfg: cfg, base: base}
+	if err := r.load(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return r, nil
+}
+
+// ServerConfig возвращает конфигурацию для сервера с протоколами ALPN nextProtos:
+// gRPC работает только поверх h2, HTTP шлюзу нужен ещё http/1.1.
+// Сертификаты каждого соединения берутся из Reloader.
+func (r *Reloader) ServerConfig(nextProtos ...string) *tls.Config {
+	cfg := r.base.Clone()
+	cfg.NextProtos = nextProtos
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		current := r.config().Clone()
+		current.NextProtos = nextProtos
+		return current, nil
+	}
+	return cfg
+}
+
+// config возвращает текущую конфигурацию, перечитывая файлы, если они изменились.
+// Ошибка перезагрузки не прерывает соединения: используются прежние сертификаты.
+func (r *Reloader) config() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) < r.cfg.ReloadInterval {
+		return r.current
+	}
+	r.checkedAt = time.Now()
+
+	modTimes, err := r.statFiles()
+	if err != nil {
+		r.log.Error("failed to check tls files", slog.String("error", err.Error()))
+		return r.current
+	}
+	if equalTimes(modTimes, r.modTimes) {
+		return r.current
+	}
+	if err := r.loadLocked(); err != nil {
+		r.log.Error("failed to reload tls certificates", slog.String("error", err.Error()))
+		return r.current
+	}
+	r.log.Info("tls certificates reloaded")
+	return r.current
+}
+
+// load загружает сертификаты при создании
+func (r *Reloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkedAt = time.Now()
+	return r.loadLocked()
+}
+
+// loadLocked читает сертификат, ключ и CA клиентов; вызывается под r.mu
+func (r *Reloader) loadLocked() error {
+	modTimes, err := r.statFiles()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+	cfg := r.base.Clone()
+	cfg.Certificates = []tls.Certificate{cert}
+	if r.cfg.ClientCAFile != "" {
+		pool, err := loadPool(r.cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		cfg.ClientCAs = pool
+	}
+	r.current, r.modTimes = cfg, modTimes
+	return nil
+}
+
+// statFiles возвращает время изменения всех файлов конфигурации
+func (r *Reloader) statFiles() ([]time.Time, error) {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	modTimes := make([]time.Time, 0, len(files))
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+// baseConfig собирает неизменяемые параметры TLS
+func baseConfig(cfg Config) (*tls.Config, error) {
+	minVersion, ok := versions[cfg.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownVersion, cfg.MinVersion)
+	}
+
+	var suites []uint16
+	for _, name := range cfg.CipherSuites {
+		id, ok := cipherSuite(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCipherSuite, name)
+		}
+		suites = append(suites, id)
+	}
+
+	var clientAuth tls.ClientAuthType
+	switch cfg.ClientAuth {
+	case ClientAuthNone, "":
+		clientAuth = tls.NoClientCert
+	case ClientAuthOptional:
+		clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownClientAuth, cfg.ClientAuth)
+	}
+	if clientAuth != tls.NoClientCert && cfg.ClientCAFile == "" {
+		return nil, ErrClientCARequired
+	}
+
+	return &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: suites,
+		ClientAuth:   clientAuth,
+	}, nil
+}
+
+// cipherSuite ищет безопасный набор шифров по имени IANA
+func cipherSuite(name string) (uint16, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
+
+// loadPool читает сертификаты CA из PEM файла
+func loadPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// equalTimes сравнивает списки времени изменения файлов
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
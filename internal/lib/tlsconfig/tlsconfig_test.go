@@ -0,0 +1,415 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA - удостоверяющий центр, выпускающий сертификаты для тестов
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue выпускает сертификат сервера для localhost или клиента с указанным серийным номером
+func (ca *testCA) issue(t *testing.T, serial int64, client bool) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+	}
+	if client {
+		tmpl.Subject.CommonName = "client"
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		tmpl.DNSNames = nil
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// clientCert выпускает клиентский сертификат для tls.Config клиента
+func (ca *testCA) clientCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, 100, true)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return cert
+}
+
+// writeFile записывает файл и выставляет время изменения: без этого перезапись в ту же секунду
+// на файловых системах с грубой точностью времени не была бы замечена
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// testFiles - файлы сертификатов сервера и CA клиентов во временном каталоге
+type testFiles struct {
+	cert, key, clientCA string
+}
+
+func newFiles(t *testing.T, serverCA, clientCA *testCA) testFiles {
+	t.Helper()
+	dir := t.TempDir()
+	files := testFiles{
+		cert:     filepath.Join(dir, "server.pem"),
+		key:      filepath.Join(dir, "server-key.pem"),
+		clientCA: filepath.Join(dir, "client-ca.pem"),
+	}
+	certPEM, keyPEM := serverCA.issue(t, 1, false)
+	modTime := time.Now().Add(-time.Minute)
+	writeFile(t, files.cert, certPEM, modTime)
+	writeFile(t, files.key, keyPEM, modTime)
+	writeFile(t, files.clientCA, clientCA.pem, modTime)
+	return files
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// handshake соединяет сервер и клиента через net.Pipe и возвращает состояние соединения на стороне сервера.
+// Ошибка клиента учитывается тоже: в TLS 1.3 отказ в сертификате клиента он видит только при чтении.
+func handshake(t *testing.T, server *tls.Config, client *tls.Config) (tls.ConnectionState, error) {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+	_ = serverConn.SetDeadline(time.Now().Add(5 * time.Second))
+	_ = clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	srv := tls.Server(serverConn, server)
+	done := make(chan error, 1)
+	go func() {
+		err := srv.Handshake()
+		if err == nil {
+			_, err = srv.Write([]byte{1}) // Клиент дочитывает рукопожатие TLS 1.3
+		}
+		if err != nil {
+			_ = serverConn.Close()
+		}
+		done <- err
+	}()
+
+	cli := tls.Client(clientConn, client)
+	clientErr := cli.Handshake()
+	if clientErr == nil {
+		_, clientErr = cli.Read(make([]byte, 1))
+	}
+	if clientErr != nil {
+		_ = clientConn.Close()
+	}
+	if serverErr := <-done; serverErr != nil {
+		return tls.ConnectionState{}, serverErr
+	}
+	return srv.ConnectionState(), clientErr
+}
+
+// clientConfig - клиент, доверяющий CA сервера
+func clientConfig(serverCA *testCA) *tls.Config {
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	return &tls.Config{RootCAs: roots, ServerName: "localhost"}
+}
+
+func TestNew_FailCases(t *testing.T) {
+	serverCA, clientCA := newCA(t, "server ca"), newCA(t, "client ca")
+	files := newFiles(t, serverCA, clientCA)
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	writeFile(t, empty, []byte("no certificates here\n"), time.Now())
+
+	valid := Config{CertFile: files.cert, KeyFile: files.key, MinVersion: "1.2", ClientAuth: ClientAuthNone}
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr error
+		wantMsg string
+	}{
+		{name: "unknown version", modify: func(c *Config) { c.MinVersion = "1.1" }, wantErr: ErrUnknownVersion},
+		{name: "empty version", modify: func(c *Config) { c.MinVersion = "" }, wantErr: ErrUnknownVersion},
+		{name: "unknown cipher suite", modify: func(c *Config) { c.CipherSuites = []string{"TLS_FAKE"} }, wantErr: ErrUnknownCipherSuite},
+		{name: "insecure cipher suite", modify: func(c *Config) { c.CipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"} }, wantErr: ErrUnknownCipherSuite},
+		{name: "unknown client auth", modify: func(c *Config) { c.ClientAuth = "always" }, wantErr: ErrUnknownClientAuth},
+		{name: "require without ca", modify: func(c *Config) { c.ClientAuth = ClientAuthRequire }, wantErr: ErrClientCARequired},
+		{name: "optional without ca", modify: func(c *Config) { c.ClientAuth = ClientAuthOptional }, wantErr: ErrClientCARequired},
+		{name: "missing key", modify: func(c *Config) { c.KeyFile = filepath.Join(t.TempDir(), "missing.pem") }, wantErr: fs.ErrNotExist},
+		{name: "key does not match", modify: func(c *Config) { c.KeyFile = files.clientCA }, wantMsg: "tlsconfig.New"},
+		{
+			name:    "ca without certificates",
+			modify:  func(c *Config) { c.ClientCAFile, c.ClientAuth = empty, ClientAuthRequire },
+			wantMsg: "no certificates found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			_, err := New(discardLogger(), cfg)
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Contains(t, err.Error(), tt.wantMsg)
+		})
+	}
+}
+
+func TestServerConfig_ClientAuth(t *testing.T) {
+	serverCA, clientCA, otherCA := newCA(t, "server ca"), newCA(t, "client ca"), newCA(t, "other ca")
+	files := newFiles(t, serverCA, clientCA)
+
+	tests := []struct {
+		name       string
+		clientAuth string
+		cert       *tls.Certificate // Сертификат клиента; nil - клиент его не предъявляет
+		wantErr    bool
+		wantChain  bool // Сервер видит проверенную цепочку клиента
+	}{
+		{name: "none without cert", clientAuth: ClientAuthNone},
+		{name: "none does not request cert", clientAuth: ClientAuthNone, cert: ptr(clientCA.clientCert(t))},
+		{name: "optional without cert", clientAuth: ClientAuthOptional},
+		{name: "optional with trusted cert", clientAuth: ClientAuthOptional, cert: ptr(clientCA.clientCert(t)), wantChain: true},
+		{name: "optional with untrusted cert", clientAuth: ClientAuthOptional, cert: ptr(otherCA.clientCert(t)), wantErr: true},
+		{name: "require without cert", clientAuth: ClientAuthRequire, wantErr: true},
+		{name: "require with trusted cert", clientAuth: ClientAuthRequire, cert: ptr(clientCA.clientCert(t)), wantChain: true},
+		{name: "require with untrusted cert", clientAuth: ClientAuthRequire, cert: ptr(otherCA.clientCert(t)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{CertFile: files.cert, KeyFile: files.key, MinVersion: "1.2", ClientAuth: tt.clientAuth}
+			if tt.clientAuth != ClientAuthNone {
+				cfg.ClientCAFile = files.clientCA
+			}
+			r, err := New(discardLogger(), cfg)
+			require.NoError(t, err)
+
+			client := clientConfig(serverCA)
+			if tt.cert != nil {
+				// Сертификат отправляется, даже если его CA нет в списке сервера: иначе клиент Go его не предъявит
+				client.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return tt.cert, nil }
+			}
+			state, err := handshake(t, r.ServerConfig("h2"), client)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChain, len(state.VerifiedChains) > 0)
+			assert.Equal(t, tt.wantChain, len(state.PeerCertificates) > 0)
+		})
+	}
+}
+
+func TestServerConfig_MinVersion(t *testing.T) {
+	serverCA, clientCA := newCA(t, "server ca"), newCA(t, "client ca")
+	files := newFiles(t, serverCA, clientCA)
+
+	tests := []struct {
+		name        string
+		minVersion  string
+		clientMax   uint16
+		wantVersion uint16
+		wantErr     bool
+	}{
+		{name: "1.2 accepts 1.2", minVersion: "1.2", clientMax: tls.VersionTLS12, wantVersion: tls.VersionTLS12},
+		{name: "1.2 prefers 1.3", minVersion: "1.2", wantVersion: tls.VersionTLS13},
+		{name: "1.3 rejects 1.2", minVersion: "1.3", clientMax: tls.VersionTLS12, wantErr: true},
+		{name: "1.3 accepts 1.3", minVersion: "1.3", wantVersion: tls.VersionTLS13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(discardLogger(), Config{CertFile: files.cert, KeyFile: files.key, MinVersion: tt.minVersion})
+			require.NoError(t, err)
+
+			client := clientConfig(serverCA)
+			client.MaxVersion = tt.clientMax
+			state, err := handshake(t, r.ServerConfig("h2"), client)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, state.Version)
+		})
+	}
+}
+
+func TestServerConfig_CipherSuites(t *testing.T) {
+	serverCA, clientCA := newCA(t, "server ca"), newCA(t, "client ca")
+	files := newFiles(t, serverCA, clientCA)
+	r, err := New(discardLogger(), Config{
+		CertFile:     files.cert,
+		KeyFile:      files.key,
+		MinVersion:   "1.2",
+		CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"},
+	})
+	require.NoError(t, err)
+
+	// Клиент TLS 1.2 с разрешённым набором договаривается о нём
+	client := clientConfig(serverCA)
+	client.MaxVersion = tls.VersionTLS12
+	state, err := handshake(t, r.ServerConfig("h2"), client)
+	require.NoError(t, err)
+	assert.Equal(t, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256, state.CipherSuite)
+
+	// Клиент TLS 1.2 без разрешённых наборов не подключается
+	client = clientConfig(serverCA)
+	client.MaxVersion = tls.VersionTLS12
+	client.CipherSuites = []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+	_, err = handshake(t, r.ServerConfig("h2"), client)
+	require.Error(t, err)
+}
+
+func TestServerConfig_NextProtos(t *testing.T) {
+	serverCA, clientCA := newCA(t, "server ca"), newCA(t, "client ca")
+	files := newFiles(t, serverCA, clientCA)
+	r, err := New(discardLogger(), Config{CertFile: files.cert, KeyFile: files.key, MinVersion: "1.2"})
+	require.NoError(t, err)
+
+	client := clientConfig(serverCA)
+	client.NextProtos = []string{"http/1.1"}
+	state, err := handshake(t, r.ServerConfig("h2", "http/1.1"), client)
+	require.NoError(t, err)
+	assert.Equal(t, "http/1.1", state.NegotiatedProtocol)
+
+	client.NextProtos = []string{"h2", "http/1.1"}
+	state, err = handshake(t, r.ServerConfig("h2"), client)
+	require.NoError(t, err)
+	assert.Equal(t, "h2", state.NegotiatedProtocol)
+}
+
+func TestReloader_Reload(t *testing.T) {
+	serverCA, clientCA := newCA(t, "server ca"), newCA(t, "client ca")
+	files := newFiles(t, serverCA, clientCA)
+	r, err := New(discardLogger(), Config{
+		CertFile:     files.cert,
+		KeyFile:      files.key,
+		MinVersion:   "1.2",
+		ClientCAFile: files.clientCA,
+		ClientAuth:   ClientAuthRequire,
+	})
+	require.NoError(t, err)
+	server := r.ServerConfig("h2")
+
+	serverSerial := func(client *tls.Config) int64 {
+		t.Helper()
+		var got int64
+		client = client.Clone()
+		client.VerifyConnection = func(cs tls.ConnectionState) error {
+			got = cs.PeerCertificates[0].SerialNumber.Int64()
+			return nil
+		}
+		_, err := handshake(t, server, client)
+		require.NoError(t, err)
+		return got
+	}
+	client := clientConfig(serverCA)
+	client.Certificates = []tls.Certificate{clientCA.clientCert(t)}
+	require.Equal(t, int64(1), serverSerial(client))
+
+	// Новый сертификат подхватывается без перезапуска
+	certPEM, keyPEM := serverCA.issue(t, 2, false)
+	writeFile(t, files.cert, certPEM, time.Now())
+	writeFile(t, files.key, keyPEM, time.Now())
+	assert.Equal(t, int64(2), serverSerial(client))
+
+	// Ошибка перезагрузки не рвёт соединения: остаются прежние сертификаты
+	writeFile(t, files.cert, []byte("broken"), time.Now().Add(time.Second))
+	assert.Equal(t, int64(2), serverSerial(client))
+
+	// Смена CA клиентов отзывает доверие к сертификатам старого CA
+	writeFile(t, files.cert, certPEM, time.Now().Add(2*time.Second))
+	newClientCA := newCA(t, "new client ca")
+	writeFile(t, files.clientCA, newClientCA.pem, time.Now().Add(2*time.Second))
+	_, err = handshake(t, server, client)
+	require.Error(t, err)
+	client.Certificates = []tls.Certificate{newClientCA.clientCert(t)}
+	assert.Equal(t, int64(2), serverSerial(client))
+}
+
+func TestReloader_ReloadInterval(t *testing.T) {
+	serverCA, clientCA := newCA(t, "server ca"), newCA(t, "client ca")
+	files := newFiles(t, serverCA, clientCA)
+	r, err := New(discardLogger(), Config{CertFile: files.cert, KeyFile: files.key, MinVersion: "1.2", ReloadInterval: time.Hour})
+	require.NoError(t, err)
+
+	certPEM, keyPEM := serverCA.issue(t, 2, false)
+	writeFile(t, files.cert, certPEM, time.Now())
+	writeFile(t, files.key, keyPEM, time.Now())
+
+	// До истечения интервала файлы не проверяются
+	var got int64
+	client := clientConfig(serverCA)
+	client.VerifyConnection = func(cs tls.ConnectionState) error {
+		got = cs.PeerCertificates[0].SerialNumber.Int64()
+		return nil
+	}
+	_, err = handshake(t, r.ServerConfig("h2"), client)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got)
+}
+
+func TestReloader_MissingFileKeepsCertificate(t *testing.T) {
+	serverCA, clientCA := newCA(t, "server ca"), newCA(t, "client ca")
+	files := newFiles(t, serverCA, clientCA)
+	r, err := New(discardLogger(), Config{CertFile: files.cert, KeyFile: files.key, MinVersion: "1.2"})
+	require.NoError(t, err)
+
+	// Файл удалён, например на время замены: соединения обслуживаются с загруженным сертификатом
+	require.NoError(t, os.Remove(files.key))
+	_, err = handshake(t, r.ServerConfig("h2"), clientConfig(serverCA))
+	require.NoError(t, err)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
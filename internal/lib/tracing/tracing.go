@@ -0,0 +1,139 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"os"
+)
+
+// Экспортёры трасс в конфигурации
+const (
+	ExporterNone   = "none"   // Трассировка выключена
+	ExporterOTLP   = "otlp"   // OTLP по gRPC в коллектор
+	ExporterStdout = "stdout" // JSON в стандартный вывод
+	ExporterFile   = "file"   // JSON в файл, для разбора без коллектора
+)
+
+// ErrUnknownExporter возвращается для неизвестного экспортёра в конфигурации
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+// Config описывает экспорт трасс
+type Config struct {
+	Exporter    string  // Имя экспортёра: none, otlp, stdout или file
+	Endpoint    string  // Адрес коллектора OTLP
+	Insecure    bool    // Подключаться к коллектору без TLS
+	File        string  // Путь к файлу для экспортёра file
+	SampleRatio float64 // Доля трасс, начатых в сервисе; решение входящего контекста соблюдается
+	ServiceName string  // Имя сервиса в ресурсе трасс
+}
+
+// Provider владеет поставщиком трасс и закрывает экспортёр при остановке
+type Provider struct {
+	tp     *sdktrace.TracerProvider // Поставщик трасс, nil - трассировка выключена
+	closer io.Closer                // Файл экспортёра file, может отсутствовать
+}
+
+// New создаёт поставщик трасс по конфигурации и делает его глобальным вместе с W3C-распространением контекста.
+// Для экспортёра none глобальным остаётся поставщик без записи, а Enabled возвращает false.
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	const op = "tracing.New"
+
+	// Контекст трассы передаётся в заголовках W3C traceparent и baggage
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	p := &Provider{}
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return p, nil
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		var f *os.File
+		f, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			break
+		}
+		p.closer = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownExporter, cfg.Exporter)
+	}
+	if err != nil {
+		if p.closer != nil {
+			_ = p.closer.Close()
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(p.tp)
+	return p, nil
+}
+
+// Enabled сообщает, записываются ли трассы
+func (p *Provider) Enabled() bool {
+	return p.tp != nil
+}
+
+// Shutdown отправляет накопленные спаны и закрывает экспортёр
+func (p *Provider) Shutdown(ctx context.Context) error {
+	const op = "tracing.Shutdown"
+
+	if p.tp == nil {
+		return nil
+	}
+	err := p.tp.Shutdown(ctx)
+	if p.closer != nil {
+		err = errors.Join(err, p.closer.Close())
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// End завершает спан и отмечает его ошибкой, если *err не nil.
+// Вызывается через defer с указателем на именованный результат.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-SSO-Signature" // Подпись: sha256=<hex HMAC-SHA256 с секретом вебхука от "<timestamp>.<тело>">
+	TimestampHeader = "X-SSO-Timestamp" // Время отправки, Unix секунды; входит в подпись
+)
+
+var (
+	ErrUnexpectedStatus = errors.New("unexpected webhook response status")     // Получатель ответил не 2xx
+	ErrNoSecret         = errors.New("webhook secret is required")             // Запрос без подписи получатель не может проверить
+	ErrInvalidSignature = errors.New("invalid webhook signature")              // Подпись не совпала
+	ErrStaleTimestamp   = errors.New("webhook timestamp is outside tolerance") // Запрос устарел или повторён
+)
+
+// Post отправляет подписанный body на url. Подпись покрывает время отправки и тело, поэтому получатель
+// может проверить, что запрос отправлен сервисом, и отбросить перехваченный запрос, повторённый позже.
+// header дополняет заголовки запроса. Любой ответ, кроме 2xx, - ошибка.
+func Post(ctx context.Context, client *http.Client, url string, secret []byte, body []byte, header http.Header) error {
+	if len(secret) == 0 {
+		return ErrNoSecret
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Соединение переиспользуется, только если тело прочитано
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+	return nil
+}
+
+// Sign возвращает значение SignatureHeader для времени отправки timestamp (значение TimestampHeader) и тела запроса
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись запроса на стороне получателя: подпись должна совпасть,
+// а время отправки отличаться от now не больше чем на tolerance
+func Verify(secret []byte, header http.Header, body []byte, now time.Time, tolerance time.Duration) error {
+	timestamp := header.Get(TimestampHeader)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(header.Get(SignatureHeader))) {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(sent, 0)); d > tolerance || d < -tolerance {
+		return ErrStaleTimestamp
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"id":1}`)
+	now := time.Unix(1700000000, 0)
+	signed := func(at time.Time, body []byte) http.Header {
+		ts := strconv.FormatInt(at.Unix(), 10)
+		h := http.Header{}
+		h.Set(TimestampHeader, ts)
+		h.Set(SignatureHeader, Sign(secret, ts, body))
+		return h
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		secret  []byte
+		wantErr error
+	}{
+		{name: "valid", header: signed(now, body), body: body, secret: secret},
+		{name: "clock skew within tolerance", header: signed(now.Add(30*time.Second), body), body: body, secret: secret},
+		{name: "tampered body", header: signed(now, body), body: []byte(`{"id":2}`), secret: secret, wantErr: ErrInvalidSignature},
+		{name: "wrong secret", header: signed(now, body), body: body, secret: []byte("other"), wantErr: ErrInvalidSignature},
+		{name: "replayed later", header: signed(now.Add(-10*time.Minute), body), body: body, secret: secret, wantErr: ErrStaleTimestamp},
+		{
+			name: "timestamp changed",
+			header: func() http.Header {
+				h := signed(now.Add(-10*time.Minute), body)
+				h.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+				return h
+			}(),
+			body: body, secret: secret, wantErr: ErrInvalidSignature,
+		},
+		{name: "no timestamp", header: http.Header{SignatureHeader: {Sign(secret, "", body)}}, body: body, secret: secret, wantErr: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, now, time.Minute)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
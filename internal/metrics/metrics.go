@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/codes"
+	"net/http"
+	"time"
+)
+
+const namespace = "sso" // Префикс имён всех метрик сервиса
+
+// Результаты входа для метки result
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+// Metrics хранит метрики сервиса в собственном реестре
+type Metrics struct {
+	registry *prometheus.Registry
+
+	grpcHandling  *prometheus.HistogramVec // Длительность обработки gRPC вызовов
+	logins        *prometheus.CounterVec   // Попытки входа
+	registrations *prometheus.CounterVec   // Регистрации
+	passwordHash  *prometheus.HistogramVec // Длительность хэширования и проверки паролей
+	storageQuery  *prometheus.HistogramVec // Длительность методов хранилища
+}
+
+// New создаёт и регистрирует метрики сервиса, а также метрики процесса и среды Go
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		grpcHandling: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "server_handling_seconds",
+			Help:      "Duration of gRPC calls by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by result, failure reason and app.",
+		}, []string{"result", "reason", "app_id"}),
+		registrations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registrations_total",
+			Help:      "User registrations by result.",
+		}, []string{"result"}),
+		passwordHash: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "password_hash_seconds",
+			Help:      "Duration of password hashing and verification by operation and algorithm.",
+			// bcrypt с cost 10 занимает десятки миллисекунд, поэтому шкала начинается с 5 мс
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 10),
+		}, []string{"operation", "algorithm"}),
+		storageQuery: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "query_seconds",
+			Help:      "Duration of storage methods.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 14),
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.grpcHandling,
+		m.logins,
+		m.registrations,
+		m.passwordHash,
+		m.storageQuery,
+	)
+	return m
+}
+
+// Handler возвращает HTTP обработчик, отдающий метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveCall записывает длительность gRPC вызова
+func (m *Metrics) ObserveCall(method string, code codes.Code, duration time.Duration) {
+	m.grpcHandling.WithLabelValues(method, code.String()).Observe(duration.Seconds())
+}
+
+// ObserveQuery записывает длительность метода хранилища
+func (m *Metrics) ObserveQuery(method string, duration time.Duration) {
+	m.storageQuery.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// LoginSucceeded учитывает успешный вход
+func (m *Metrics) LoginSucceeded(appID string) {
+	m.logins.WithLabelValues(resultSuccess, "", appID).Inc()
+}
+
+// LoginFailed учитывает неудачный вход с причиной отказа
+func (m *Metrics) LoginFailed(appID, reason string) {
+	m.logins.WithLabelValues(resultFailure, reason, appID).Inc()
+}
+
+// UserRegistered учитывает попытку регистрации с её результатом
+func (m *Metrics) UserRegistered(result string) {
+	m.registrations.WithLabelValues(result).Inc()
+}
+
+// ObservePasswordHash записывает длительность хэширования или проверки пароля
+func (m *Metrics) ObservePasswordHash(operation, algorithm string, duration time.Duration) {
+	m.passwordHash.WithLabelValues(operation, algorithm).Observe(duration.Seconds())
+}
@@ -0,0 +1,316 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/passhash"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Fixture описывает начальные данные окружения
+type Fixture struct {
+	Apps  []App  `yaml:"apps"`  // Приложения
+	Roles []Role `yaml:"roles"` // Роли
+	Users []User `yaml:"users"` // Пользователи (как правило, администраторы)
+}
+
+// App описывает приложение в фикстуре
+type App struct {
+	ID           int      `yaml:"id"`            // Идентификатор приложения, на него ссылаются токены
+	Name         string   `yaml:"name"`          // Название приложения
+	Secret       string   `yaml:"secret"`        // Секрет для подписи токенов
+	RedirectURIs []string `yaml:"redirect_uris"` // Адреса возврата OAuth 2.0; пустой - вход через OAuth недоступен
+	PublicClient bool     `yaml:"public_client"` // Публичный клиент OAuth 2.0 (SPA, мобильное приложение): меняет код на токен без секрета
+}
+
+// Role описывает роль в фикстуре
+type Role struct {
+	Name        string `yaml:"name"`        // Имя роли
+	Description string `yaml:"description"` // Описание роли
+}
+
+// User описывает пользователя в фикстуре
+type User struct {
+	Email        string   `yaml:"email"`         // Email пользователя
+	Password     string   `yaml:"password"`      // Пароль в открытом виде (только для локальных окружений)
+	PasswordHash string   `yaml:"password_hash"` // Готовый bcrypt хэш пароля
+	IsAdmin      bool     `yaml:"is_admin"`      // Флаг администратора
+	Roles        []string `yaml:"roles"`         // Имена назначаемых ролей
+}
+
+// Change описывает результат применения одной записи фикстуры
+type Change struct {
+	Kind   string               // Тип записи: app, role, user, grant
+	Key    string               // Ключ записи для вывода
+	Result storage.UpsertResult // Что произошло с записью
+}
+
+// Store объединяет методы хранилища, необходимые для применения фикстур
+type Store interface {
+	UpsertApp(ctx context.Context, app models.App) (storage.UpsertResult, error)
+	UpsertRole(ctx context.Context, role models.Role) (storage.UpsertResult, error)
+	User(ctx context.Context, email string) (models.User, error)
+	UpsertUser(ctx context.Context, email string, passHash []byte, passAlgo string, isAdmin bool) (int64, storage.UpsertResult, error)
+	GrantRole(ctx context.Context, userID int64, roleName string) (storage.UpsertResult, error)
+}
+
+// extensions перечисляет поддерживаемые форматы файлов (JSON является подмножеством YAML)
+var extensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// Load читает все фикстуры окружения env из директории dir/env в алфавитном порядке.
+// Ссылки вида ${VAR} в значениях заменяются значениями переменных окружения,
+// чтобы секреты production не хранились в репозитории.
+func Load(dir, env string) (Fixture, error) {
+	const op = "seed.Load"
+
+	envDir := filepath.Join(dir, env)
+	entries, err := os.ReadDir(envDir)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && extensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var fx Fixture
+	for _, name := range names {
+		part, err := loadFile(filepath.Join(envDir, name))
+		if err != nil {
+			return Fixture{}, fmt.Errorf("%s: %s: %w", op, name, err)
+		}
+		fx.Apps = append(fx.Apps, part.Apps...)
+		fx.Roles = append(fx.Roles, part.Roles...)
+		fx.Users = append(fx.Users, part.Users...)
+	}
+
+	if err := fx.Validate(); err != nil {
+		return Fixture{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return fx, nil
+}
+
+// envRef - явная ссылка на переменную окружения ${VAR}. Прочие символы $ в значениях
+// (хэши bcrypt вида $2a$10$..., пароли) остаются как есть.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// loadFile разбирает один файл фикстуры
+func loadFile(path string) (Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, err
+	}
+
+	// Подстановка выполняется в разобранных скалярах, а не в тексте файла:
+	// значение переменной не может изменить структуру YAML
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return Fixture{}, err
+	}
+	if doc.Kind == 0 { // Пустой файл
+		return Fixture{}, nil
+	}
+
+	var missing []string
+	expandEnv(&doc, &missing)
+	if len(missing) > 0 {
+		return Fixture{}, fmt.Errorf("undefined environment variables: %s", strings.Join(missing, ", "))
+	}
+
+	expanded, err := yaml.Marshal(&doc)
+	if err != nil {
+		return Fixture{}, err
+	}
+
+	var fx Fixture
+	dec := yaml.NewDecoder(bytes.NewReader(expanded))
+	dec.KnownFields(true) // Опечатки в ключах считаются ошибкой
+	if err := dec.Decode(&fx); err != nil && !errors.Is(err, io.EOF) {
+		return Fixture{}, err
+	}
+	return fx, nil
+}
+
+// expandEnv заменяет ссылки ${VAR} в скалярных значениях узла и его потомков,
+// имена неустановленных переменных добавляются в missing
+func expandEnv(node *yaml.Node, missing *[]string) {
+	if node.Kind != yaml.ScalarNode {
+		for _, child := range node.Content {
+			expandEnv(child, missing)
+		}
+		return
+	}
+	if !envRef.MatchString(node.Value) {
+		return
+	}
+	node.Value = envRef.ReplaceAllStringFunc(node.Value, func(ref string) string {
+		key := envRef.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			*missing = append(*missing, key)
+		}
+		return v
+	})
+	if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		node.Tag = "" // Тип значения без кавычек определяется по подставленному тексту, например id: ${APP_ID}
+	}
+}
+
+// Validate проверяет фикстуру на полноту и отсутствие дубликатов
+func (fx Fixture) Validate() error {
+	var errs []error
+
+	appIDs := map[int]bool{}
+	for i, app := range fx.Apps {
+		switch {
+		case app.ID <= 0:
+			errs = append(errs, fmt.Errorf("apps[%d]: id must be positive", i))
+		case app.Name == "" || app.Secret == "":
+			errs = append(errs, fmt.Errorf("apps[%d]: name and secret are required", i))
+		case appIDs[app.ID]:
+			errs = append(errs, fmt.Errorf("apps[%d]: duplicate id %d", i, app.ID))
+		}
+		appIDs[app.ID] = true
+		for _, uri := range app.RedirectURIs {
+			if err := checkRedirectURI(uri); err != nil {
+				errs = append(errs, fmt.Errorf("apps[%d]: redirect_uris: %q: %w", i, uri, err))
+			}
+		}
+	}
+
+	roles := map[string]bool{}
+	for i, role := range fx.Roles {
+		switch {
+		case role.Name == "":
+			errs = append(errs, fmt.Errorf("roles[%d]: name is required", i))
+		case roles[role.Name]:
+			errs = append(errs, fmt.Errorf("roles[%d]: duplicate name %q", i, role.Name))
+		}
+		roles[role.Name] = true
+	}
+
+	emails := map[string]bool{}
+	for i, user := range fx.Users {
+		switch {
+		case user.Email == "":
+			errs = append(errs, fmt.Errorf("users[%d]: email is required", i))
+		case emails[user.Email]:
+			errs = append(errs, fmt.Errorf("users[%d]: duplicate email %q", i, user.Email))
+		case (user.Password == "") == (user.PasswordHash == ""):
+			errs = append(errs, fmt.Errorf("users[%d]: exactly one of password and password_hash is required", i))
+		case user.PasswordHash != "":
+			if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+				errs = append(errs, fmt.Errorf("users[%d]: password_hash is not a bcrypt hash", i))
+			}
+		}
+		emails[user.Email] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+// Apply идемпотентно записывает фикстуру в хранилище.
+// Для атомарности вызывающий код должен передавать хранилище внутри транзакции.
+func Apply(ctx context.Context, st Store, fx Fixture) ([]Change, error) {
+	const op = "seed.Apply"
+
+	var changes []Change
+
+	for _, role := range fx.Roles {
+		res, err := st.UpsertRole(ctx, models.Role{Name: role.Name, Description: role.Description})
+		if err != nil {
+			return nil, fmt.Errorf("%s: role %q: %w", op, role.Name, err)
+		}
+		changes = append(changes, Change{Kind: "role", Key: role.Name, Result: res})
+	}
+
+	for _, app := range fx.Apps {
+		res, err := st.UpsertApp(ctx, models.App{ID: app.ID, Name: app.Name, Secret: app.Secret, RedirectURIs: app.RedirectURIs, PublicClient: app.PublicClient})
+		if err != nil {
+			return nil, fmt.Errorf("%s: app %d: %w", op, app.ID, err)
+		}
+		changes = append(changes, Change{Kind: "app", Key: fmt.Sprintf("%d:%s", app.ID, app.Name), Result: res})
+	}
+
+	for _, user := range fx.Users {
+		passHash, err := userPassHash(ctx, st, user)
+		if err != nil {
+			return nil, fmt.Errorf("%s: user %q: %w", op, user.Email, err)
+		}
+		id, res, err := st.UpsertUser(ctx, user.Email, passHash, passhash.Bcrypt, user.IsAdmin)
+		if err != nil {
+			return nil, fmt.Errorf("%s: user %q: %w", op, user.Email, err)
+		}
+		changes = append(changes, Change{Kind: "user", Key: user.Email, Result: res})
+
+		for _, role := range user.Roles {
+			res, err := st.GrantRole(ctx, id, role)
+			if err != nil {
+				return nil, fmt.Errorf("%s: user %q role %q: %w", op, user.Email, role, err)
+			}
+			changes = append(changes, Change{Kind: "grant", Key: user.Email + "->" + role, Result: res})
+		}
+	}
+
+	return changes, nil
+}
+
+// userPassHash возвращает хэш, который нужно записать, или nil, если пароль менять не нужно
+func userPassHash(ctx context.Context, st Store, user User) ([]byte, error) {
+	if user.PasswordHash != "" {
+		return []byte(user.PasswordHash), nil
+	}
+
+	existing, err := st.User(ctx, user.Email)
+	switch {
+	case err == nil:
+		// Пароль уже совпадает: не перехэшируем, иначе каждый запуск менял бы соль
+		if bcrypt.CompareHashAndPassword(existing.PassHash, []byte(user.Password)) == nil {
+			return nil, nil
+		}
+	case !errors.Is(err, storage.ErrUserNotFound):
+		return nil, err
+	}
+
+	return passhash.Hash(user.Password)
+}
+
+// checkRedirectURI проверяет адрес возврата OAuth 2.0: абсолютный, без фрагмента, http - только для
+// локального адреса (RFC 8252). Собственные схемы мобильных приложений допускаются.
+func checkRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	switch {
+	case err != nil:
+		return err
+	case u.Scheme == "" || (u.Host == "" && u.Opaque == "" && u.Path == ""):
+		return errors.New("must be an absolute URI")
+	case u.Fragment != "" || strings.Contains(uri, "#"):
+		return errors.New("must not contain a fragment")
+	case u.Scheme == "http" && u.Hostname() != "localhost" && !isLoopback(u.Hostname()):
+		return errors.New("http is allowed only for localhost, use https")
+	}
+	return nil
+}
+
+// isLoopback сообщает, является ли host локальным IP адресом
+func isLoopback(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,73 @@
+package seed
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const bcryptHash = "$2a$10$BP6w4VsYR1ytYwx2q8UypunWx8aIYCY4NKgkzvvg4enK9tEiQyMPq"
+
+func TestLoadFile_Expand(t *testing.T) {
+	t.Setenv("SEED_TEST_EMAIL", "admin@example.com")
+	t.Setenv("SEED_TEST_APP_ID", "7")
+	t.Setenv("SEED_TEST_SECRET", "a: b # c")
+
+	tests := []struct {
+		name    string
+		content string
+		want    Fixture
+		wantErr string
+	}{
+		{
+			name:    "literal bcrypt hash",
+			content: "users:\n  - email: a@localhost\n    password_hash: " + bcryptHash + "\n",
+			want:    Fixture{Users: []User{{Email: "a@localhost", PasswordHash: bcryptHash}}},
+		},
+		{
+			name:    "password with dollar",
+			content: "users:\n  - email: a@localhost\n    password: 'pa$$w$rd'\n",
+			want:    Fixture{Users: []User{{Email: "a@localhost", Password: "pa$$w$rd"}}},
+		},
+		{
+			name:    "env reference",
+			content: "users:\n  - email: ${SEED_TEST_EMAIL}\n    password_hash: " + bcryptHash + "\n",
+			want:    Fixture{Users: []User{{Email: "admin@example.com", PasswordHash: bcryptHash}}},
+		},
+		{
+			name:    "env reference keeps yaml structure and types",
+			content: "apps:\n  - id: ${SEED_TEST_APP_ID}\n    name: app\n    secret: ${SEED_TEST_SECRET}\n",
+			want:    Fixture{Apps: []App{{ID: 7, Name: "app", Secret: "a: b # c"}}},
+		},
+		{
+			name:    "undefined variable",
+			content: "users:\n  - email: ${SEED_TEST_UNDEFINED}\n",
+			wantErr: "undefined environment variables: SEED_TEST_UNDEFINED",
+		},
+		{
+			name:    "unknown key",
+			content: "users:\n  - mail: a@localhost\n",
+			wantErr: "field mail not found",
+		},
+		{
+			name: "empty file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fixture.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			fx, err := loadFile(path)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, fx)
+		})
+	}
+}
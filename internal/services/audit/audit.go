@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/actor"
+	"github.com/linemk/gRPC_auth/internal/lib/requestid"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Ограничения размера страницы QueryAuditLog
+const (
+	DefaultLimit = 100  // Если клиент не указал размер страницы
+	MaxLimit     = 1000 // Больше за один запрос не отдаётся
+)
+
+// ErrChainBroken возвращается проверкой, если цепочка хэшей нарушена: запись изменена, удалена или вставлена
+var ErrChainBroken = errors.New("audit chain is broken")
+
+// Store описывает хранилище журнала аудита
+type Store interface {
+	AppendAuditEvent(ctx context.Context, event models.AuditEvent) (models.AuditEvent, error) // Дозапись с вычислением хэша
+	AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)  // Выборка по фильтру
+	ForEachAuditEvent(ctx context.Context, fn func(event models.AuditEvent) error) error      // Обход всего журнала
+}
+
+// Log записывает события безопасности в журнал аудита с цепочкой хэшей
+type Log struct {
+	log   *slog.Logger
+	store Store
+	mu    sync.Mutex // Дозапись последовательна: каждая запись ссылается на предыдущую
+
+	forwarders []*forwarder // Получатели событий; добавляются AddSink до Run
+}
+
+// New создаёт журнал аудита
+func New(log *slog.Logger, store Store) *Log {
+	return &Log{log: log.With(slog.String("component", "audit")), store: store}
+}
+
+// Record дописывает событие в журнал. Время, идентификатор запроса и, если не задан,
+// выполняющий действие пользователь берутся из ctx. Ошибка записи не прерывает
+// действие, о котором сообщает событие, и только журналируется.
+// Записанное событие передаётся получателям, подходящим по фильтру.
+func (l *Log) Record(ctx context.Context, event models.AuditEvent) {
+	const op = "audit.Record"
+
+	event.Time = time.Now()
+	if id, ok := requestid.FromContext(ctx); ok {
+		event.RequestID = id
+	}
+	if id, ok := actor.FromContext(ctx); ok && event.ActorID == 0 {
+		event.ActorID = id
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Событие записывается, даже если клиент уже отменил запрос: действие могло состояться
+	recorded, err := l.store.AppendAuditEvent(context.WithoutCancel(ctx), event)
+	if err != nil {
+		requestid.Logger(ctx, l.log).Error("failed to record audit event",
+			slog.String("op", op),
+			slog.String("type", string(event.Type)),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	// Под блокировкой дозаписи события попадают к получателям в порядке номеров
+	l.publish(recorded)
+}
+
+// Query возвращает записи журнала по фильтру. Limit приводится к диапазону 1..MaxLimit.
+// nextAfterSeq - курсор следующей страницы, 0 - если страница неполная и записей больше нет.
+func (l *Log) Query(ctx context.Context, filter models.AuditFilter) (events []models.AuditEvent, nextAfterSeq int64, err error) {
+	const op = "audit.Query"
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = DefaultLimit
+	case filter.Limit > MaxLimit:
+		filter.Limit = MaxLimit
+	}
+	events, err = l.store.AuditEvents(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(events) == filter.Limit {
+		nextAfterSeq = events[len(events)-1].Seq
+	}
+	return events, nextAfterSeq, nil
+}
+
+// Report - итог проверки журнала
+type Report struct {
+	Events int64  // Сколько записей проверено
+	Head   string // Хэш последней записи; сохранённый вне базы, позволяет обнаружить удаление последних записей
+}
+
+// Verify проходит журнал от первой записи и проверяет номера, ссылки на предыдущие записи и хэши.
+// Возвращает ErrChainBroken с номером первой нарушенной записи.
+func Verify(ctx context.Context, store Store) (Report, error) {
+	const op = "audit.Verify"
+
+	report := Report{Head: models.AuditGenesisHash}
+	err := store.ForEachAuditEvent(ctx, func(event models.AuditEvent) error {
+		switch {
+		case event.Seq != report.Events+1:
+			return fmt.Errorf("seq %d: expected seq %d, records are missing: %w", event.Seq, report.Events+1, ErrChainBroken)
+		case event.PrevHash != report.Head:
+			return fmt.Errorf("seq %d: prev_hash does not match previous record: %w", event.Seq, ErrChainBroken)
+		case event.ComputeHash() != event.Hash:
+			return fmt.Errorf("seq %d: record was modified: %w", event.Seq, ErrChainBroken)
+		}
+		report.Events, report.Head = event.Seq, event.Hash
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+	return report, nil
+}
@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// memStore - журнал в памяти; Verify нужен только обход
+type memStore struct {
+	events []models.AuditEvent
+	err    error // Ошибка обхода, например недоступной базы
+}
+
+func (s *memStore) AppendAuditEvent(context.Context, models.AuditEvent) (models.AuditEvent, error) {
+	return models.AuditEvent{}, errors.New("not implemented")
+}
+
+func (s *memStore) AuditEvents(context.Context, models.AuditFilter) ([]models.AuditEvent, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *memStore) ForEachAuditEvent(_ context.Context, fn func(event models.AuditEvent) error) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, e := range s.events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chain строит цепочку из n правильно связанных записей
+func chain(n int) []models.AuditEvent {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := make([]models.AuditEvent, 0, n)
+	prev := models.AuditGenesisHash
+	for i := 1; i <= n; i++ {
+		e := models.AuditEvent{
+			Seq:       int64(i),
+			Time:      start.Add(time.Duration(i) * time.Second),
+			Type:      models.AuditUserLogin,
+			Outcome:   models.AuditSuccess,
+			SubjectID: int64(i),
+			AppID:     1,
+			Details:   map[string]string{"reason": "ok"},
+			PrevHash:  prev,
+		}
+		e.Hash = e.ComputeHash()
+		prev = e.Hash
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestVerify(t *testing.T) {
+	errDB := errors.New("database is locked")
+
+	tests := []struct {
+		name       string
+		tamper     func(events []models.AuditEvent) []models.AuditEvent
+		storeErr   error
+		wantEvents int64
+		wantErr    error
+		wantMsg    string
+	}{
+		{
+			name:       "intact",
+			tamper:     func(events []models.AuditEvent) []models.AuditEvent { return events },
+			wantEvents: 5,
+		},
+		{
+			name:       "empty",
+			tamper:     func([]models.AuditEvent) []models.AuditEvent { return nil },
+			wantEvents: 0,
+		},
+		{
+			name: "field modified",
+			tamper: func(events []models.AuditEvent) []models.AuditEvent {
+				events[2].Outcome = models.AuditFailure
+				return events
+			},
+			wantEvents: 2,
+			wantErr:    ErrChainBroken,
+			wantMsg:    "seq 3: record was modified",
+		},
+		{
+			name: "details modified",
+			tamper: func(events []models.AuditEvent) []models.AuditEvent {
+				events[0].Details = map[string]string{"reason": "forged"}
+				return events
+			},
+			wantErr: ErrChainBroken,
+			wantMsg: "seq 1: record was modified",
+		},
+		{
+			name: "record deleted",
+			tamper: func(events []models.AuditEvent) []models.AuditEvent {
+				return append(events[:1], events[2:]...)
+			},
+			wantEvents: 1,
+			wantErr:    ErrChainBroken,
+			wantMsg:    "seq 3: expected seq 2",
+		},
+		{
+			name: "first record deleted",
+			tamper: func(events []models.AuditEvent) []models.AuditEvent {
+				return events[1:]
+			},
+			wantErr: ErrChainBroken,
+			wantMsg: "seq 2: expected seq 1",
+		},
+		{
+			name: "record rehashed after modification",
+			tamper: func(events []models.AuditEvent) []models.AuditEvent {
+				events[1].SubjectID = 42
+				events[1].Hash = events[1].ComputeHash()
+				return events
+			},
+			wantEvents: 2,
+			wantErr:    ErrChainBroken,
+			wantMsg:    "seq 3: prev_hash does not match",
+		},
+		{
+			name: "records swapped and renumbered",
+			tamper: func(events []models.AuditEvent) []models.AuditEvent {
+				events[1], events[2] = events[2], events[1]
+				events[1].Seq, events[2].Seq = 2, 3
+				return events
+			},
+			wantEvents: 1,
+			wantErr:    ErrChainBroken,
+			wantMsg:    "seq 2: prev_hash does not match",
+		},
+		{
+			name:     "store error",
+			tamper:   func(events []models.AuditEvent) []models.AuditEvent { return events },
+			storeErr: errDB,
+			wantErr:  errDB,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := tt.tamper(chain(5))
+			report, err := Verify(context.Background(), &memStore{events: events, err: tt.storeErr})
+			assert.Equal(t, tt.wantEvents, report.Events)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Contains(t, err.Error(), tt.wantMsg)
+				return
+			}
+			require.NoError(t, err)
+			if len(events) > 0 {
+				assert.Equal(t, events[len(events)-1].Hash, report.Head)
+			} else {
+				assert.Equal(t, models.AuditGenesisHash, report.Head)
+			}
+		})
+	}
+}
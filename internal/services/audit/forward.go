@@ -0,0 +1,311 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ограничения повторной доставки
+const (
+	maxRetryInterval = 5 * time.Minute // Больше интервал между попытками не растёт
+	flushTimeout     = 5 * time.Second // Столько ждётся последняя отправка при остановке
+)
+
+// ErrSinkExists возвращается при добавлении получателя с занятым именем
+var ErrSinkExists = errors.New("audit sink already exists")
+
+// Sink доставляет события журнала аудита во внешнюю систему
+type Sink interface {
+	Send(ctx context.Context, events []models.AuditEvent) error // Отправка пачки событий; ошибка - ни одно не считается доставленным
+	Close() error
+}
+
+// Filter отбирает события для получателя. Пустой список пропускает всё.
+type Filter struct {
+	Types    []string // Типы событий; "user.*" - все типы с префиксом "user."
+	Outcomes []string // Исходы: success, failure
+}
+
+// Match сообщает, подходит ли событие под фильтр
+func (f Filter) Match(e models.AuditEvent) bool {
+	if len(f.Outcomes) > 0 && !slices.Contains(f.Outcomes, string(e.Outcome)) {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if prefix, ok := strings.CutSuffix(t, "*"); ok {
+			if strings.HasPrefix(string(e.Type), prefix) {
+				return true
+			}
+		} else if t == string(e.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// SinkOptions - параметры доставки событий получателю
+type SinkOptions struct {
+	Filter        Filter
+	SpoolDir      string        // Каталог очередей недоставленных событий
+	BufferSize    int           // Ёмкость очереди в памяти; при переполнении события пишутся сразу на диск
+	BatchSize     int           // Сколько событий отправляется за раз
+	FlushInterval time.Duration // Сколько неполная пачка ждёт новых событий
+	RetryInterval time.Duration // Первый интервал между попытками; удваивается до maxRetryInterval
+}
+
+// forwarder доставляет события одному получателю не менее одного раза: пачка, которую не удалось
+// отправить, пишется в очередь на диске и отправляется повторно, пока получатель не примет её.
+// Пока получатель недоступен, события из памяти перекладываются на диск вслед за недоставленными,
+// чтобы сохранить порядок. При переполнении очереди в памяти событие пишется на диск сразу
+// и может быть доставлено раньше событий, ещё ждущих в памяти.
+type forwarder struct {
+	name  string
+	log   *slog.Logger
+	sink  Sink
+	opts  SinkOptions
+	spool *spool
+
+	mu      sync.Mutex // Защищает stopped и запись в queue после остановки
+	stopped bool
+	queue   chan models.AuditEvent
+}
+
+// AddSink добавляет получателя событий. Вызывается до Run; name - имя файла очереди в SpoolDir.
+func (l *Log) AddSink(name string, sink Sink, opts SinkOptions) error {
+	const op = "audit.AddSink"
+
+	for _, f := range l.forwarders {
+		if f.name == name {
+			return fmt.Errorf("%s: %w: %q", op, ErrSinkExists, name)
+		}
+	}
+	sp, err := openSpool(opts.SpoolDir, name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	l.forwarders = append(l.forwarders, &forwarder{
+		name:  name,
+		log:   l.log.With(slog.String("sink", name)),
+		sink:  sink,
+		opts:  opts,
+		spool: sp,
+		queue: make(chan models.AuditEvent, opts.BufferSize),
+	})
+	return nil
+}
+
+// Run доставляет события получателям до отмены ctx. При остановке оставшиеся события
+// отправляются последний раз, а недоставленные сохраняются в очереди на диске.
+func (l *Log) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, f := range l.forwarders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.run(ctx)
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+	return nil
+}
+
+// publish передаёт записанное событие получателям, чей фильтр его пропускает
+func (l *Log) publish(event models.AuditEvent) {
+	for _, f := range l.forwarders {
+		if f.opts.Filter.Match(event) {
+			f.enqueue(event)
+		}
+	}
+}
+
+// enqueue ставит событие в очередь в памяти, не блокируя запись журнала.
+// Если очередь переполнена или доставка остановлена, событие сразу пишется на диск.
+func (f *forwarder) enqueue(event models.AuditEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.stopped {
+		select {
+		case f.queue <- event:
+			return
+		default:
+		}
+	}
+	f.store([]models.AuditEvent{event})
+}
+
+// store сохраняет события в очереди на диске. Если не удалось и это, событие теряется для получателя,
+// но остаётся в журнале аудита.
+func (f *forwarder) store(events []models.AuditEvent) {
+	if err := f.spool.append(events); err != nil {
+		f.log.Error("failed to spool audit events, events are lost for this sink",
+			slog.Int("events", len(events)),
+			slog.Int64("first_seq", events[0].Seq),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// run - цикл доставки: сначала очередь на диске, затем пачки из памяти
+func (f *forwarder) run(ctx context.Context) {
+	retry := f.opts.RetryInterval
+	failing := false
+	for {
+		if f.spool.pending() {
+			if err := f.drain(ctx); err != nil {
+				if ctx.Err() != nil {
+					f.shutdown(nil)
+					return
+				}
+				if !failing {
+					f.log.Warn("audit sink unavailable, events are spooled", slog.String("error", err.Error()))
+					failing = true
+				}
+				if !f.wait(ctx, retry) {
+					f.shutdown(nil)
+					return
+				}
+				retry = min(retry*2, maxRetryInterval)
+				continue
+			}
+		}
+		if failing {
+			f.log.Info("audit sink recovered, spooled events delivered")
+			failing = false
+		}
+		retry = f.opts.RetryInterval
+
+		batch, ok := f.collect(ctx)
+		if !ok {
+			f.shutdown(batch)
+			return
+		}
+		if err := f.sink.Send(ctx, batch); err != nil {
+			f.store(batch)
+			if ctx.Err() != nil {
+				f.shutdown(nil)
+				return
+			}
+			// Повтор пойдёт через очередь на диске
+		}
+	}
+}
+
+// collect ждёт первое событие и добирает пачку до BatchSize или истечения FlushInterval.
+// false - ctx отменён; собранные события возвращаются для последней отправки.
+func (f *forwarder) collect(ctx context.Context) ([]models.AuditEvent, bool) {
+	var batch []models.AuditEvent
+	select {
+	case <-ctx.Done():
+		return nil, false
+	case e := <-f.queue:
+		batch = append(batch, e)
+	}
+
+	timer := time.NewTimer(f.opts.FlushInterval)
+	defer timer.Stop()
+	for len(batch) < f.opts.BatchSize {
+		select {
+		case <-ctx.Done():
+			return batch, false
+		case e := <-f.queue:
+			batch = append(batch, e)
+		case <-timer.C:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// drain отправляет очередь на диске пачками, удаляя каждую после подтверждения
+func (f *forwarder) drain(ctx context.Context) error {
+	for {
+		batch, err := f.spool.peek(f.opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		if batch.lines == 0 {
+			return nil
+		}
+		if len(batch.events) > 0 {
+			if err := f.sink.Send(ctx, batch.events); err != nil {
+				return err
+			}
+		}
+		if err := f.spool.commit(batch); err != nil {
+			return err
+		}
+		if len(batch.corrupt) > 0 {
+			f.log.Error("corrupt spooled audit events moved to quarantine",
+				slog.Int("lines", len(batch.corrupt)),
+				slog.String("file", f.spool.quarantine),
+			)
+		}
+	}
+}
+
+// wait выжидает интервал повтора, перекладывая поступающие события на диск вслед за недоставленными.
+// false - ctx отменён.
+func (f *forwarder) wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case e := <-f.queue:
+			f.store([]models.AuditEvent{e})
+		}
+	}
+}
+
+// shutdown останавливает приём событий, последний раз отправляет оставшиеся и закрывает получателя.
+// Если на диске уже есть недоставленные события, отправка не выполняется: оставшиеся дописываются за ними.
+func (f *forwarder) shutdown(batch []models.AuditEvent) {
+	f.mu.Lock()
+	f.stopped = true
+	f.mu.Unlock()
+
+queued:
+	for {
+		select {
+		case e := <-f.queue:
+			batch = append(batch, e)
+		default:
+			break queued
+		}
+	}
+
+	if len(batch) > 0 {
+		if f.spool.pending() {
+			f.store(batch)
+		} else {
+			ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			err := f.sink.Send(ctx, batch)
+			cancel()
+			if err != nil {
+				f.log.Warn("audit sink unavailable on shutdown, events are spooled",
+					slog.Int("events", len(batch)),
+					slog.String("error", err.Error()),
+				)
+				f.store(batch)
+			}
+		}
+	}
+	if err := f.sink.Close(); err != nil {
+		f.log.Warn("failed to close audit sink", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/auditsink"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// spool - очередь недоставленных событий на диске: файл JSONL, события дописываются в конец
+// и удаляются из начала только после успешной отправки. Очередь переживает перезапуск сервиса.
+// Строки, которые не удаётся разобрать (например, дописанные не до конца при сбое), переносятся
+// в файл карантина, чтобы не останавливать доставку остальных событий.
+type spool struct {
+	mu         sync.Mutex
+	path       string
+	quarantine string // Файл для неразборчивых строк очереди
+}
+
+// spoolBatch - начало очереди, прочитанное peek
+type spoolBatch struct {
+	events  []models.AuditEvent
+	lines   int      // Сколько строк в начале файла занимают events и corrupt
+	corrupt [][]byte // Неразборчивые строки; commit переносит их в карантин
+}
+
+// openSpool создаёт каталог очереди; файл появляется при первой записи
+func openSpool(dir, name string) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &spool{
+		path:       filepath.Join(dir, name+".jsonl"),
+		quarantine: filepath.Join(dir, name+".corrupt"),
+	}, nil
+}
+
+// append дописывает события и сбрасывает их на диск
+func (s *spool) append(events []models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(auditsink.NewRecord(e)); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	// Обрывок строки от прерванной записи завершается переводом строки, иначе он склеится
+	// с первым новым событием; peek перенесёт его в карантин
+	if torn, err := tornTail(f); err != nil || torn {
+		if err == nil {
+			_, err = f.Write([]byte{'\n'})
+		}
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// pending сообщает, есть ли в очереди события
+func (s *spool) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	return err == nil && info.Size() > 0
+}
+
+// peek возвращает до limit первых событий очереди, не удаляя их. Неразборчивые строки пропускаются
+// и возвращаются в corrupt. Последняя строка без перевода строки может дописываться прямо сейчас
+// или оборвана сбоем, поэтому не читается.
+func (s *spool) peek(limit int) (spoolBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b spoolBatch
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return b, err
+	}
+	defer f.Close()
+
+	rd := bufio.NewReaderSize(f, 64<<10)
+	for len(b.events) < limit {
+		line, err := rd.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return spoolBatch{}, err
+		}
+		b.lines++
+		var r auditsink.Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			b.corrupt = append(b.corrupt, line)
+			continue
+		}
+		b.events = append(b.events, r.Event())
+	}
+	return b, nil
+}
+
+// commit удаляет из начала очереди строки, прочитанные peek, и переносит неразборчивые строки в карантин.
+// События, дописанные после peek, сохраняются.
+func (s *spool) commit(b spoolBatch) error {
+	if b.lines == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(b.corrupt) > 0 {
+		if err := appendFile(s.quarantine, bytes.Join(b.corrupt, nil)); err != nil {
+			return fmt.Errorf("quarantine: %w", err)
+		}
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < b.lines && len(data) > 0; i++ {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break // Обрывок в конце файла peek не читал
+		}
+		data = data[end+1:]
+	}
+	if len(data) == 0 {
+		return os.Remove(s.path)
+	}
+
+	// Остаток записывается во временный файл и подменяет очередь атомарно: сбой не потеряет события
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// tornTail сообщает, что непустой файл не заканчивается переводом строки
+func tornTail(f *os.File) (bool, error) {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+// appendFile дописывает data в конец файла path и сбрасывает его на диск
+func appendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
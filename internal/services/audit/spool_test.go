@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func spoolEvents(seqs ...int64) []models.AuditEvent {
+	events := make([]models.AuditEvent, 0, len(seqs))
+	for _, seq := range seqs {
+		events = append(events, models.AuditEvent{Seq: seq, Type: models.AuditUserLogin, Outcome: models.AuditSuccess})
+	}
+	return events
+}
+
+func seqs(events []models.AuditEvent) []int64 {
+	out := make([]int64, 0, len(events))
+	for _, e := range events {
+		out = append(out, e.Seq)
+	}
+	return out
+}
+
+// writeRaw дописывает в файл очереди произвольные байты, как прерванная или повреждённая запись
+func writeRaw(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	require.NoError(t, err)
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestSpool_PeekCommit(t *testing.T) {
+	tests := []struct {
+		name           string
+		prepare        func(t *testing.T, s *spool)
+		limit          int
+		wantSeqs       []int64
+		wantCorrupt    int
+		wantLeft       []int64 // События в очереди после commit
+		wantQuarantine bool
+	}{
+		{
+			name:     "empty",
+			prepare:  func(*testing.T, *spool) {},
+			limit:    10,
+			wantSeqs: []int64{},
+		},
+		{
+			name: "limit",
+			prepare: func(t *testing.T, s *spool) {
+				require.NoError(t, s.append(spoolEvents(1, 2, 3)))
+			},
+			limit:    2,
+			wantSeqs: []int64{1, 2},
+			wantLeft: []int64{3},
+		},
+		{
+			name: "corrupt line is skipped",
+			prepare: func(t *testing.T, s *spool) {
+				require.NoError(t, s.append(spoolEvents(1)))
+				writeRaw(t, s.path, "{not json\n")
+				require.NoError(t, s.append(spoolEvents(2)))
+			},
+			limit:          10,
+			wantSeqs:       []int64{1, 2},
+			wantCorrupt:    1,
+			wantQuarantine: true,
+		},
+		{
+			name: "torn last line is not read",
+			prepare: func(t *testing.T, s *spool) {
+				require.NoError(t, s.append(spoolEvents(1)))
+				writeRaw(t, s.path, `{"seq":2,"ty`)
+			},
+			limit:    10,
+			wantSeqs: []int64{1},
+		},
+		{
+			name: "torn line is terminated by next append",
+			prepare: func(t *testing.T, s *spool) {
+				require.NoError(t, s.append(spoolEvents(1)))
+				writeRaw(t, s.path, `{"seq":2,"ty`)
+				require.NoError(t, s.append(spoolEvents(3)))
+			},
+			limit:          10,
+			wantSeqs:       []int64{1, 3},
+			wantCorrupt:    1,
+			wantQuarantine: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := openSpool(t.TempDir(), "sink")
+			require.NoError(t, err)
+			tt.prepare(t, s)
+
+			b, err := s.peek(tt.limit)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSeqs, seqs(b.events))
+			assert.Len(t, b.corrupt, tt.wantCorrupt)
+
+			require.NoError(t, s.commit(b))
+			left, err := s.peek(100)
+			require.NoError(t, err)
+			assert.Equal(t, append([]int64{}, tt.wantLeft...), seqs(left.events))
+			assert.Empty(t, left.corrupt)
+
+			_, err = os.Stat(s.quarantine)
+			assert.Equal(t, tt.wantQuarantine, err == nil)
+		})
+	}
+}
@@ -2,122 +2,260 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/linemk/gRPC_auth/internal/domain/models"
 	"github.com/linemk/gRPC_auth/internal/lib/jwt"
+	"github.com/linemk/gRPC_auth/internal/lib/passhash"
+	"github.com/linemk/gRPC_auth/internal/lib/requestid"
+	"github.com/linemk/gRPC_auth/internal/lib/tracing"
 	"github.com/linemk/gRPC_auth/internal/storage"
-	"golang.org/x/crypto/bcrypt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"log/slog"
+	"maps"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
+// tracer создаёт спаны методов сервиса; без настроенного поставщика трасс спаны не записываются
+var tracer = otel.Tracer("github.com/linemk/gRPC_auth/internal/services/auth")
+
 type Auth struct {
 	log          *slog.Logger  // Логгер для записи информации, предупреждений и ошибок
 	userSaver    UserSaver     // Интерфейс для сохранения пользователей
 	userProvider UserProvider  // Интерфейс для получения данных пользователя
 	appProvider  AppProvider   // Интерфейс для получения данных приложения
-	tokenTTL     time.Duration // Время жизни токена в формате Duration
+	sessions     SessionStore  // Интерфейс для хранения сеансов
+	tokenTTL     atomic.Int64  // Время жизни токена (time.Duration); меняется при перезагрузке конфигурации
+	gracePeriod  time.Duration // Срок, в течение которого удалённую учётную запись можно восстановить
+	metrics      Metrics       // Метрики входов, регистраций и хэширования паролей
+	auditor      Auditor       // Журнал аудита событий безопасности
 }
 
 type UserSaver interface {
-	SaveUser(ctx context.Context, email string, passHash []byte) (uid int64, err error) // Метод интерфейса для сохранения нового пользователя
+	SaveUser(ctx context.Context, email string, passHash []byte) (uid int64, err error)                  // Метод интерфейса для сохранения нового пользователя
+	UpdatePassword(ctx context.Context, userID int64, passHash []byte, passAlgo string) error            // Метод интерфейса для замены хэша пароля
+	SetUserStatus(ctx context.Context, userID int64, from, to models.UserStatus) error                   // Метод интерфейса для смены состояния учётной записи
+	TouchLastLogin(ctx context.Context, userID int64) error                                              // Метод интерфейса для записи времени входа
+	SoftDeleteUser(ctx context.Context, userID int64, from models.UserStatus, deletedAt time.Time) error // Метод интерфейса для мягкого удаления с отзывом сеансов
+	RestoreUser(ctx context.Context, userID int64, deletedAfter time.Time) error                         // Метод интерфейса для восстановления удалённой учётной записи
 }
 
 type UserProvider interface {
-	User(ctx context.Context, email string) (models.User, error) // Метод интерфейса для получения пользователя по email
-	IsAdmin(ctx context.Context, userID int64) (bool, error)     // Метод интерфейса для проверки, является ли пользователь администратором
+	User(ctx context.Context, email string) (models.User, error)     // Метод интерфейса для получения пользователя по email
+	UserByID(ctx context.Context, userID int64) (models.User, error) // Метод интерфейса для получения пользователя по идентификатору
+	IsAdmin(ctx context.Context, userID int64) (bool, error)         // Метод интерфейса для проверки, является ли пользователь администратором
+}
+
+type SessionStore interface {
+	CreateSession(ctx context.Context, session models.Session) error // Метод интерфейса для сохранения нового сеанса
+	Session(ctx context.Context, id string) (models.Session, error)  // Метод интерфейса для получения сеанса по идентификатору
 }
 
 type AppProvider interface {
 	App(ctx context.Context, appID int) (models.App, error) // Метод интерфейса для получения данных о приложении по appID
 }
 
+type Metrics interface {
+	LoginSucceeded(appID string)                                             // Метод интерфейса для учёта успешного входа
+	LoginFailed(appID, reason string)                                        // Метод интерфейса для учёта неудачного входа
+	UserRegistered(result string)                                            // Метод интерфейса для учёта регистрации
+	ObservePasswordHash(operation, algorithm string, duration time.Duration) // Метод интерфейса для учёта длительности хэширования
+}
+
+type Auditor interface {
+	Record(ctx context.Context, event models.AuditEvent) // Метод интерфейса для записи события в журнал аудита
+}
+
+// Причины неудачного входа и результаты регистрации для метрик
+const (
+	reasonUserNotFound    = "user_not_found"
+	reasonInvalidPassword = "invalid_password"
+	reasonInvalidApp      = "invalid_app"
+	reasonError           = "error"
+
+	registrationCreated = "created"
+	registrationExists  = "exists"
+	registrationError   = "error"
+
+	unknownApp = "unknown" // Метка для несуществующих приложений, чтобы клиент не мог раздуть число рядов
+)
+
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials") // Ошибка неверных учетных данных
 	ErrInvalidAppID       = errors.New("invalid app id")      // Ошибка некорректного идентификатора приложения
 	ErrUserExists         = errors.New("user already exists") // Ошибка, если пользователь уже существует
+	ErrInvalidToken       = errors.New("invalid token")       // Ошибка проверки токена
+	ErrNotAdmin           = errors.New("admin role required") // Ошибка, если операция доступна только администратору
+
+	ErrUserPendingVerification = errors.New("user is pending verification")  // Учётная запись ещё не подтверждена
+	ErrUserSuspended           = errors.New("user is suspended")             // Учётная запись приостановлена
+	ErrUserLocked              = errors.New("user is locked")                // Учётная запись заблокирована
+	ErrUserDeleted             = errors.New("user is deleted")               // Учётная запись удалена
+	ErrInvalidStatus           = errors.New("invalid user status")           // Неизвестное состояние учётной записи
+	ErrInvalidTransition       = errors.New("invalid status transition")     // Переход между состояниями запрещён
+	ErrGracePeriodOver         = errors.New("deletion grace period is over") // Удалённую учётную запись уже нельзя восстановить
 )
 
 // New создает объект Auth для работы сервиса
-func New(log *slog.Logger, userSaver UserSaver, userProvider UserProvider, appProvider AppProvider, tokenTTL time.Duration) *Auth {
-	return &Auth{
+func New(
+	log *slog.Logger,
+	userSaver UserSaver,
+	userProvider UserProvider,
+	appProvider AppProvider,
+	sessions SessionStore,
+	tokenTTL time.Duration,
+	gracePeriod time.Duration,
+	metrics Metrics,
+	auditor Auditor,
+) *Auth {
+	a := &Auth{
 		log:          log,          // Устанавливает логгер
 		userSaver:    userSaver,    // Устанавливает объект для сохранения пользователей
 		userProvider: userProvider, // Устанавливает объект для получения информации о пользователях
 		appProvider:  appProvider,  // Устанавливает объект для получения информации о приложениях
-		tokenTTL:     tokenTTL,     // Устанавливает время жизни токена
+		sessions:     sessions,     // Устанавливает объект для хранения сеансов
+		gracePeriod:  gracePeriod,  // Устанавливает срок восстановления удалённых учётных записей
+		metrics:      metrics,      // Устанавливает метрики сервиса
+		auditor:      auditor,      // Устанавливает журнал аудита
 	}
+	a.SetTokenTTL(tokenTTL) // Устанавливает время жизни токена
+	return a
 }
 
-func (a *Auth) Login(ctx context.Context, email string, password string, appID int) (string, error) {
+// SetTokenTTL меняет время жизни новых токенов и сеансов; выданные токены не меняются
+func (a *Auth) SetTokenTTL(ttl time.Duration) {
+	a.tokenTTL.Store(int64(ttl))
+}
+
+func (a *Auth) Login(ctx context.Context, email string, password string, appID int) (_ string, err error) {
 	const op = "auth.Login" // Название операции для логирования
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
 
-	log := a.log.With(
-		slog.String("op", op),          // Добавляет название операции в лог
-		slog.String("username", email), // Добавляет email пользователя в лог
+	log := a.logger(ctx).With(
+		slog.String("op", op),       // Добавляет название операции в лог
+		slog.String("email", email), // Добавляет email пользователя в лог
 	)
-	log.Info("checking user")                    // Логирует, что начата проверка пользователя
-	user, err := a.userProvider.User(ctx, email) // Получение информации о пользователе по email
+	user, err := a.authenticate(ctx, log, email, password, appID) // Проверяет email, пароль и состояние учётной записи
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) { // Если пользователь не найден
-			a.log.Warn("user not found", slog.String("email", email))  // Логирует предупреждение о том, что пользователь не найден
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials) // Возвращает ошибку "неверные учетные данные"
-		}
-		a.log.Error("failed to get user", err)   // Логирует ошибку получения пользователя
-		return "", fmt.Errorf("%s: %w", op, err) // Возвращает ошибку
-	}
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil { // Сравнивает хэш пароля с предоставленным паролем
-		a.log.Warn("invalid password", slog.String("email", email)) // Логирует предупреждение о некорректном пароле
-		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)  // Возвращает ошибку "неверные учетные данные"
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	app, err := a.appProvider.App(ctx, appID) // Получает данные приложения по appID
+	app, err := a.loginApp(ctx, user, appID) // Получает данные приложения по appID
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err) // Возвращает ошибку при получении приложения
 	}
-	log.Info("user logged in", slog.String("email", email)) // Логирует успешную авторизацию пользователя
+	log.Info("user logged in") // Логирует успешную авторизацию пользователя
 
-	token, err := jwt.NewToken(user, app, a.tokenTTL) // Генерирует новый JWT токен для пользователя
+	token, _, err := a.issueToken(ctx, log, user, app, nil) // Открывает сеанс и генерирует новый JWT токен для пользователя
 	if err != nil {
-		a.log.Error("failed to generate token", err) // Логирует ошибку генерации токена
-		return "", fmt.Errorf("%s: %w", op, err)     // Возвращает ошибку
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 	return token, nil // Возвращает токен
 }
 
-func (a *Auth) RegisterNewUser(ctx context.Context, email string, password string) (int64, error) {
+// Authenticate проверяет email, пароль и состояние учётной записи пользователя, входящего
+// в приложение appID, не выдавая токен. Неудачи учитываются в метриках и журнале аудита так же, как у Login.
+func (a *Auth) Authenticate(ctx context.Context, email string, password string, appID int) (_ models.User, err error) {
+	const op = "auth.Authenticate"
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	log := a.logger(ctx).With(slog.String("op", op), slog.String("email", email))
+	user, err := a.authenticate(ctx, log, email, password, appID)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// IssueToken открывает сеанс пользователя userID, ранее прошедшего Authenticate, в приложении appID
+// и выдаёт токен. Состояние учётной записи проверяется повторно: между входом и выдачей токена
+// её могли заблокировать. grant записывается в журнал аудита как способ входа.
+func (a *Auth) IssueToken(ctx context.Context, userID int64, appID int, grant string) (_ string, _ models.Session, err error) {
+	const op = "auth.IssueToken"
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	log := a.logger(ctx).With(slog.String("op", op), slog.Int64("user_id", userID), slog.String("grant", grant))
+	user, err := a.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		return "", models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := checkStatus(user.Status); err != nil {
+		log.Warn("token rejected", slog.String("status", string(user.Status)))
+		a.loginFailed(ctx, appID, string(user.Status))
+		a.auditLogin(ctx, user.ID, appID, string(user.Status))
+		return "", models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	app, err := a.loginApp(ctx, user, appID)
+	if err != nil {
+		return "", models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	token, session, err := a.issueToken(ctx, log, user, app, map[string]string{"grant": grant})
+	if err != nil {
+		return "", models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("token issued")
+	return token, session, nil
+}
+
+func (a *Auth) RegisterNewUser(ctx context.Context, email string, password string) (_ int64, err error) {
 	const op = "auth.RegisterNewUser" // Название операции для логирования
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
 
-	log := a.log.With(
+	log := a.logger(ctx).With(
 		slog.String("op", op),       // Добавляет название операции в лог
 		slog.String("email", email), // Добавляет email пользователя в лог
 	)
 
-	log.Info("registreting new user")                                                  // Логирует начало регистрации нового пользователя
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost) // Генерирует хэш для указанного пароля
+	log.Info("registreting new user")              // Логирует начало регистрации нового пользователя
+	passHash, err := a.hashPassword(ctx, password) // Генерирует хэш для указанного пароля
 	if err != nil {
-		log.Error("failed to hash password", err) // Логирует ошибку при создании хэша пароля
-		return 0, fmt.Errorf("%s: %w", op, err)   // Возвращает ошибку
+		log.Error("failed to hash password", slog.String("error", err.Error())) // Логирует ошибку при создании хэша пароля
+		a.metrics.UserRegistered(registrationError)                             // Учитывает неудачную регистрацию в метриках
+		return 0, fmt.Errorf("%s: %w", op, err)                                 // Возвращает ошибку
 	}
 
 	// сохраняем в БД
 	id, err := a.userSaver.SaveUser(ctx, email, passHash) // Сохраняет нового пользователя в БД
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) { // Если пользователь уже существует
-			log.Warn("user already exists", err)              // Логирует предупреждение о существующем пользователе
+			log.Warn("user already exists", slog.String("error", err.Error())) // Логирует предупреждение о существующем пользователе
+			a.metrics.UserRegistered(registrationExists)                       // Учитывает отказ в регистрации в метриках
+			a.auditor.Record(ctx, models.AuditEvent{                           // Записывает отказ в журнал аудита без email
+				Type:    models.AuditUserRegister,
+				Outcome: models.AuditFailure,
+				Details: map[string]string{"reason": registrationExists},
+			})
 			return 0, fmt.Errorf("%s: %w", op, ErrUserExists) // Возвращает ошибку "пользователь уже существует"
 		}
-		log.Error("failed to save user", err)   // Логирует ошибку сохранения пользователя
-		return 0, fmt.Errorf("%s: %w", op, err) // Возвращает ошибку
+		log.Error("failed to save user", slog.String("error", err.Error())) // Логирует ошибку сохранения пользователя
+		a.metrics.UserRegistered(registrationError)                         // Учитывает неудачную регистрацию в метриках
+		return 0, fmt.Errorf("%s: %w", op, err)                             // Возвращает ошибку
 	}
 
-	log.Info("user created", slog.String("email", email)) // Логирует успешное создание пользователя
-	return id, nil                                        // Возвращает идентификатор пользователя
+	log.Info("user created")                      // Логирует успешное создание пользователя
+	a.metrics.UserRegistered(registrationCreated) // Учитывает регистрацию в метриках
+	a.auditor.Record(ctx, models.AuditEvent{      // Записывает регистрацию в журнал аудита
+		Type:      models.AuditUserRegister,
+		Outcome:   models.AuditSuccess,
+		ActorID:   id,
+		SubjectID: id,
+	})
+	return id, nil // Возвращает идентификатор пользователя
 }
 
-func (a *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+func (a *Auth) IsAdmin(ctx context.Context, userID int64) (_ bool, err error) {
 	const op = "auth.IsAdmin" // Название операции для логирования
-	log := a.log.With(
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
+	log := a.logger(ctx).With(
 		slog.String("op", op),         // Добавляет название операции в лог
 		slog.Int64("user_id", userID), // Добавляет идентификатор пользователя в лог
 	)
@@ -125,11 +263,419 @@ func (a *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	isAdmin, err := a.userProvider.IsAdmin(ctx, userID) // Проверяет, является ли пользователь администратором
 	if err != nil {
 		if errors.Is(err, storage.ErrAppNotFound) { // Если приложение не найдено
-			log.Warn("app not found", err)                          // Логирует предупреждение, что приложение не найдено
-			return false, fmt.Errorf("%s: %w", op, ErrInvalidAppID) // Возвращает ошибку
+			log.Warn("app not found", slog.String("error", err.Error())) // Логирует предупреждение, что приложение не найдено
+			return false, fmt.Errorf("%s: %w", op, ErrInvalidAppID)      // Возвращает ошибку
 		}
 		return false, fmt.Errorf("%s: %w", op, err) // Возвращает ошибку
 	}
 	log.Info("checked if user is Admin", slog.Bool("Is_Admin", isAdmin)) // Логирует результат проверки
-	return isAdmin, nil                                                  // Возвращает результат проверки
+	a.auditor.Record(ctx, models.AuditEvent{                             // Записывает проверку в журнал аудита
+		Type:      models.AuditAdminCheck,
+		Outcome:   models.AuditSuccess,
+		SubjectID: userID,
+		Details:   map[string]string{"is_admin": strconv.FormatBool(isAdmin)},
+	})
+	return isAdmin, nil // Возвращает результат проверки
+}
+
+// upgradePassHash заменяет импортированный хэш пароля хэшем текущей схемы.
+// Ошибка не прерывает вход: пароль уже проверен, обновление повторится при следующем входе.
+func (a *Auth) upgradePassHash(ctx context.Context, log *slog.Logger, user models.User, password string) {
+	passHash, err := a.hashPassword(ctx, password)
+	if err != nil {
+		log.Error("failed to rehash password", slog.String("error", err.Error()))
+		return
+	}
+	if err := a.userSaver.UpdatePassword(ctx, user.ID, passHash, passhash.Current); err != nil {
+		log.Error("failed to upgrade password hash", slog.String("error", err.Error()))
+		return
+	}
+	log.Info("password hash upgraded", slog.String("from", user.PassAlgo), slog.String("to", passhash.Current))
+}
+
+// VerifyToken проверяет подпись и срок действия токена, выпущенного сервисом
+func (a *Auth) VerifyToken(ctx context.Context, token string) (_ jwt.Claims, err error) {
+	const op = "auth.VerifyToken"
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	claims, err := jwt.ParseToken(token, func(appID int) (string, error) {
+		app, err := a.appProvider.App(ctx, appID) // Секрет подписи принадлежит приложению
+		if err != nil {
+			return "", err
+		}
+		return app.Secret, nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrInvalidToken) {
+			return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Токен действителен, пока не отозван его сеанс
+	session, err := a.sessions.Session(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if session.Revoked() || session.UserID != claims.UserID {
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	// и пока действительна учётная запись владельца
+	user, err := a.userProvider.UserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return jwt.Claims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := checkStatus(user.Status); err != nil {
+		return jwt.Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return claims, nil
+}
+
+// AuthorizeAdmin проверяет токен и возвращает идентификатор пользователя, если он администратор
+func (a *Auth) AuthorizeAdmin(ctx context.Context, token string) (_ int64, err error) {
+	const op = "auth.AuthorizeAdmin"
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	claims, err := a.VerifyToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			a.auditAdminAccess(ctx, 0, models.AuditFailure, "invalid_token")
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	isAdmin, err := a.userProvider.IsAdmin(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) { // Хранилище сообщает так об отсутствии пользователя
+			return 0, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if !isAdmin {
+		a.logger(ctx).Warn("admin access denied", slog.String("op", op), slog.Int64("user_id", claims.UserID))
+		a.auditAdminAccess(ctx, claims.UserID, models.AuditFailure, "not_admin")
+		return 0, fmt.Errorf("%s: %w", op, ErrNotAdmin)
+	}
+	a.auditAdminAccess(ctx, claims.UserID, models.AuditSuccess, "")
+	return claims.UserID, nil
+}
+
+// SetUserStatus переводит учётную запись в новое состояние с проверкой допустимости перехода
+func (a *Auth) SetUserStatus(ctx context.Context, userID int64, to models.UserStatus) (_ models.User, err error) {
+	const op = "auth.SetUserStatus"
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
+	log := a.logger(ctx).With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.String("to", string(to)),
+	)
+
+	if !to.Valid() {
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidStatus)
+	}
+	user, err := a.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if user.Status == to {
+		return user, nil // Повторный запрос ничего не меняет
+	}
+	if !user.Status.CanTransitionTo(to) {
+		log.Warn("status transition rejected", slog.String("from", string(user.Status)))
+		a.auditor.Record(ctx, models.AuditEvent{
+			Type:      models.AuditUserStatusChange,
+			Outcome:   models.AuditFailure,
+			SubjectID: userID,
+			Details:   map[string]string{"from": string(user.Status), "to": string(to), "reason": "invalid_transition"},
+		})
+		return models.User{}, fmt.Errorf("%s: %s -> %s: %w", op, user.Status, to, ErrInvalidTransition)
+	}
+	switch {
+	case to == models.UserDeleted: // Удаление всегда отзывает сеансы и запускает льготный период
+		return a.DeleteAccount(ctx, userID)
+	case user.Status == models.UserDeleted: // Восстановление возможно только в течение льготного периода
+		return a.RestoreAccount(ctx, userID)
+	}
+	if err := a.userSaver.SetUserStatus(ctx, userID, user.Status, to); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user status changed", slog.String("from", string(user.Status)))
+	a.auditor.Record(ctx, models.AuditEvent{
+		Type:      models.AuditUserStatusChange,
+		Outcome:   models.AuditSuccess,
+		SubjectID: userID,
+		Details:   map[string]string{"from": string(user.Status), "to": string(to)},
+	})
+	user.Status = to
+	return user, nil
+}
+
+// DeleteAccount мягко удаляет учётную запись и отзывает все её сеансы.
+// Персональные данные стираются фоновой очисткой после окончания льготного периода.
+func (a *Auth) DeleteAccount(ctx context.Context, userID int64) (_ models.User, err error) {
+	const op = "auth.DeleteAccount"
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
+	log := a.logger(ctx).With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	user, err := a.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if user.Status == models.UserDeleted {
+		return user, nil // Повторное удаление не продлевает льготный период
+	}
+	if !user.Status.CanTransitionTo(models.UserDeleted) {
+		return models.User{}, fmt.Errorf("%s: %s -> %s: %w", op, user.Status, models.UserDeleted, ErrInvalidTransition)
+	}
+
+	deletedAt := time.Now().UTC()
+	if err := a.userSaver.SoftDeleteUser(ctx, userID, user.Status, deletedAt); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user deleted", slog.String("from", string(user.Status)), slog.Time("purge_after", deletedAt.Add(a.gracePeriod)))
+	a.auditor.Record(ctx, models.AuditEvent{
+		Type:      models.AuditUserDelete,
+		Outcome:   models.AuditSuccess,
+		SubjectID: userID,
+		Details:   map[string]string{"from": string(user.Status)},
+	})
+	user.Status, user.DeletedAt = models.UserDeleted, deletedAt
+	return user, nil
+}
+
+// RestoreAccount возвращает удалённую учётную запись в активное состояние, если льготный период не истёк.
+// Отозванные при удалении сеансы не восстанавливаются.
+func (a *Auth) RestoreAccount(ctx context.Context, userID int64) (_ models.User, err error) {
+	const op = "auth.RestoreAccount"
+	ctx, span := tracer.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	if err := a.userSaver.RestoreUser(ctx, userID, time.Now().Add(-a.gracePeriod)); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrGracePeriodOver):
+			a.auditor.Record(ctx, models.AuditEvent{
+				Type:      models.AuditUserRestore,
+				Outcome:   models.AuditFailure,
+				SubjectID: userID,
+				Details:   map[string]string{"reason": "grace_period_over"},
+			})
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrGracePeriodOver)
+		case errors.Is(err, storage.ErrStatusChanged):
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrInvalidTransition)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.logger(ctx).Info("user restored", slog.String("op", op), slog.Int64("user_id", userID))
+	a.auditor.Record(ctx, models.AuditEvent{Type: models.AuditUserRestore, Outcome: models.AuditSuccess, SubjectID: userID})
+	user, err := a.userProvider.UserByID(ctx, userID)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// PurgeAfter возвращает момент, после которого удалённая учётная запись будет очищена
+func (a *Auth) PurgeAfter(user models.User) time.Time {
+	return user.DeletedAt.Add(a.gracePeriod)
+}
+
+// authenticate проверяет email, пароль и состояние учётной записи. После успешной проверки
+// устаревший хэш пароля заменяется и записывается время входа.
+func (a *Auth) authenticate(ctx context.Context, log *slog.Logger, email string, password string, appID int) (models.User, error) {
+	log.Info("checking user")                    // Логирует, что начата проверка пользователя
+	user, err := a.userProvider.User(ctx, email) // Получение информации о пользователе по email
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) { // Если пользователь не найден
+			log.Warn("user not found")                      // Логирует предупреждение о том, что пользователь не найден
+			a.loginFailed(ctx, appID, reasonUserNotFound)   // Учитывает неудачный вход в метриках
+			a.auditLogin(ctx, 0, appID, reasonUserNotFound) // Записывает неудачный вход в журнал аудита
+			return models.User{}, ErrInvalidCredentials     // Возвращает ошибку "неверные учетные данные"
+		}
+		log.Error("failed to get user", slog.String("error", err.Error())) // Логирует ошибку получения пользователя
+		a.loginFailed(ctx, appID, reasonError)                             // Учитывает неудачный вход в метриках
+		return models.User{}, err                                          // Возвращает ошибку
+	}
+	if err := a.verifyPassword(ctx, user, password); err != nil { // Сравнивает хэш пароля с предоставленным паролем
+		if !errors.Is(err, passhash.ErrMismatch) { // Хэш не проверить: повреждён или дороже допустимого
+			log.Error("failed to verify password hash", slog.String("error", err.Error()))
+		}
+		log.Warn("invalid password")                             // Логирует предупреждение о некорректном пароле
+		a.loginFailed(ctx, appID, reasonInvalidPassword)         // Учитывает неудачный вход в метриках
+		a.auditLogin(ctx, user.ID, appID, reasonInvalidPassword) // Записывает неудачный вход в журнал аудита
+		return models.User{}, ErrInvalidCredentials              // Возвращает ошибку "неверные учетные данные"
+	}
+	if err := checkStatus(user.Status); err != nil { // Входить может только активный пользователь
+		log.Warn("login rejected", slog.String("status", string(user.Status)))
+		a.loginFailed(ctx, appID, string(user.Status)) // Причина отказа - состояние учётной записи
+		a.auditLogin(ctx, user.ID, appID, string(user.Status))
+		return models.User{}, err
+	}
+	if user.PassAlgo != passhash.Current { // Хэш импортирован из старой системы
+		a.upgradePassHash(ctx, log, user, password) // Перехэшируем пароль текущей схемой
+	}
+	if err := a.userSaver.TouchLastLogin(ctx, user.ID); err != nil { // Время входа не критично для выдачи токена
+		log.Error("failed to update last login time", slog.String("error", err.Error()))
+	}
+	return user, nil
+}
+
+// loginApp возвращает приложение, в которое входит пользователь; неизвестное приложение учитывается как неудачный вход
+func (a *Auth) loginApp(ctx context.Context, user models.User, appID int) (models.App, error) {
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			a.metrics.LoginFailed(unknownApp, reasonInvalidApp)
+			a.auditLogin(ctx, user.ID, appID, reasonInvalidApp)
+		} else {
+			a.metrics.LoginFailed(unknownApp, reasonError)
+		}
+		return models.App{}, err
+	}
+	return app, nil
+}
+
+// issueToken открывает сеанс и подписывает токен секретом приложения. details дополняют запись
+// об успешном входе в журнале аудита.
+func (a *Auth) issueToken(ctx context.Context, log *slog.Logger, user models.User, app models.App, details map[string]string) (string, models.Session, error) {
+	appLabel := strconv.Itoa(app.ID)
+	ttl := time.Duration(a.tokenTTL.Load())           // Одно значение для сеанса и токена, даже если конфигурация меняется
+	session, err := a.newSession(ctx, user, app, ttl) // Открывает сеанс, к которому привязывается токен
+	if err != nil {
+		log.Error("failed to create session", slog.String("error", err.Error()))
+		a.metrics.LoginFailed(appLabel, reasonError)
+		return "", models.Session{}, err
+	}
+
+	token, err := jwt.NewToken(user, app, session.ID, ttl) // Генерирует новый JWT токен для пользователя
+	if err != nil {
+		log.Error("failed to generate token", slog.String("error", err.Error())) // Логирует ошибку генерации токена
+		a.metrics.LoginFailed(appLabel, reasonError)
+		return "", models.Session{}, err
+	}
+	a.metrics.LoginSucceeded(appLabel) // Учитывает успешный вход в метриках
+
+	auditDetails := map[string]string{"session_id": session.ID} // Позволяет связать вход с отзывом сеанса
+	maps.Copy(auditDetails, details)
+	a.auditor.Record(ctx, models.AuditEvent{
+		Type:      models.AuditUserLogin,
+		Outcome:   models.AuditSuccess,
+		ActorID:   user.ID,
+		SubjectID: user.ID,
+		AppID:     app.ID,
+		Details:   auditDetails,
+	})
+	return token, session, nil
+}
+
+// hashPassword хэширует пароль текущей схемой и учитывает длительность в метриках
+func (a *Auth) hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := tracer.Start(ctx, "auth.hashPassword")
+	span.SetAttributes(attribute.String("password.algorithm", passhash.Current))
+	defer span.End()
+
+	start := time.Now()
+	passHash, err := passhash.Hash(password)
+	a.metrics.ObservePasswordHash("hash", passhash.Current, time.Since(start))
+	return passHash, err
+}
+
+// verifyPassword проверяет пароль по хэшу пользователя и учитывает длительность в метриках
+func (a *Auth) verifyPassword(ctx context.Context, user models.User, password string) error {
+	_, span := tracer.Start(ctx, "auth.verifyPassword")
+	span.SetAttributes(attribute.String("password.algorithm", user.PassAlgo))
+	defer span.End()
+
+	start := time.Now()
+	err := passhash.Verify(user.PassAlgo, user.PassHash, password)
+	a.metrics.ObservePasswordHash("verify", user.PassAlgo, time.Since(start))
+	return err
+}
+
+// loginFailed учитывает неудачный вход. Метка приложения ставится, только если оно существует,
+// иначе клиент мог бы создавать новые ряды метрик произвольными app_id.
+func (a *Auth) loginFailed(ctx context.Context, appID int, reason string) {
+	label := unknownApp
+	if _, err := a.appProvider.App(ctx, appID); err == nil {
+		label = strconv.Itoa(appID)
+	}
+	a.metrics.LoginFailed(label, reason)
+}
+
+// auditLogin записывает неудачный вход в журнал аудита. Email не записывается:
+// для неизвестного пользователя subjectID равен 0.
+func (a *Auth) auditLogin(ctx context.Context, subjectID int64, appID int, reason string) {
+	a.auditor.Record(ctx, models.AuditEvent{
+		Type:      models.AuditUserLogin,
+		Outcome:   models.AuditFailure,
+		SubjectID: subjectID,
+		AppID:     appID,
+		Details:   map[string]string{"reason": reason},
+	})
+}
+
+// auditAdminAccess записывает проверку токена администратора в журнал аудита
+func (a *Auth) auditAdminAccess(ctx context.Context, userID int64, outcome models.AuditOutcome, reason string) {
+	event := models.AuditEvent{Type: models.AuditAdminCheck, Outcome: outcome, ActorID: userID, SubjectID: userID}
+	if reason != "" {
+		event.Details = map[string]string{"reason": reason}
+	}
+	a.auditor.Record(ctx, event)
+}
+
+// logger возвращает логгер сервиса с идентификатором запроса из контекста
+func (a *Auth) logger(ctx context.Context) *slog.Logger {
+	return requestid.Logger(ctx, a.log)
+}
+
+// newSession создаёт сеанс со случайным идентификатором на время жизни токена ttl
+func (a *Auth) newSession(ctx context.Context, user models.User, app models.App, ttl time.Duration) (models.Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return models.Session{}, err
+	}
+	now := time.Now().UTC()
+	session := models.Session{
+		ID:        hex.EncodeToString(id),
+		UserID:    user.ID,
+		AppID:     app.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := a.sessions.CreateSession(ctx, session); err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+// checkStatus возвращает ошибку, соответствующую состоянию неактивной учётной записи
+func checkStatus(status models.UserStatus) error {
+	switch status {
+	case models.UserActive:
+		return nil
+	case models.UserPendingVerification:
+		return ErrUserPendingVerification
+	case models.UserSuspended:
+		return ErrUserSuspended
+	case models.UserLocked:
+		return ErrUserLocked
+	case models.UserDeleted:
+		return ErrUserDeleted
+	}
+	return ErrInvalidStatus
 }
@@ -0,0 +1,217 @@
+package dataexport
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/requestid"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"io"
+	"log/slog"
+	"time"
+)
+
+const (
+	Format  = "sso-personal-data" // Имя формата архива
+	Version = 1                   // Текущая версия формата
+)
+
+// Типы записей архива
+const (
+	kindHeader    = "header"
+	kindProfile   = "profile"
+	kindRole      = "role"
+	kindSession   = "session"
+	kindLogin     = "login"
+	kindSignature = "signature"
+)
+
+// notCollected перечисляет категории данных, которые сервис не хранит.
+// Они указываются в заголовке, чтобы отсутствие записей было явным.
+var notCollected = []string{"consents", "linked_identities"}
+
+// ErrUserNotFound возвращается, если пользователя для выгрузки нет
+var ErrUserNotFound = errors.New("user not found")
+
+// record - одна строка JSONL архива
+type record struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Header - первая строка архива
+type Header struct {
+	Format       string    `json:"format"`
+	Version      int       `json:"version"`
+	UserID       int64     `json:"user_id"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	KeyID        string    `json:"key_id"`
+	NotCollected []string  `json:"not_collected"`
+}
+
+type profileRecord struct {
+	ID          int64      `json:"id"`
+	Email       string     `json:"email"`
+	Status      string     `json:"status"`
+	IsAdmin     bool       `json:"is_admin"`
+	PassAlgo    string     `json:"password_algorithm"`
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+}
+
+type roleRecord struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type sessionRecord struct {
+	ID        string     `json:"id"`
+	AppID     int        `json:"app_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+type loginRecord struct {
+	At      time.Time `json:"at"`
+	AppID   int       `json:"app_id"`
+	AppName string    `json:"app_name,omitempty"`
+}
+
+// Source - хранилище, из которого собираются данные пользователя
+type Source interface {
+	UserByID(ctx context.Context, userID int64) (models.User, error)
+	UserRoles(ctx context.Context, userID int64) ([]models.Role, error)
+	ForEachUserSession(ctx context.Context, userID int64, fn func(session models.Session) error) error
+	App(ctx context.Context, appID int) (models.App, error)
+}
+
+// Exporter собирает всё, что хранится о пользователе, в подписанный JSONL архив
+type Exporter struct {
+	log    *slog.Logger
+	src    Source
+	signer *Signer
+}
+
+// New создаёт Exporter
+func New(log *slog.Logger, src Source, signer *Signer) *Exporter {
+	return &Exporter{log: log, src: src, signer: signer}
+}
+
+// Export записывает архив с данными пользователя в w по мере чтения из хранилища.
+// Последняя строка архива содержит подпись всех предыдущих строк.
+func (e *Exporter) Export(ctx context.Context, userID int64, w io.Writer) error {
+	const op = "dataexport.Export"
+	log := requestid.Logger(ctx, e.log).With(slog.String("op", op), slog.Int64("user_id", userID))
+
+	user, err := e.src.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	bw := bufio.NewWriter(w)
+	digest := sha512.New()
+	enc := json.NewEncoder(io.MultiWriter(bw, digest)) // Подписываются ровно те байты, что уходят клиенту
+	write := func(kind string, data any) error {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(record{Kind: kind, Data: raw})
+	}
+
+	if err := write(kindHeader, Header{
+		Format:       Format,
+		Version:      Version,
+		UserID:       user.ID,
+		GeneratedAt:  time.Now().UTC(),
+		KeyID:        e.signer.KeyID(),
+		NotCollected: notCollected,
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := write(kindProfile, profileRecord{
+		ID:          user.ID,
+		Email:       user.Email,
+		Status:      string(user.Status),
+		IsAdmin:     user.IsAdmin,
+		PassAlgo:    user.PassAlgo,
+		CreatedAt:   optionalTime(user.CreatedAt),
+		UpdatedAt:   optionalTime(user.UpdatedAt),
+		LastLoginAt: optionalTime(user.LastLoginAt),
+		DeletedAt:   optionalTime(user.DeletedAt),
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	roles, err := e.src.UserRoles(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for _, role := range roles {
+		if err := write(kindRole, roleRecord{Name: role.Name, Description: role.Description}); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// Каждый успешный вход открывает сеанс, поэтому история входов строится по сеансам
+	appNames := map[int]string{}
+	sessions := 0
+	err = e.src.ForEachUserSession(ctx, userID, func(session models.Session) error {
+		sessions++
+		if err := write(kindSession, sessionRecord{
+			ID:        session.ID,
+			AppID:     session.AppID,
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+			RevokedAt: optionalTime(session.RevokedAt),
+		}); err != nil {
+			return err
+		}
+		name, ok := appNames[session.AppID]
+		if !ok {
+			name = e.appName(ctx, session.AppID)
+			appNames[session.AppID] = name
+		}
+		return write(kindLogin, loginRecord{At: session.CreatedAt, AppID: session.AppID, AppName: name})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := write(kindSignature, e.signer.sign(digest.Sum(nil))); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("personal data exported", slog.Int("roles", len(roles)), slog.Int("sessions", sessions))
+	return nil
+}
+
+// appName возвращает имя приложения или пустую строку, если приложение уже удалено
+func (e *Exporter) appName(ctx context.Context, appID int) string {
+	app, err := e.src.App(ctx, appID)
+	if err != nil {
+		return ""
+	}
+	return app.Name
+}
+
+// optionalTime превращает нулевое время в null
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
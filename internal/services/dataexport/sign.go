@@ -0,0 +1,195 @@
+package dataexport
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// signatureAlgorithm - Ed25519ph: подписывается SHA-512 дайджест архива,
+// поэтому архив не нужно держать в памяти ни при подписи, ни при проверке
+const signatureAlgorithm = "ed25519ph"
+
+// ErrInvalidSignature возвращается, если архив изменён или подписан другим ключом
+var ErrInvalidSignature = errors.New("invalid archive signature")
+
+var signOptions = &ed25519.Options{Hash: crypto.SHA512}
+
+// signatureRecord - последняя строка архива
+type signatureRecord struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	Signature []byte `json:"signature"`
+}
+
+// Signer подписывает архивы закрытым ключом Ed25519
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner создаёт Signer из закрытого ключа
+func NewSigner(key ed25519.PrivateKey) *Signer {
+	return &Signer{key: key, keyID: KeyID(key.Public().(ed25519.PublicKey))}
+}
+
+// LoadSigner читает закрытый ключ Ed25519 в формате PEM (PKCS #8), например созданный
+// командой openssl genpkey -algorithm ed25519
+func LoadSigner(path string) (*Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseSigner(block)
+}
+
+// ParseSigner разбирает закрытый ключ Ed25519 в формате PEM (PKCS #8), переданный значением
+func ParseSigner(data []byte) (*Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("parse signing key: no PEM data")
+	}
+	return parseSigner(block)
+}
+
+// parseSigner создаёт Signer из PEM блока закрытого ключа
+func parseSigner(block *pem.Block) (*Signer, error) {
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key must be ed25519, got %T", key)
+	}
+	return NewSigner(edKey), nil
+}
+
+// GenerateSigner создаёт Signer со случайным ключом. Такой ключ живёт до перезапуска,
+// поэтому подписанные им архивы нельзя проверить позже.
+func GenerateSigner() (*Signer, error) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, fmt.Errorf("generate signing key: %w", err)
+	}
+	return NewSigner(key), nil
+}
+
+// KeyID возвращает идентификатор ключа подписи
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// sign подписывает SHA-512 дайджест архива
+func (s *Signer) sign(digest []byte) signatureRecord {
+	sig, err := s.key.Sign(nil, digest, signOptions)
+	if err != nil {
+		panic(err) // Ed25519ph с SHA-512 дайджестом не возвращает ошибок
+	}
+	return signatureRecord{Algorithm: signatureAlgorithm, KeyID: s.keyID, Signature: sig}
+}
+
+// KeyID возвращает короткий идентификатор открытого ключа
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// LoadPublicKey читает открытый ключ Ed25519 в формате PEM (PKIX).
+// Для удобства принимается и закрытый ключ: открытый выводится из него.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		signer, err := LoadSigner(path)
+		if err != nil {
+			return nil, err
+		}
+		return signer.key.Public().(ed25519.PublicKey), nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key must be ed25519, got %T", key)
+	}
+	return edKey, nil
+}
+
+// Verify проверяет подпись архива из r и возвращает его заголовок.
+// Архив читается построчно, поэтому размер архива не ограничен памятью.
+func Verify(r io.Reader, pub ed25519.PublicKey) (Header, error) {
+	const op = "dataexport.Verify"
+
+	br := bufio.NewReader(r)
+	digest := sha512.New()
+	var header Header
+	for line := 1; ; line++ {
+		raw, err := br.ReadBytes('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return Header{}, fmt.Errorf("%s: %w: signature record is missing", op, ErrInvalidSignature)
+			}
+			return Header{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		var rec record
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return Header{}, fmt.Errorf("%s: line %d: %w", op, line, err)
+		}
+		switch {
+		case line == 1:
+			if rec.Kind != kindHeader {
+				return Header{}, fmt.Errorf("%s: first record is %q, want %q", op, rec.Kind, kindHeader)
+			}
+			if err := json.Unmarshal(rec.Data, &header); err != nil {
+				return Header{}, fmt.Errorf("%s: header: %w", op, err)
+			}
+			if header.Format != Format || header.Version != Version {
+				return Header{}, fmt.Errorf("%s: unsupported format %q version %d", op, header.Format, header.Version)
+			}
+		case rec.Kind == kindSignature:
+			var sig signatureRecord
+			if err := json.Unmarshal(rec.Data, &sig); err != nil {
+				return Header{}, fmt.Errorf("%s: signature: %w", op, err)
+			}
+			if rest, _ := io.ReadAll(br); len(bytes.TrimSpace(rest)) > 0 {
+				return Header{}, fmt.Errorf("%s: %w: data after signature", op, ErrInvalidSignature)
+			}
+			if sig.Algorithm != signatureAlgorithm ||
+				ed25519.VerifyWithOptions(pub, digest.Sum(nil), sig.Signature, signOptions) != nil {
+				return Header{}, fmt.Errorf("%s: %w", op, ErrInvalidSignature)
+			}
+			return header, nil
+		}
+		digest.Write(raw)
+	}
+}
+
+// readPEM читает первый PEM блок из файла
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("read key %s: no PEM data", path)
+	}
+	return block, nil
+}
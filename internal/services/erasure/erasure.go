@@ -0,0 +1,127 @@
+package erasure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"log/slog"
+	"time"
+)
+
+// Режимы очистки персональных данных
+const (
+	ModeAnonymize = "anonymize" // Строка пользователя остаётся, персональные данные заменяются
+	ModeErase     = "erase"     // Строка пользователя удаляется целиком
+)
+
+// batchSize ограничивает число пользователей, очищаемых за один проход
+const batchSize = 100
+
+// ErrInvalidMode возвращается для неизвестного режима очистки
+var ErrInvalidMode = errors.New("invalid purge mode")
+
+// Store описывает хранилище, из которого стираются удалённые учётные записи
+type Store interface {
+	DeletedUsersBefore(ctx context.Context, before time.Time, limit int) ([]int64, error) // Пользователи, льготный период которых истёк
+	PurgeUser(ctx context.Context, userID int64, erase bool) error                        // Необратимая очистка одного пользователя
+}
+
+// Auditor записывает очистку в журнал аудита
+type Auditor interface {
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+// Purger периодически стирает персональные данные учётных записей,
+// удалённых раньше, чем истёк льготный период
+type Purger struct {
+	log         *slog.Logger
+	store       Store
+	gracePeriod time.Duration // Срок, в течение которого учётную запись можно восстановить
+	interval    time.Duration // Период между проходами очистки
+	erase       bool          // Удалять строку пользователя вместо обезличивания
+	auditor     Auditor       // Журнал аудита; в нём остаётся только идентификатор очищенного пользователя
+}
+
+// New создаёт Purger; mode - ModeAnonymize или ModeErase
+func New(log *slog.Logger, store Store, auditor Auditor, gracePeriod, interval time.Duration, mode string) (*Purger, error) {
+	if mode != ModeAnonymize && mode != ModeErase {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+	return &Purger{
+		log:         log.With(slog.String("component", "erasure")),
+		store:       store,
+		gracePeriod: gracePeriod,
+		interval:    interval,
+		erase:       mode == ModeErase,
+		auditor:     auditor,
+	}, nil
+}
+
+// Run выполняет очистку сразу и затем каждые interval, пока не отменён ctx
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.PurgeOnce(ctx); err != nil && ctx.Err() == nil {
+			p.log.Error("purge failed", slog.String("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// mode возвращает режим очистки
+func (p *Purger) mode() string {
+	if p.erase {
+		return ModeErase
+	}
+	return ModeAnonymize
+}
+
+// PurgeOnce очищает все учётные записи с истёкшим льготным периодом и возвращает их количество.
+// Каждый пользователь очищается в отдельной транзакции, поэтому прерванный проход
+// продолжится со следующего пользователя.
+func (p *Purger) PurgeOnce(ctx context.Context) (int, error) {
+	const op = "erasure.PurgeOnce"
+
+	before := time.Now().Add(-p.gracePeriod)
+	purged := 0
+	for {
+		ids, err := p.store.DeletedUsersBefore(ctx, before, batchSize)
+		if err != nil {
+			return purged, fmt.Errorf("%s: %w", op, err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		progressed := false
+		for _, id := range ids {
+			if err := p.store.PurgeUser(ctx, id, p.erase); err != nil {
+				if errors.Is(err, storage.ErrStatusChanged) || errors.Is(err, storage.ErrUserNotFound) {
+					continue // Пользователя восстановили или очистили параллельно
+				}
+				return purged, fmt.Errorf("%s: user %d: %w", op, id, err)
+			}
+			p.log.Info("user purged", slog.Int64("user_id", id), slog.Bool("erased", p.erase))
+			p.auditor.Record(ctx, models.AuditEvent{
+				Type:      models.AuditUserPurge,
+				Outcome:   models.AuditSuccess,
+				SubjectID: id,
+				Details:   map[string]string{"mode": p.mode()},
+			})
+			purged++
+			progressed = true
+		}
+		if !progressed || len(ids) < batchSize {
+			break
+		}
+	}
+	return purged, nil
+}
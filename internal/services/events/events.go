@@ -0,0 +1,327 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/webhook"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ограничения размера страницы ListDeadLetters
+const (
+	DefaultLimit = 100  // Если клиент не указал размер страницы
+	MaxLimit     = 1000 // Больше за один запрос не отдаётся
+)
+
+// Заголовки запроса к вебхуку, кроме подписи webhook.SignatureHeader и времени отправки webhook.TimestampHeader
+const (
+	EventIDHeader   = "X-SSO-Event-ID"         // Номер события; получатель по нему отбрасывает повторы
+	EventTypeHeader = "X-SSO-Event-Type"       // Тип события
+	AttemptHeader   = "X-SSO-Delivery-Attempt" // Номер попытки, начиная с 1
+)
+
+// pruneInterval - как часто удаляются доставленные события старше срока хранения
+const pruneInterval = time.Hour
+
+// ErrDuplicateEndpoint возвращается, если у приложения задано несколько вебхуков
+var ErrDuplicateEndpoint = errors.New("duplicate webhook for app")
+
+// Store описывает outbox событий и доставки на вебхуки
+type Store interface {
+	UndispatchedEvents(ctx context.Context, limit int) ([]models.DomainEvent, error)                    // События, ещё не разложенные по доставкам
+	DispatchEvent(ctx context.Context, eventID int64, appIDs []int, at time.Time) error                 // Создание доставок события
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)      // Доставки, время попытки которых наступило
+	MarkDelivered(ctx context.Context, deliveryID int64, at time.Time) error                            // Успешная доставка
+	MarkFailed(ctx context.Context, deliveryID int64, lastErr string, next time.Time, dead bool) error  // Неудачная попытка
+	DeadDeliveries(ctx context.Context, filter models.DeliveryFilter) ([]models.WebhookDelivery, error) // Недоставленные события
+	PruneOutbox(ctx context.Context, before time.Time) (int64, error)                                   // Удаление доставленных событий
+}
+
+// Endpoint - вебхук приложения
+type Endpoint struct {
+	AppID  int
+	URL    string
+	Secret string   // Ключ подписи HMAC-SHA256
+	Types  []string // Типы событий; "user.*" - все типы с префиксом "user.", пустой - все
+}
+
+// Options - параметры доставки
+type Options struct {
+	PollInterval     time.Duration // Как часто проверять outbox и доставки
+	BatchSize        int           // Сколько событий и доставок обрабатывается за проход
+	Timeout          time.Duration // Срок одного запроса к вебхуку
+	MaxAttempts      int           // После стольких неудачных попыток доставка попадает в недоставленные
+	RetryInterval    time.Duration // Интервал после первой неудачи; удваивается с каждой попыткой
+	MaxRetryInterval time.Duration // Больше интервал между попытками не растёт
+	Retention        time.Duration // Сколько хранить доставленные события
+}
+
+// Dispatcher доставляет события outbox на вебхуки приложений. Событие с приложением доставляется
+// только его вебхуку, остальные - всем вебхукам, подписанным на тип события. Каждое событие
+// доставляется не менее одного раза: до ответа 2xx или до исчерпания попыток.
+type Dispatcher struct {
+	log       *slog.Logger
+	store     Store
+	endpoints map[int]Endpoint // Вебхуки по идентификатору приложения
+	opts      Options
+	client    *http.Client
+}
+
+// New создаёт Dispatcher; у приложения может быть только один вебхук
+func New(log *slog.Logger, store Store, endpoints []Endpoint, opts Options) (*Dispatcher, error) {
+	byApp := make(map[int]Endpoint, len(endpoints))
+	for _, ep := range endpoints {
+		if _, ok := byApp[ep.AppID]; ok {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateEndpoint, ep.AppID)
+		}
+		byApp[ep.AppID] = ep
+	}
+	return &Dispatcher{
+		log:       log.With(slog.String("component", "events")),
+		store:     store,
+		endpoints: byApp,
+		opts:      opts,
+		client:    &http.Client{Timeout: opts.Timeout},
+	}, nil
+}
+
+// Run доставляет события каждые PollInterval, пока не отменён ctx. Попытка, прерванная остановкой,
+// повторяется после запуска.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+	defer d.client.CloseIdleConnections()
+
+	var pruned time.Time
+	for {
+		if err := d.DispatchOnce(ctx); err != nil && ctx.Err() == nil {
+			d.log.Error("event dispatch failed", slog.String("error", err.Error()))
+		}
+		if time.Since(pruned) >= pruneInterval {
+			if err := d.prune(ctx); err != nil && ctx.Err() == nil {
+				d.log.Error("outbox prune failed", slog.String("error", err.Error()))
+			}
+			pruned = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce раскладывает новые события по доставкам и выполняет доставки, время которых наступило
+func (d *Dispatcher) DispatchOnce(ctx context.Context) error {
+	const op = "events.DispatchOnce"
+
+	for {
+		events, err := d.store.UndispatchedEvents(ctx, d.opts.BatchSize)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, event := range events {
+			if err := d.store.DispatchEvent(ctx, event.ID, d.route(event), time.Now()); err != nil {
+				return fmt.Errorf("%s: event %d: %w", op, event.ID, err)
+			}
+		}
+		if len(events) < d.opts.BatchSize {
+			break
+		}
+	}
+
+	for {
+		deliveries, err := d.store.DueDeliveries(ctx, time.Now(), d.opts.BatchSize)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := d.deliverAll(ctx, deliveries); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(deliveries) < d.opts.BatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// DeadLetters возвращает доставки, попытки которых исчерпаны. Limit приводится к диапазону 1..MaxLimit.
+// nextAfterID - курсор следующей страницы, 0 - если страница неполная и записей больше нет.
+func (d *Dispatcher) DeadLetters(ctx context.Context, filter models.DeliveryFilter) (deliveries []models.WebhookDelivery, nextAfterID int64, err error) {
+	const op = "events.DeadLetters"
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = DefaultLimit
+	case filter.Limit > MaxLimit:
+		filter.Limit = MaxLimit
+	}
+	deliveries, err = d.store.DeadDeliveries(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(deliveries) == filter.Limit {
+		nextAfterID = deliveries[len(deliveries)-1].ID
+	}
+	return deliveries, nextAfterID, nil
+}
+
+// route возвращает приложения, вебхукам которых доставляется событие, по возрастанию идентификаторов
+func (d *Dispatcher) route(event models.DomainEvent) []int {
+	var appIDs []int
+	for appID, ep := range d.endpoints {
+		if event.AppID != 0 && event.AppID != appID {
+			continue
+		}
+		if ep.subscribed(event.Type) {
+			appIDs = append(appIDs, appID)
+		}
+	}
+	slices.Sort(appIDs)
+	return appIDs
+}
+
+// deliverAll выполняет доставки: вебхуки разных приложений параллельно, чтобы медленный получатель
+// не задерживал остальных, а доставки одному приложению - по очереди
+func (d *Dispatcher) deliverAll(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	byApp := make(map[int][]models.WebhookDelivery)
+	for _, delivery := range deliveries {
+		byApp[delivery.AppID] = append(byApp[delivery.AppID], delivery)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, queue := range byApp {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, delivery := range queue {
+				if err := d.deliver(ctx, delivery); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("delivery %d: %w", delivery.ID, err))
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// deliver выполняет одну попытку доставки и записывает её итог.
+// Ошибка возвращается, только если итог не удалось записать.
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) error {
+	attempt := delivery.Attempts + 1
+	log := d.log.With(
+		slog.Int64("delivery_id", delivery.ID),
+		slog.Int64("event_id", delivery.Event.ID),
+		slog.String("type", string(delivery.Event.Type)),
+		slog.Int("app_id", delivery.AppID),
+		slog.Int("attempt", attempt),
+	)
+
+	ep, ok := d.endpoints[delivery.AppID]
+	if !ok { // Вебхук убрали из настроек после раскладки
+		log.Error("webhook is not configured, event moved to dead letters")
+		return d.store.MarkFailed(ctx, delivery.ID, "webhook is not configured", time.Now(), true)
+	}
+
+	body, err := json.Marshal(newPayload(delivery.Event))
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set(EventIDHeader, strconv.FormatInt(delivery.Event.ID, 10))
+	header.Set(EventTypeHeader, string(delivery.Event.Type))
+	header.Set(AttemptHeader, strconv.Itoa(attempt))
+
+	err = webhook.Post(ctx, d.client, ep.URL, []byte(ep.Secret), body, header)
+	if err == nil {
+		log.Debug("event delivered")
+		return d.store.MarkDelivered(ctx, delivery.ID, time.Now())
+	}
+	if ctx.Err() != nil {
+		return nil // Попытка прервана остановкой и не засчитывается
+	}
+
+	dead := attempt >= d.opts.MaxAttempts
+	next := time.Now().Add(d.backoff(attempt))
+	if dead {
+		log.Error("webhook delivery failed, event moved to dead letters", slog.String("error", err.Error()))
+	} else {
+		log.Warn("webhook delivery failed", slog.String("error", err.Error()), slog.Time("next_attempt_at", next))
+	}
+	return d.store.MarkFailed(ctx, delivery.ID, err.Error(), next, dead)
+}
+
+// backoff возвращает интервал перед попыткой attempt+1: RetryInterval, удвоенный за каждую
+// предыдущую неудачу, не больше MaxRetryInterval, плюс до 10% случайно, чтобы повторы не шли одновременно
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	interval := d.opts.MaxRetryInterval
+	if shift := attempt - 1; shift < 32 && d.opts.RetryInterval<<shift < interval && d.opts.RetryInterval<<shift > 0 {
+		interval = d.opts.RetryInterval << shift
+	}
+	return interval + rand.N(interval/10+1)
+}
+
+// prune удаляет доставленные события старше срока хранения
+func (d *Dispatcher) prune(ctx context.Context) error {
+	pruned, err := d.store.PruneOutbox(ctx, time.Now().Add(-d.opts.Retention))
+	if err != nil {
+		return err
+	}
+	if pruned > 0 {
+		d.log.Info("outbox pruned", slog.Int64("events", pruned))
+	}
+	return nil
+}
+
+// subscribed сообщает, подписан ли вебхук на тип события
+func (ep Endpoint) subscribed(t models.EventType) bool {
+	return matchType(ep.Types, t)
+}
+
+// matchType сообщает, подходит ли тип события под список; "user.*" - все типы с префиксом "user.", пустой список - все
+func matchType(types []string, t models.EventType) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, want := range types {
+		if prefix, ok := strings.CutSuffix(want, "*"); ok {
+			if strings.HasPrefix(string(t), prefix) {
+				return true
+			}
+		} else if want == string(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// payload - тело запроса к вебхуку
+type payload struct {
+	ID         int64             `json:"id"`
+	Type       models.EventType  `json:"type"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	UserID     int64             `json:"user_id,omitempty"`
+	AppID      int               `json:"app_id,omitempty"`
+	Data       map[string]string `json:"data,omitempty"`
+}
+
+// newPayload собирает тело запроса из события
+func newPayload(e models.DomainEvent) payload {
+	return payload{ID: e.ID, Type: e.Type, OccurredAt: e.CreatedAt.UTC(), UserID: e.UserID, AppID: e.AppID, Data: e.Data}
+}
@@ -0,0 +1,150 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCursorExpired   = errors.New("events after cursor have been pruned") // Часть событий после курсора удалена по сроку хранения
+	ErrTooManyWatchers = errors.New("too many event watchers")              // Занято MaxWatchers подписок
+	ErrWatcherStopped  = errors.New("event watcher is stopped")             // Сервис останавливается
+)
+
+// WatchStore читает outbox для подписчиков
+type WatchStore interface {
+	EventsAfter(ctx context.Context, afterID int64, limit int) ([]models.DomainEvent, error) // События после курсора
+	PrunedThrough(ctx context.Context) (int64, error)                                        // Наибольший номер удалённого события
+}
+
+// WatchOptions - параметры подписок
+type WatchOptions struct {
+	PollInterval time.Duration // Как часто проверять новые события
+	BatchSize    int           // Сколько событий читается за раз
+	MaxWatchers  int           // Сколько подписок обслуживается одновременно
+}
+
+// Watcher передаёт события outbox подписчикам. Подписка читает outbox сама по курсору, поэтому
+// после переподключения с последним полученным курсором события не теряются, а медленный подписчик
+// не копит события в памяти: следующая пачка читается, только когда отправлена предыдущая.
+type Watcher struct {
+	log   *slog.Logger
+	store WatchStore
+	opts  WatchOptions
+	slots chan struct{} // Занятые подписки
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// NewWatcher создаёт Watcher
+func NewWatcher(log *slog.Logger, store WatchStore, opts WatchOptions) *Watcher {
+	return &Watcher{
+		log:     log.With(slog.String("component", "events.watch")),
+		store:   store,
+		opts:    opts,
+		slots:   make(chan struct{}, opts.MaxWatchers),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Watch передаёт send события после filter.AfterID, подходящие под фильтр, в порядке записи,
+// пока не отменён ctx, не остановлен Watcher или send не вернул ошибку.
+// send получает события очередной прочитанной пачки, возможно ни одного, и курсор - номер последнего
+// прочитанного события: с ним подписка продолжается без повторов и пропусков.
+// ErrCursorExpired - события после курсора могли быть удалены по сроку хранения, подписку нужно начать с 0.
+func (w *Watcher) Watch(ctx context.Context, filter models.EventFilter, send func(events []models.DomainEvent, cursor int64) error) error {
+	const op = "events.Watch"
+
+	select {
+	case <-w.stopped:
+		return fmt.Errorf("%s: %w", op, ErrWatcherStopped)
+	default:
+	}
+	select {
+	case w.slots <- struct{}{}:
+		defer func() { <-w.slots }()
+	default:
+		return fmt.Errorf("%s: %w", op, ErrTooManyWatchers)
+	}
+
+	cursor := filter.AfterID
+	if cursor == 0 {
+		pruned, err := w.store.PrunedThrough(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		cursor = pruned
+	}
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		events, err := w.store.EventsAfter(ctx, cursor, w.opts.BatchSize)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		// Граница проверяется после чтения: удаление между проверкой и чтением иначе осталось бы незамеченным
+		pruned, err := w.store.PrunedThrough(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if pruned > cursor {
+			return fmt.Errorf("%s: cursor %d, pruned through %d: %w", op, cursor, pruned, ErrCursorExpired)
+		}
+
+		if len(events) > 0 {
+			matched := make([]models.DomainEvent, 0, len(events))
+			for _, e := range events {
+				if matchFilter(filter, e) {
+					matched = append(matched, e)
+				}
+			}
+			cursor = events[len(events)-1].ID
+			if err := send(matched, cursor); err != nil {
+				return err
+			}
+		}
+
+		if len(events) == w.opts.BatchSize { // Подписчик отстал: следующая пачка читается без ожидания
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-w.stopped:
+				return fmt.Errorf("%s: %w", op, ErrWatcherStopped)
+			default:
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.stopped:
+			return fmt.Errorf("%s: %w", op, ErrWatcherStopped)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop завершает все подписки с ErrWatcherStopped и отклоняет новые. Подписки бесконечны,
+// поэтому без Stop плавная остановка gRPC сервера ждала бы их до истечения срока.
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopped)
+		w.log.Info("event watchers stopped")
+	})
+}
+
+// matchFilter сообщает, подходит ли событие под фильтр подписки. Событие без приложения подходит
+// под фильтр любого приложения: так же оно доставляется на вебхуки всех приложений.
+func matchFilter(filter models.EventFilter, e models.DomainEvent) bool {
+	if filter.AppID != 0 && e.AppID != 0 && e.AppID != filter.AppID {
+		return false
+	}
+	return matchType(filter.Types, e.Type)
+}
@@ -0,0 +1,285 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/lib/requestid"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const (
+	ChallengeMethodS256    = "S256"               // Единственный поддерживаемый метод PKCE: plain не защищает от перехвата кода
+	GrantAuthorizationCode = "authorization_code" // Тип обмена в запросе токена
+)
+
+// codeBytes - длина кода авторизации в байтах до кодирования
+const codeBytes = 32
+
+var (
+	ErrUnknownClient      = errors.New("unknown client")                                   // Приложение не найдено
+	ErrInvalidRedirectURI = errors.New("redirect_uri is not registered for the client")    // Адрес возврата не зарегистрирован
+	ErrInvalidChallenge   = errors.New("code_challenge with method S256 is required")      // Нет PKCE или метод не S256
+	ErrInvalidClient      = errors.New("client authentication failed")                     // Нет секрета или он неверный
+	ErrInvalidGrant       = errors.New("authorization code is invalid, expired or reused") // Код нельзя обменять на токен
+)
+
+// Store хранит приложения, коды авторизации и сеансы
+type Store interface {
+	App(ctx context.Context, appID int) (models.App, error)                                  // Приложение с адресами возврата
+	SaveAuthCode(ctx context.Context, code models.AuthCode) error                            // Сохранение выданного кода
+	UseAuthCode(ctx context.Context, codeHash string, at time.Time) (models.AuthCode, error) // Одноразовое использование кода
+	SetAuthCodeSession(ctx context.Context, codeHash, sessionID string) error                // Сеанс, открытый по коду
+	RevokeSession(ctx context.Context, id string) error                                      // Отзыв сеанса при повторе кода
+}
+
+// Authenticator проверяет пользователя и выдаёт токены так же, как вход по паролю
+type Authenticator interface {
+	Authenticate(ctx context.Context, email string, password string, appID int) (models.User, error)
+	IssueToken(ctx context.Context, userID int64, appID int, grant string) (string, models.Session, error)
+}
+
+// Auditor записывает события в журнал аудита
+type Auditor interface {
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+// AuthorizeRequest - параметры запроса авторизации
+type AuthorizeRequest struct {
+	ClientID            int    // Приложение (client_id)
+	RedirectURI         string // Адрес возврата, должен быть зарегистрирован у приложения
+	CodeChallenge       string // code_challenge PKCE
+	CodeChallengeMethod string // code_challenge_method, только S256
+}
+
+// TokenRequest - параметры обмена кода авторизации на токен
+type TokenRequest struct {
+	ClientID     int    // Приложение (client_id)
+	ClientSecret string // Секрет приложения; обязателен для конфиденциальных клиентов
+	Code         string // Код авторизации
+	RedirectURI  string // Тот же адрес возврата, что в запросе авторизации
+	CodeVerifier string // code_verifier PKCE
+}
+
+// Token - выданный токен доступа
+type Token struct {
+	AccessToken string        // Токен того же формата, что выдаёт Login
+	ExpiresIn   time.Duration // Оставшееся время жизни токена
+}
+
+// Server - сервер авторизации OAuth 2.0: выдаёт коды авторизации с PKCE и обменивает их на токены
+type Server struct {
+	log     *slog.Logger
+	store   Store
+	auth    Authenticator
+	auditor Auditor
+	codeTTL time.Duration // Время жизни кода авторизации
+}
+
+// New создаёт сервер авторизации
+func New(log *slog.Logger, store Store, auth Authenticator, auditor Auditor, codeTTL time.Duration) *Server {
+	return &Server{
+		log:     log,
+		store:   store,
+		auth:    auth,
+		auditor: auditor,
+		codeTTL: codeTTL,
+	}
+}
+
+// Client возвращает приложение clientID, если redirectURI зарегистрирован у него.
+// Пока клиент не проверен, ошибку нельзя отправлять на redirectURI: адрес может принадлежать злоумышленнику.
+func (s *Server) Client(ctx context.Context, clientID int, redirectURI string) (models.App, error) {
+	const op = "oauth.Client"
+
+	app, err := s.store.App(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.App{}, fmt.Errorf("%s: %w", op, ErrUnknownClient)
+		}
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !slices.Contains(app.RedirectURIs, redirectURI) { // Только точное совпадение, без префиксов и шаблонов
+		return models.App{}, fmt.Errorf("%s: %w", op, ErrInvalidRedirectURI)
+	}
+	return app, nil
+}
+
+// CheckChallenge проверяет параметры PKCE запроса авторизации: метод S256 и code_challenge -
+// base64url от SHA-256, 43 символа без дополнения
+func CheckChallenge(challenge, method string) error {
+	if method != ChallengeMethodS256 {
+		return ErrInvalidChallenge
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil || len(raw) != sha256.Size {
+		return ErrInvalidChallenge
+	}
+	return nil
+}
+
+// Authorize проверяет учётные данные пользователя и выдаёт приложению одноразовый код авторизации.
+// Ошибки проверки пользователя возвращаются от Authenticator без изменений.
+func (s *Server) Authorize(ctx context.Context, req AuthorizeRequest, email, password string) (string, error) {
+	const op = "oauth.Authorize"
+
+	app, err := s.Client(ctx, req.ClientID, req.RedirectURI)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := CheckChallenge(req.CodeChallenge, req.CodeChallengeMethod); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	user, err := s.auth.Authenticate(ctx, email, password, app.ID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	raw := make([]byte, codeBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	code := base64.RawURLEncoding.EncodeToString(raw)
+	now := time.Now()
+	if err := s.store.SaveAuthCode(ctx, models.AuthCode{
+		CodeHash:      hashCode(code),
+		AppID:         app.ID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		CodeChallenge: req.CodeChallenge,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(s.codeTTL),
+	}); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.auditor.Record(ctx, models.AuditEvent{
+		Type:      models.AuditOAuthAuthorize,
+		Outcome:   models.AuditSuccess,
+		ActorID:   user.ID,
+		SubjectID: user.ID,
+		AppID:     app.ID,
+	})
+	return code, nil
+}
+
+// Deny записывает отказ пользователя предоставить доступ приложению
+func (s *Server) Deny(ctx context.Context, req AuthorizeRequest) {
+	s.auditor.Record(ctx, models.AuditEvent{
+		Type:    models.AuditOAuthAuthorize,
+		Outcome: models.AuditFailure,
+		AppID:   req.ClientID,
+		Details: map[string]string{"reason": "access_denied"},
+	})
+}
+
+// Exchange обменивает код авторизации на токен. Код одноразовый: при повторном предъявлении
+// сеанс, открытый по нему, отзывается (RFC 6749, раздел 4.1.2), так как код мог быть перехвачен.
+func (s *Server) Exchange(ctx context.Context, req TokenRequest) (Token, error) {
+	const op = "oauth.Exchange"
+	log := requestid.Logger(ctx, s.log).With(slog.String("op", op), slog.Int("app_id", req.ClientID))
+
+	app, err := s.store.App(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return Token{}, fmt.Errorf("%s: %w", op, ErrUnknownClient)
+		}
+		return Token{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// Конфиденциальный клиент обязан предъявить секрет (RFC 6749, раздел 3.2.1); публичный защищён PKCE,
+	// но если всё же передал секрет, тот должен быть верным
+	switch {
+	case req.ClientSecret == "" && !app.PublicClient:
+		s.tokenFailed(ctx, 0, app.ID, "client_secret_required")
+		return Token{}, fmt.Errorf("%s: %w", op, ErrInvalidClient)
+	case req.ClientSecret != "" && subtle.ConstantTimeCompare([]byte(req.ClientSecret), []byte(app.Secret)) != 1:
+		s.tokenFailed(ctx, 0, app.ID, "invalid_client")
+		return Token{}, fmt.Errorf("%s: %w", op, ErrInvalidClient)
+	}
+
+	hash := hashCode(req.Code)
+	code, err := s.store.UseAuthCode(ctx, hash, time.Now())
+	switch {
+	case errors.Is(err, storage.ErrCodeUsed):
+		log.Warn("authorization code reused, revoking its session", slog.Int64("user_id", code.UserID))
+		if code.SessionID != "" {
+			if err := s.store.RevokeSession(ctx, code.SessionID); err != nil {
+				log.Error("failed to revoke session of reused code", slog.String("error", err.Error()))
+			}
+		}
+		s.tokenFailed(ctx, code.UserID, app.ID, "code_reused")
+		return Token{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	case errors.Is(err, storage.ErrCodeNotFound):
+		s.tokenFailed(ctx, 0, app.ID, "invalid_code")
+		return Token{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	case err != nil:
+		return Token{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case code.AppID != app.ID:
+		s.tokenFailed(ctx, code.UserID, app.ID, "client_mismatch")
+		return Token{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	case code.RedirectURI != req.RedirectURI:
+		s.tokenFailed(ctx, code.UserID, app.ID, "redirect_uri_mismatch")
+		return Token{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	case !verifyChallenge(code.CodeChallenge, req.CodeVerifier):
+		s.tokenFailed(ctx, code.UserID, app.ID, "invalid_code_verifier")
+		return Token{}, fmt.Errorf("%s: %w", op, ErrInvalidGrant)
+	}
+
+	token, session, err := s.auth.IssueToken(ctx, code.UserID, app.ID, GrantAuthorizationCode)
+	if err != nil {
+		return Token{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.store.SetAuthCodeSession(ctx, hash, session.ID); err != nil { // Без этого повтор кода не отзовёт сеанс
+		log.Error("failed to bind session to authorization code", slog.String("error", err.Error()))
+	}
+	return Token{AccessToken: token, ExpiresIn: time.Until(session.ExpiresAt)}, nil
+}
+
+// tokenFailed записывает отказ в обмене кода в журнал аудита
+func (s *Server) tokenFailed(ctx context.Context, userID int64, appID int, reason string) {
+	s.auditor.Record(ctx, models.AuditEvent{
+		Type:      models.AuditOAuthToken,
+		Outcome:   models.AuditFailure,
+		SubjectID: userID,
+		AppID:     appID,
+		Details:   map[string]string{"reason": reason},
+	})
+}
+
+// verifyChallenge проверяет code_verifier: 43-128 символов и base64url(SHA-256(verifier)) равен challenge (RFC 7636)
+func verifyChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, c := range verifier {
+		if !isUnreserved(c) {
+			return false
+		}
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// isUnreserved сообщает, допустим ли символ в code_verifier: буквы, цифры и -._~
+func isUnreserved(c rune) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// hashCode возвращает хэш кода для хранения: утечка таблицы кодов не даёт действующих кодов
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
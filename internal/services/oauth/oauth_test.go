@@ -0,0 +1,68 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// Пример из RFC 7636, приложение B
+const (
+	rfcVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func challengeOf(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestVerifyChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		verifier  string
+		want      bool
+	}{
+		{name: "rfc 7636 example", challenge: rfcChallenge, verifier: rfcVerifier, want: true},
+		{name: "minimum length", challenge: challengeOf(strings.Repeat("a", 43)), verifier: strings.Repeat("a", 43), want: true},
+		{name: "maximum length", challenge: challengeOf(strings.Repeat("a", 128)), verifier: strings.Repeat("a", 128), want: true},
+		{name: "all unreserved characters", challenge: challengeOf("ABCXYZabcxyz0189-._~" + strings.Repeat("q", 23)), verifier: "ABCXYZabcxyz0189-._~" + strings.Repeat("q", 23), want: true},
+		{name: "too short", challenge: challengeOf(strings.Repeat("a", 42)), verifier: strings.Repeat("a", 42)},
+		{name: "too long", challenge: challengeOf(strings.Repeat("a", 129)), verifier: strings.Repeat("a", 129)},
+		{name: "reserved character", challenge: challengeOf(rfcVerifier[:42] + "+"), verifier: rfcVerifier[:42] + "+"},
+		{name: "non-ascii character", challenge: challengeOf(rfcVerifier[:41] + "ж"), verifier: rfcVerifier[:41] + "ж"},
+		{name: "different verifier", challenge: rfcChallenge, verifier: strings.Repeat("a", 43)},
+		{name: "plain challenge", challenge: rfcVerifier, verifier: rfcVerifier},
+		{name: "padded challenge", challenge: rfcChallenge + "=", verifier: rfcVerifier},
+		{name: "empty challenge", challenge: "", verifier: rfcVerifier},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, verifyChallenge(tt.challenge, tt.verifier))
+		})
+	}
+}
+
+func TestCheckChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		method    string
+		wantErr   error
+	}{
+		{name: "s256", challenge: rfcChallenge, method: ChallengeMethodS256},
+		{name: "plain method", challenge: rfcVerifier, method: "plain", wantErr: ErrInvalidChallenge},
+		{name: "no method", challenge: rfcChallenge, method: "", wantErr: ErrInvalidChallenge},
+		{name: "empty challenge", challenge: "", method: ChallengeMethodS256, wantErr: ErrInvalidChallenge},
+		{name: "not base64url", challenge: strings.Replace(rfcChallenge, "-", "+", 1), method: ChallengeMethodS256, wantErr: ErrInvalidChallenge},
+		{name: "wrong digest size", challenge: rfcChallenge[:40], method: ChallengeMethodS256, wantErr: ErrInvalidChallenge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, CheckChallenge(tt.challenge, tt.method), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package userimport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reader читает строки импорта; в конце данных возвращает io.EOF.
+// Ошибка разбора отдельной строки возвращается как *RowError.
+type Reader interface {
+	Next() (Row, error)
+}
+
+// Поддерживаемые форматы входных данных
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// NewReader создаёт Reader для указанного формата
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		return &jsonlReader{scanner: newScanner(r)}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// csvReader читает CSV с заголовком email,password_hash[,algorithm][,is_admin]
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	line    int64
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1 // Число полей проверяем сами, чтобы ошибка касалась одной строки
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	for _, required := range []string{"email", "password_hash"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header has no %q column", required)
+		}
+	}
+	return &csvReader{r: cr, columns: columns, line: 1}, nil
+}
+
+func (c *csvReader) Next() (Row, error) {
+	record, err := c.r.Read()
+	c.line++
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return Row{}, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Row{}, &RowError{Line: c.line, Err: err}
+		}
+		return Row{}, err
+	}
+	if len(record) != len(c.columns) {
+		return Row{}, &RowError{Line: c.line, Err: fmt.Errorf("expected %d fields, got %d", len(c.columns), len(record))}
+	}
+
+	row := Row{
+		Line:         c.line,
+		Email:        strings.TrimSpace(record[c.columns["email"]]),
+		PasswordHash: strings.TrimSpace(record[c.columns["password_hash"]]),
+	}
+	if i, ok := c.columns["algorithm"]; ok {
+		row.Algorithm = strings.TrimSpace(record[i])
+	}
+	if i, ok := c.columns["is_admin"]; ok && strings.TrimSpace(record[i]) != "" {
+		if row.IsAdmin, err = strconv.ParseBool(strings.TrimSpace(record[i])); err != nil {
+			return Row{}, &RowError{Line: c.line, Email: row.Email, Err: fmt.Errorf("invalid is_admin: %w", err)}
+		}
+	}
+	return row, nil
+}
+
+// jsonlReader читает JSON объекты по одному на строку
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int64
+}
+
+// jsonlRow - формат строки JSONL
+type jsonlRow struct {
+	Email        string `json:"email"`
+	PasswordHash string `json:"password_hash"`
+	Algorithm    string `json:"algorithm"`
+	IsAdmin      bool   `json:"is_admin"`
+}
+
+func (j *jsonlReader) Next() (Row, error) {
+	for j.scanner.Scan() {
+		j.line++
+		text := strings.TrimSpace(j.scanner.Text())
+		if text == "" {
+			continue // Пустые строки пропускаем
+		}
+		var r jsonlRow
+		if err := json.Unmarshal([]byte(text), &r); err != nil {
+			return Row{}, &RowError{Line: j.line, Err: err}
+		}
+		return Row{Line: j.line, Email: r.Email, PasswordHash: r.PasswordHash, Algorithm: r.Algorithm, IsAdmin: r.IsAdmin}, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return Row{}, err
+	}
+	return Row{}, io.EOF
+}
+
+// newScanner создаёт сканер строк с увеличенным буфером
+func newScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	return s
+}
@@ -0,0 +1,172 @@
+package userimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/lib/passhash"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"io"
+	"log/slog"
+	"net/mail"
+)
+
+// Row - один импортируемый пользователь
+type Row struct {
+	Line         int64  // Номер строки во входных данных, служит точкой возобновления
+	Email        string // Email пользователя
+	PasswordHash string // Хэш пароля из внешней системы
+	Algorithm    string // Алгоритм хэша; если пуст, определяется по формату
+	IsAdmin      bool   // Флаг администратора
+}
+
+// RowError описывает ошибку обработки отдельной строки
+type RowError struct {
+	Line  int64  // Номер строки
+	Email string // Email из строки, если удалось прочитать
+	Err   error  // Причина
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d (%s): %v", e.Line, e.Email, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Progress - накопленная статистика импорта
+type Progress struct {
+	Processed  int64 // Обработано строк
+	Imported   int64 // Создано пользователей
+	Skipped    int64 // Пропущено (пользователь уже существует)
+	Failed     int64 // Строк с ошибками
+	Checkpoint int64 // Номер последней обработанной строки
+}
+
+var (
+	ErrInvalidEmail = errors.New("invalid email") // Некорректный email
+	ErrEmptyHash    = errors.New("empty hash")    // Не передан хэш пароля
+	ErrSkipped      = errors.New("user skipped")  // Пользователь уже существует
+)
+
+// UserImporter сохраняет пользователя с хэшем из внешней системы
+type UserImporter interface {
+	ImportUser(ctx context.Context, email string, passHash []byte, passAlgo string, isAdmin bool) (int64, error)
+}
+
+// Importer импортирует пользователей построчно
+type Importer struct {
+	log   *slog.Logger // Логгер
+	users UserImporter // Хранилище пользователей
+}
+
+// New создаёт Importer
+func New(log *slog.Logger, users UserImporter) *Importer {
+	return &Importer{
+		log:   log,
+		users: users,
+	}
+}
+
+// Import импортирует одну строку и обновляет статистику p.
+// Ошибки строки возвращаются как *RowError и не прерывают импорт;
+// любая другая ошибка означает, что продолжать нельзя.
+func (i *Importer) Import(ctx context.Context, row Row, p *Progress) error {
+	const op = "userimport.Import"
+
+	err := i.importRow(ctx, row)
+	p.Processed++
+	p.Checkpoint = row.Line
+
+	var rowErr *RowError
+	switch {
+	case err == nil:
+		p.Imported++
+		return nil
+	case errors.Is(err, ErrSkipped):
+		p.Skipped++
+		return nil
+	case errors.As(err, &rowErr):
+		p.Failed++
+		i.log.Warn("row rejected", slog.String("op", op), slog.Int64("line", row.Line), slog.String("error", rowErr.Err.Error()))
+		return err
+	default:
+		return fmt.Errorf("%s: line %d: %w", op, row.Line, err)
+	}
+}
+
+// importRow проверяет строку и сохраняет пользователя
+func (i *Importer) importRow(ctx context.Context, row Row) error {
+	if _, err := mail.ParseAddress(row.Email); err != nil || row.Email == "" {
+		return &RowError{Line: row.Line, Email: row.Email, Err: ErrInvalidEmail}
+	}
+	if row.PasswordHash == "" {
+		return &RowError{Line: row.Line, Email: row.Email, Err: ErrEmptyHash}
+	}
+
+	algo := row.Algorithm
+	if algo == "" {
+		var err error
+		if algo, err = passhash.Detect(row.PasswordHash); err != nil {
+			return &RowError{Line: row.Line, Email: row.Email, Err: err}
+		}
+	}
+	if err := passhash.Validate(algo, row.PasswordHash); err != nil {
+		return &RowError{Line: row.Line, Email: row.Email, Err: err}
+	}
+
+	if _, err := i.users.ImportUser(ctx, row.Email, []byte(row.PasswordHash), algo, row.IsAdmin); err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			return ErrSkipped
+		}
+		return err
+	}
+	return nil
+}
+
+// progressBatch - как часто Run сообщает о прогрессе
+const progressBatch = 100
+
+// Run импортирует все строки из r, пропуская строки с номером не больше checkpoint.
+// report вызывается для каждой отклонённой строки (rowErr != nil)
+// и после каждых progressBatch строк, а также в конце импорта (rowErr == nil).
+func (i *Importer) Run(ctx context.Context, r Reader, checkpoint int64, report func(p Progress, rowErr *RowError)) (Progress, error) {
+	p := Progress{Checkpoint: checkpoint}
+	for {
+		if err := ctx.Err(); err != nil {
+			return p, err
+		}
+
+		row, err := r.Next()
+		var rowErr *RowError
+		switch {
+		case errors.Is(err, io.EOF):
+			report(p, nil)
+			return p, nil
+		case errors.As(err, &rowErr):
+			// Строку не удалось разобрать
+			if rowErr.Line > checkpoint {
+				p.Processed++
+				p.Failed++
+				p.Checkpoint = rowErr.Line
+				report(p, rowErr)
+			}
+			continue
+		case err != nil:
+			return p, err
+		case row.Line <= checkpoint:
+			continue // Строка обработана в предыдущем запуске
+		}
+
+		if err := i.Import(ctx, row, &p); err != nil {
+			if !errors.As(err, &rowErr) {
+				return p, err
+			}
+			report(p, rowErr)
+		}
+		if p.Processed%progressBatch == 0 {
+			report(p, nil)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"strings"
+	"time"
+)
+
+// auditPageSize - сколько записей журнала аудита читается за один запрос при обходе
+const auditPageSize = 500
+
+// auditColumns - столбцы записи журнала аудита в порядке scanAuditEvent
+const auditColumns = "seq, created_at, type, outcome, actor_id, subject_id, app_id, request_id, details, prev_hash, hash"
+
+// AppendAuditEvent дописывает событие в конец журнала аудита: присваивает номер,
+// связывает с последней записью и вычисляет хэш. Возвращает записанное событие.
+// Параллельная дозапись из другого соединения нарушит уникальность prev_hash и вернёт ошибку.
+func (s *Storage) AppendAuditEvent(ctx context.Context, event models.AuditEvent) (models.AuditEvent, error) {
+	const op = "storage.sqlite.AppendAuditEvent"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return models.AuditEvent{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(event.Details) == 0 {
+		details = []byte("{}")
+	}
+	event.Time = event.Time.UTC().Truncate(time.Microsecond) // С такой точностью время читается обратно
+
+	err = s.WithTx(ctx, func(tx *Storage) error {
+		event.Seq, event.PrevHash = 1, models.AuditGenesisHash
+		err := tx.db.QueryRowContext(ctx, "SELECT seq + 1, hash FROM audit_log ORDER BY seq DESC LIMIT 1").
+			Scan(&event.Seq, &event.PrevHash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		event.Hash = event.ComputeHash()
+
+		_, err = tx.db.ExecContext(ctx, `
+			INSERT INTO audit_log (seq, created_at, type, outcome, actor_id, subject_id, app_id, request_id, details, prev_hash, hash)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			event.Seq, event.Time, event.Type, event.Outcome, event.ActorID, event.SubjectID, event.AppID,
+			event.RequestID, string(details), event.PrevHash, event.Hash)
+		return err
+	})
+	if err != nil {
+		return models.AuditEvent{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return event, nil
+}
+
+// AuditEvents возвращает записи журнала аудита по фильтру в порядке номеров
+func (s *Storage) AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	const op = "storage.sqlite.AuditEvents"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	var (
+		where = []string{"seq > ?"}
+		args  = []any{filter.AfterSeq}
+	)
+	if len(filter.Types) > 0 {
+		where = append(where, "type IN (?"+strings.Repeat(", ?", len(filter.Types)-1)+")")
+		for _, t := range filter.Types {
+			args = append(args, t)
+		}
+	}
+	if filter.Outcome != "" {
+		where, args = append(where, "outcome = ?"), append(args, filter.Outcome)
+	}
+	if filter.ActorID != 0 {
+		where, args = append(where, "actor_id = ?"), append(args, filter.ActorID)
+	}
+	if filter.SubjectID != 0 {
+		where, args = append(where, "subject_id = ?"), append(args, filter.SubjectID)
+	}
+	if !filter.Since.IsZero() {
+		where, args = append(where, "created_at >= ?"), append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		where, args = append(where, "created_at < ?"), append(args, filter.Until.UTC())
+	}
+	args = append(args, filter.Limit)
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+auditColumns+" FROM audit_log WHERE "+strings.Join(where, " AND ")+" ORDER BY seq LIMIT ?", args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// ForEachAuditEvent по одному передаёт в fn все записи журнала аудита в порядке номеров.
+// Записи читаются страницами, как в ForEachUserSession, чтобы проверка длинного журнала
+// не держала блокировку чтения базы.
+func (s *Storage) ForEachAuditEvent(ctx context.Context, fn func(event models.AuditEvent) error) error {
+	const op = "storage.sqlite.ForEachAuditEvent"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	var afterSeq int64
+	for {
+		page, err := s.AuditEvents(ctx, models.AuditFilter{AfterSeq: afterSeq, Limit: auditPageSize})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, event := range page {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if len(page) < auditPageSize {
+			return nil
+		}
+		afterSeq = page[len(page)-1].Seq
+	}
+}
+
+// scanAuditEvent читает запись журнала аудита из строки с auditColumns
+func scanAuditEvent(rows *sql.Rows) (models.AuditEvent, error) {
+	var (
+		event   models.AuditEvent
+		details string
+	)
+	err := rows.Scan(&event.Seq, &event.Time, &event.Type, &event.Outcome, &event.ActorID, &event.SubjectID,
+		&event.AppID, &event.RequestID, &details, &event.PrevHash, &event.Hash)
+	if err != nil {
+		return models.AuditEvent{}, err
+	}
+	if err := json.Unmarshal([]byte(details), &event.Details); err != nil {
+		return models.AuditEvent{}, fmt.Errorf("seq %d: details: %w", event.Seq, err)
+	}
+	return event, nil
+}
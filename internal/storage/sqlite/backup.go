@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"time"
+)
+
+const (
+	backupStepPages = 256                   // Количество страниц, копируемых за один шаг
+	backupStepPause = 10 * time.Millisecond // Пауза между шагами, чтобы не блокировать писателей
+)
+
+// Backup делает согласованную копию базы в файл destPath через SQLite backup API.
+// Копирование идёт порциями, поэтому сервис может продолжать работу.
+func (s *Storage) Backup(ctx context.Context, destPath string) error {
+	const op = "storage.sqlite.Backup"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	dest, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer dest.Close()
+
+	if err := copyDatabase(ctx, dest, s.conn); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Restore заменяет содержимое базы копией из файла srcPath.
+// Перед восстановлением копия проверяется на целостность.
+// Сервис, работающий с базой, на время восстановления должен быть остановлен.
+func (s *Storage) Restore(ctx context.Context, srcPath string) error {
+	const op = "storage.sqlite.Restore"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	src, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer src.Close()
+
+	var result string
+	if err := src.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%s: backup %s is corrupted: %s", op, srcPath, result)
+	}
+
+	if err := copyDatabase(ctx, s.conn, src); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// copyDatabase копирует основную базу соединения src в dest
+func copyDatabase(ctx context.Context, dest, src *sql.DB) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destRaw any) error {
+		return srcConn.Raw(func(srcRaw any) error {
+			destSQLite, ok := destRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", destRaw)
+			}
+			srcSQLite, ok := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", srcRaw)
+			}
+
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			for {
+				done, err := backup.Step(backupStepPages)
+				if err != nil {
+					_ = backup.Finish()
+					return err
+				}
+				if done {
+					return backup.Finish()
+				}
+				select {
+				case <-ctx.Done():
+					_ = backup.Finish()
+					return ctx.Err()
+				case <-time.After(backupStepPause):
+				}
+			}
+		})
+	})
+}
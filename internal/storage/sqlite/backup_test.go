@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newBackupStorage создаёт базу с таблицей из rows строк, занимающей несколько страниц
+func newBackupStorage(t *testing.T, rows int) (*Storage, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sso.db")
+	st, err := NewStorage(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = st.Close() })
+
+	_, err = st.conn.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, value TEXT NOT NULL)")
+	require.NoError(t, err)
+	for i := 0; i < rows; i++ {
+		_, err = st.conn.Exec("INSERT INTO items (value) VALUES (?)", strings.Repeat("x", 100))
+		require.NoError(t, err)
+	}
+	return st, path
+}
+
+// countItems возвращает число строк в таблице items
+func countItems(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM items").Scan(&n))
+	return n
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	st, _ := newBackupStorage(t, 200)
+
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, st.Backup(ctx, backup))
+
+	_, err := st.conn.Exec("DELETE FROM items WHERE id > 50")
+	require.NoError(t, err)
+	require.Equal(t, 50, countItems(t, st.conn))
+
+	require.NoError(t, st.Restore(ctx, backup))
+	assert.Equal(t, 200, countItems(t, st.conn))
+}
+
+func TestRestore_FailCases(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, path string)
+		wantErr string
+	}{
+		{
+			name: "not a database",
+			corrupt: func(t *testing.T, path string) {
+				require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("not a database\n", 1000)), 0o600))
+			},
+			wantErr: "file is not a database",
+		},
+		{
+			name: "damaged pages",
+			corrupt: func(t *testing.T, path string) {
+				f, err := os.OpenFile(path, os.O_RDWR, 0)
+				require.NoError(t, err)
+				defer f.Close()
+				// Затираем страницы таблицы, оставляя заголовок и схему на первой странице
+				_, err = f.WriteAt([]byte(strings.Repeat("\xff", 3*4096)), 2*4096)
+				require.NoError(t, err)
+			},
+			wantErr: "is corrupted",
+		},
+		{
+			name:    "missing file",
+			corrupt: func(t *testing.T, path string) { require.NoError(t, os.Remove(path)) },
+			wantErr: "unable to open database file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, _ := newBackupStorage(t, 200)
+			backup := filepath.Join(t.TempDir(), "backup.db")
+			require.NoError(t, st.Backup(ctx, backup))
+			tt.corrupt(t, backup)
+
+			_, err := st.conn.Exec("DELETE FROM items WHERE id > 50")
+			require.NoError(t, err)
+
+			err = st.Restore(ctx, backup)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			// Повреждённая копия не должна затронуть рабочую базу
+			assert.Equal(t, 50, countItems(t, st.conn))
+		})
+	}
+}
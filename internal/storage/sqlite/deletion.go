@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"time"
+)
+
+// SoftDeleteUser помечает пользователя удалённым, отзывает все его сеансы и записывает событие в одной транзакции
+func (s *Storage) SoftDeleteUser(ctx context.Context, userID int64, from models.UserStatus, deletedAt time.Time) error {
+	const op = "storage.sqlite.SoftDeleteUser"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	err := s.WithTx(ctx, func(tx *Storage) error {
+		res, err := tx.db.ExecContext(ctx,
+			"UPDATE users SET status = ?, deleted_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND status = ?",
+			models.UserDeleted, deletedAt.UTC(), userID, from)
+		if err != nil {
+			return err
+		}
+		if err := tx.expectAffected(ctx, res, userID); err != nil {
+			return err
+		}
+		if _, err := tx.RevokeUserSessions(ctx, userID); err != nil {
+			return err
+		}
+		return tx.addEvent(ctx, models.DomainEvent{
+			Type:   models.EventUserDeleted,
+			UserID: userID,
+			Data:   map[string]string{"from": string(from)},
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RestoreUser возвращает удалённого пользователя в активное состояние,
+// если он был удалён не раньше deletedAfter
+func (s *Storage) RestoreUser(ctx context.Context, userID int64, deletedAfter time.Time) error {
+	const op = "storage.sqlite.RestoreUser"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	err := s.WithTx(ctx, func(tx *Storage) error {
+		user, err := tx.UserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.Status != models.UserDeleted {
+			return storage.ErrStatusChanged
+		}
+		if !user.DeletedAt.After(deletedAfter) {
+			return storage.ErrGracePeriodOver
+		}
+		_, err = tx.db.ExecContext(ctx,
+			"UPDATE users SET status = ?, deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			models.UserActive, userID)
+		if err != nil {
+			return err
+		}
+		return tx.addEvent(ctx, models.DomainEvent{Type: models.EventUserRestored, UserID: userID})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// DeletedUsersBefore возвращает идентификаторы пользователей, удалённых до момента before
+// и ещё не очищенных
+func (s *Storage) DeletedUsersBefore(ctx context.Context, before time.Time, limit int) ([]int64, error) {
+	const op = "storage.sqlite.DeletedUsersBefore"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id FROM users
+		WHERE status = ? AND deleted_at IS NOT NULL AND deleted_at < ? AND purged_at IS NULL
+		ORDER BY deleted_at
+		LIMIT ?`, models.UserDeleted, before.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return ids, nil
+}
+
+// PurgeUser необратимо удаляет персональные данные пользователя в одной транзакции:
+// записывает надгробие (только идентификатор и время удаления и очистки), удаляет сеансы, назначения ролей и коды авторизации, затем
+// обезличивает строку пользователя (или удаляет её, если erase). Email убирается и из событий outbox,
+// ещё не удалённых по сроку хранения. После этого email снова свободен для регистрации.
+func (s *Storage) PurgeUser(ctx context.Context, userID int64, erase bool) error {
+	const op = "storage.sqlite.PurgeUser"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	err := s.WithTx(ctx, func(tx *Storage) error {
+		user, err := tx.UserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.Status != models.UserDeleted || user.DeletedAt.IsZero() || user.Email == erasedEmail(userID) {
+			return storage.ErrStatusChanged // Пользователя успели восстановить
+		}
+		now := time.Now().UTC()
+
+		if _, err := tx.db.ExecContext(ctx,
+			"INSERT INTO user_tombstones (user_id, deleted_at, purged_at) VALUES (?, ?, ?)",
+			user.ID, user.DeletedAt.UTC(), now); err != nil {
+			return err
+		}
+		for _, query := range []string{
+			"DELETE FROM sessions WHERE user_id = ?",
+			"DELETE FROM user_roles WHERE user_id = ?",
+			"DELETE FROM oauth_codes WHERE user_id = ?",
+			"UPDATE outbox SET data = json_remove(data, '$.email') WHERE user_id = ?",
+		} {
+			if _, err := tx.db.ExecContext(ctx, query, userID); err != nil {
+				return err
+			}
+		}
+
+		mode := "erase"
+		if erase {
+			_, err = tx.db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", userID)
+		} else {
+			// Строка остаётся, чтобы ссылки на идентификатор не повисли, но данные обезличены
+			mode = "anonymize"
+			_, err = tx.db.ExecContext(ctx, `
+				UPDATE users
+				SET email = ?, pass_hash = x'', pass_algo = '', is_admin = FALSE, last_login_at = NULL,
+				    purged_at = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?`, erasedEmail(userID), now, userID)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.addEvent(ctx, models.DomainEvent{Type: models.EventUserPurged, UserID: userID, Data: map[string]string{"mode": mode}})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// erasedEmail возвращает уникальный адрес-заглушку для обезличенного пользователя
+func erasedEmail(userID int64) string {
+	return fmt.Sprintf("erased-%d@invalid", userID)
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"strings"
+)
+
+// Apps возвращает все зарегистрированные приложения
+func (s *Storage) Apps(ctx context.Context) ([]models.App, error) {
+	const op = "storage.sqlite.Apps"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, secret, redirect_uris, public_client FROM apps ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var apps []models.App
+	for rows.Next() {
+		var (
+			app          models.App
+			redirectURIs string
+		)
+		if err := rows.Scan(&app.ID, &app.Name, &app.Secret, &redirectURIs, &app.PublicClient); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if app.RedirectURIs, err = decodeRedirectURIs(redirectURIs); err != nil {
+			return nil, fmt.Errorf("%s: app %d: %w", op, app.ID, err)
+		}
+		apps = append(apps, app)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return apps, nil
+}
+
+// Roles возвращает все роли
+func (s *Storage) Roles(ctx context.Context) ([]models.Role, error) {
+	const op = "storage.sqlite.Roles"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, description FROM roles ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return roles, nil
+}
+
+// ForEachUser по одному передаёт в fn всех пользователей вместе с именами их ролей.
+// Пользователи читаются курсором, поэтому вся таблица не загружается в память.
+func (s *Storage) ForEachUser(ctx context.Context, fn func(user models.User, roles []string) error) error {
+	const op = "storage.sqlite.ForEachUser"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT u.id, u.email, u.pass_hash, u.pass_algo, u.is_admin, u.status, u.deleted_at, GROUP_CONCAT(r.name, ',')
+		FROM users u
+		LEFT JOIN user_roles ur ON ur.user_id = u.id
+		LEFT JOIN roles r ON r.id = ur.role_id
+		GROUP BY u.id
+		ORDER BY u.id`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			user      models.User
+			deletedAt sql.NullTime
+			roles     sql.NullString
+		)
+		if err := rows.Scan(&user.ID, &user.Email, &user.PassHash, &user.PassAlgo, &user.IsAdmin, &user.Status, &deletedAt, &roles); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		user.DeletedAt = deletedAt.Time
+		var names []string
+		if roles.Valid {
+			names = strings.Split(roles.String, ",")
+		}
+		if err := fn(user, names); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
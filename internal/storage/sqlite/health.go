@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/storage"
+)
+
+// SchemaVersion - версия последней миграции из migrations, на которую рассчитан код.
+// Увеличивается вместе с добавлением новой миграции.
+const SchemaVersion = 13
+
+// migrationsTable - таблица версий, которую ведёт migrator по умолчанию
+const migrationsTable = "migrations"
+
+// Ping проверяет, что база доступна.
+// Проверки готовности выполняются периодически, поэтому в метрики и трассы запросов не попадают.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+
+	if err := s.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// CheckSchema проверяет, что миграции применены хотя бы до SchemaVersion и последняя завершилась.
+// Более новая схема допустима: при поэтапном обновлении старые экземпляры работают с ней до замены.
+func (s *Storage) CheckSchema(ctx context.Context) error {
+	const op = "storage.sqlite.CheckSchema"
+
+	var (
+		version int
+		dirty   bool
+	)
+	err := s.conn.QueryRowContext(ctx, "SELECT version, dirty FROM "+migrationsTable+" LIMIT 1").Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("%s: no version recorded: %w", op, storage.ErrSchemaOutdated)
+	case err != nil:
+		return fmt.Errorf("%s: %w", op, err)
+	case dirty:
+		return fmt.Errorf("%s: version %d: %w", op, version, storage.ErrSchemaDirty)
+	case version < SchemaVersion:
+		return fmt.Errorf("%s: version %d, want %d: %w", op, version, SchemaVersion, storage.ErrSchemaOutdated)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ImportUser сохраняет пользователя с хэшем пароля, полученным во внешней системе
+func (s *Storage) ImportUser(ctx context.Context, email string, passHash []byte, passAlgo string, isAdmin bool) (int64, error) {
+	const op = "storage.sqlite.ImportUser"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	var id int64
+	err := s.WithTx(ctx, func(tx *Storage) error {
+		res, err := tx.db.ExecContext(ctx,
+			`INSERT INTO users (email, pass_hash, pass_algo, is_admin, created_at, updated_at)
+			VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+			email, passHash, passAlgo, isAdmin)
+		if err != nil {
+			return err
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		return tx.addEvent(ctx, models.DomainEvent{Type: models.EventUserRegistered, UserID: id, Data: map[string]string{"email": email}})
+	})
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		// Пользователь с таким email уже есть
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// UpdatePassword заменяет хэш пароля пользователя и алгоритм, которым он получен
+func (s *Storage) UpdatePassword(ctx context.Context, userID int64, passHash []byte, passAlgo string) error {
+	const op = "storage.sqlite.UpdatePassword"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET pass_hash = ?, pass_algo = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", passHash, passAlgo, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"time"
+)
+
+// SaveAuthCode сохраняет код авторизации и удаляет истёкшие коды
+func (s *Storage) SaveAuthCode(ctx context.Context, code models.AuthCode) error {
+	const op = "storage.sqlite.SaveAuthCode"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	err := s.WithTx(ctx, func(tx *Storage) error {
+		if _, err := tx.db.ExecContext(ctx, "DELETE FROM oauth_codes WHERE expires_at < ?", code.CreatedAt.UTC()); err != nil {
+			return err
+		}
+		_, err := tx.db.ExecContext(ctx, `
+			INSERT INTO oauth_codes (code_hash, app_id, user_id, redirect_uri, code_challenge, created_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			code.CodeHash, code.AppID, code.UserID, code.RedirectURI, code.CodeChallenge, code.CreatedAt.UTC(), code.ExpiresAt.UTC())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UseAuthCode отмечает код использованным и возвращает его. Код отмечается одним запросом,
+// поэтому из двух одновременных обменов проходит только один.
+// storage.ErrCodeUsed возвращается вместе с кодом, чтобы можно было отозвать открытый по нему сеанс.
+func (s *Storage) UseAuthCode(ctx context.Context, codeHash string, at time.Time) (models.AuthCode, error) {
+	const op = "storage.sqlite.UseAuthCode"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE oauth_codes SET used_at = ? WHERE code_hash = ? AND used_at IS NULL AND expires_at > ?",
+		at.UTC(), codeHash, at.UTC())
+	if err != nil {
+		return models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	used, err := res.RowsAffected()
+	if err != nil {
+		return models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var (
+		code   models.AuthCode
+		usedAt sql.NullTime
+	)
+	err = s.db.QueryRowContext(ctx, `
+		SELECT code_hash, app_id, user_id, redirect_uri, code_challenge, created_at, expires_at, used_at, session_id
+		FROM oauth_codes WHERE code_hash = ?`, codeHash).
+		Scan(&code.CodeHash, &code.AppID, &code.UserID, &code.RedirectURI, &code.CodeChallenge,
+			&code.CreatedAt, &code.ExpiresAt, &usedAt, &code.SessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AuthCode{}, fmt.Errorf("%s: %w", op, storage.ErrCodeNotFound)
+		}
+		return models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	code.UsedAt = usedAt.Time
+	switch {
+	case used == 1:
+		return code, nil
+	case usedAt.Valid:
+		return code, fmt.Errorf("%s: %w", op, storage.ErrCodeUsed)
+	}
+	return models.AuthCode{}, fmt.Errorf("%s: %w", op, storage.ErrCodeNotFound) // Истёк
+}
+
+// SetAuthCodeSession запоминает сеанс, открытый по коду
+func (s *Storage) SetAuthCodeSession(ctx context.Context, codeHash, sessionID string) error {
+	const op = "storage.sqlite.SetAuthCodeSession"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE oauth_codes SET session_id = ? WHERE code_hash = ?", sessionID, codeHash); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// encodeRedirectURIs переводит адреса возврата в значение столбца apps.redirect_uris
+func encodeRedirectURIs(uris []string) (string, error) {
+	if len(uris) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(uris)
+	return string(data), err
+}
+
+// decodeRedirectURIs читает адреса возврата из столбца apps.redirect_uris
+func decodeRedirectURIs(data string) ([]string, error) {
+	var uris []string
+	if err := json.Unmarshal([]byte(data), &uris); err != nil {
+		return nil, fmt.Errorf("redirect_uris: %w", err)
+	}
+	return uris, nil
+}
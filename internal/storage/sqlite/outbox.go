@@ -0,0 +1,261 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"strings"
+	"time"
+)
+
+// deliveryColumns - столбцы доставки с её событием в порядке scanDelivery
+const deliveryColumns = `d.id, d.app_id, d.status, d.attempts, d.next_attempt_at, d.last_error, d.updated_at,
+	o.id, o.type, o.user_id, o.app_id, o.data, o.created_at`
+
+// addEvent записывает событие в outbox. Вызывается в транзакции изменения, о котором сообщает событие.
+func (s *Storage) addEvent(ctx context.Context, event models.DomainEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	if len(event.Data) == 0 {
+		data = []byte("{}")
+	}
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO outbox (type, user_id, app_id, data, created_at) VALUES (?, ?, ?, ?, ?)",
+		event.Type, event.UserID, event.AppID, string(data), time.Now().UTC())
+	return err
+}
+
+// UndispatchedEvents возвращает до limit событий outbox, ещё не разложенных по доставкам, в порядке записи
+func (s *Storage) UndispatchedEvents(ctx context.Context, limit int) ([]models.DomainEvent, error) {
+	const op = "storage.sqlite.UndispatchedEvents"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	events, err := s.events(ctx, "dispatched_at IS NULL ORDER BY id LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// EventsAfter возвращает до limit событий outbox с номером больше afterID в порядке записи.
+// Запись в SQLite идёт по одной транзакции, поэтому событие с меньшим номером не может появиться
+// после того, как прочитано событие с большим: курсор по номеру не пропускает события.
+func (s *Storage) EventsAfter(ctx context.Context, afterID int64, limit int) ([]models.DomainEvent, error) {
+	const op = "storage.sqlite.EventsAfter"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	events, err := s.events(ctx, "id > ? ORDER BY id LIMIT ?", afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// PrunedThrough возвращает наибольший номер события, удалённого из outbox по сроку хранения
+func (s *Storage) PrunedThrough(ctx context.Context) (int64, error) {
+	const op = "storage.sqlite.PrunedThrough"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	var id int64
+	if err := s.db.QueryRowContext(ctx, "SELECT pruned_through FROM outbox_watermark WHERE id = 1").Scan(&id); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// DispatchEvent в одной транзакции создаёт доставки события приложениям appIDs и отмечает событие разложенным.
+// Доставки, созданные до сбоя, повторно не создаются.
+func (s *Storage) DispatchEvent(ctx context.Context, eventID int64, appIDs []int, at time.Time) error {
+	const op = "storage.sqlite.DispatchEvent"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	at = at.UTC()
+	err := s.WithTx(ctx, func(tx *Storage) error {
+		for _, appID := range appIDs {
+			if _, err := tx.db.ExecContext(ctx, `
+				INSERT OR IGNORE INTO webhook_deliveries (event_id, app_id, next_attempt_at, updated_at)
+				VALUES (?, ?, ?, ?)`, eventID, appID, at, at); err != nil {
+				return err
+			}
+		}
+		_, err := tx.db.ExecContext(ctx, "UPDATE outbox SET dispatched_at = ? WHERE id = ?", at, eventID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// DueDeliveries возвращает до limit ожидающих доставок, время попытки которых наступило к now
+func (s *Storage) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	const op = "storage.sqlite.DueDeliveries"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	deliveries, err := s.deliveries(ctx, "d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at, d.id LIMIT ?",
+		models.DeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return deliveries, nil
+}
+
+// DeadDeliveries возвращает недоставленные события по фильтру в порядке номеров доставок
+func (s *Storage) DeadDeliveries(ctx context.Context, filter models.DeliveryFilter) ([]models.WebhookDelivery, error) {
+	const op = "storage.sqlite.DeadDeliveries"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	where, args := []string{"d.status = ?", "d.id > ?"}, []any{models.DeliveryDead, filter.AfterID}
+	if filter.AppID != 0 {
+		where, args = append(where, "d.app_id = ?"), append(args, filter.AppID)
+	}
+	deliveries, err := s.deliveries(ctx, strings.Join(where, " AND ")+" ORDER BY d.id LIMIT ?", append(args, filter.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return deliveries, nil
+}
+
+// MarkDelivered отмечает доставку успешной
+func (s *Storage) MarkDelivered(ctx context.Context, deliveryID int64, at time.Time) error {
+	const op = "storage.sqlite.MarkDelivered"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	if _, err := s.db.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, last_error = '', updated_at = ? WHERE id = ?",
+		models.DeliveryDelivered, at.UTC(), deliveryID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// MarkFailed учитывает неудачную попытку: назначает следующую на next или, если dead,
+// переносит доставку в список недоставленных
+func (s *Storage) MarkFailed(ctx context.Context, deliveryID int64, lastErr string, next time.Time, dead bool) error {
+	const op = "storage.sqlite.MarkFailed"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	status := models.DeliveryPending
+	if dead {
+		status = models.DeliveryDead
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_error = ?, updated_at = ?
+		WHERE id = ?`,
+		status, next.UTC(), lastErr, time.Now().UTC(), deliveryID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// PruneOutbox удаляет доставленные до before доставки и разложенные до before события,
+// у которых не осталось доставок, и запоминает наибольший номер удалённого события.
+// Недоставленные события хранятся, пока их не разберёт администратор.
+func (s *Storage) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.PruneOutbox"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	const prunable = `dispatched_at IS NOT NULL AND dispatched_at < ?
+		AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = outbox.id)`
+
+	var pruned int64
+	err := s.WithTx(ctx, func(tx *Storage) error {
+		if _, err := tx.db.ExecContext(ctx,
+			"DELETE FROM webhook_deliveries WHERE status = ? AND updated_at < ?", models.DeliveryDelivered, before.UTC()); err != nil {
+			return err
+		}
+		if _, err := tx.db.ExecContext(ctx, `
+			UPDATE outbox_watermark
+			SET pruned_through = max(pruned_through, coalesce((SELECT max(id) FROM outbox WHERE `+prunable+`), 0))
+			WHERE id = 1`, before.UTC()); err != nil {
+			return err
+		}
+		res, err := tx.db.ExecContext(ctx, "DELETE FROM outbox WHERE "+prunable, before.UTC())
+		if err != nil {
+			return err
+		}
+		pruned, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return pruned, nil
+}
+
+// events выбирает события outbox; where - условие и порядок
+func (s *Storage) events(ctx context.Context, where string, args ...any) ([]models.DomainEvent, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, type, user_id, app_id, data, created_at FROM outbox WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.DomainEvent
+	for rows.Next() {
+		var (
+			event models.DomainEvent
+			data  string
+		)
+		if err := rows.Scan(&event.ID, &event.Type, &event.UserID, &event.AppID, &data, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &event.Data); err != nil {
+			return nil, fmt.Errorf("event %d: data: %w", event.ID, err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// deliveries выбирает доставки с событиями; where - условие и порядок по столбцам d (доставка) и o (событие)
+func (s *Storage) deliveries(ctx context.Context, where string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries d JOIN outbox o ON o.id = d.event_id WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// scanDelivery читает доставку и её событие из строки с deliveryColumns
+func scanDelivery(rows *sql.Rows) (models.WebhookDelivery, error) {
+	var (
+		d    models.WebhookDelivery
+		data string
+	)
+	err := rows.Scan(&d.ID, &d.AppID, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.UpdatedAt,
+		&d.Event.ID, &d.Event.Type, &d.Event.UserID, &d.Event.AppID, &data, &d.Event.CreatedAt)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if err := json.Unmarshal([]byte(data), &d.Event.Data); err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("event %d: data: %w", d.Event.ID, err)
+	}
+	return d, nil
+}
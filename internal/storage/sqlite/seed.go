@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/storage"
+)
+
+// UpsertApp создаёт приложение с заданным ID или приводит существующее к нужному состоянию
+func (s *Storage) UpsertApp(ctx context.Context, app models.App) (storage.UpsertResult, error) {
+	const op = "storage.sqlite.UpsertApp"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	redirectURIs, err := encodeRedirectURIs(app.RedirectURIs)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var current models.App
+	var currentURIs string
+	err = s.db.QueryRowContext(ctx, "SELECT name, secret, redirect_uris, public_client FROM apps WHERE id = ?", app.ID).
+		Scan(&current.Name, &current.Secret, &currentURIs, &current.PublicClient)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Приложения нет - создаём
+		if _, err := s.db.ExecContext(ctx, "INSERT INTO apps (id, name, secret, redirect_uris, public_client) VALUES (?, ?, ?, ?, ?)",
+			app.ID, app.Name, app.Secret, redirectURIs, app.PublicClient); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		return storage.Created, nil
+	case err != nil:
+		return "", fmt.Errorf("%s: %w", op, err)
+	case current.Name == app.Name && current.Secret == app.Secret && currentURIs == redirectURIs && current.PublicClient == app.PublicClient:
+		return storage.Unchanged, nil
+	}
+
+	// Приложение есть, но отличается - обновляем
+	if _, err := s.db.ExecContext(ctx, "UPDATE apps SET name = ?, secret = ?, redirect_uris = ?, public_client = ? WHERE id = ?",
+		app.Name, app.Secret, redirectURIs, app.PublicClient, app.ID); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return storage.Updated, nil
+}
+
+// UpsertRole создаёт роль по имени или обновляет её описание
+func (s *Storage) UpsertRole(ctx context.Context, role models.Role) (storage.UpsertResult, error) {
+	const op = "storage.sqlite.UpsertRole"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	var description string
+	err := s.db.QueryRowContext(ctx, "SELECT description FROM roles WHERE name = ?", role.Name).Scan(&description)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if _, err := s.db.ExecContext(ctx, "INSERT INTO roles (name, description) VALUES (?, ?)",
+			role.Name, role.Description); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		return storage.Created, nil
+	case err != nil:
+		return "", fmt.Errorf("%s: %w", op, err)
+	case description == role.Description:
+		return storage.Unchanged, nil
+	}
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE roles SET description = ? WHERE name = ?",
+		role.Description, role.Name); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return storage.Updated, nil
+}
+
+// UpsertUser создаёт пользователя по email или обновляет его флаг администратора.
+// Пустой passHash оставляет пароль существующего пользователя без изменений.
+func (s *Storage) UpsertUser(ctx context.Context, email string, passHash []byte, passAlgo string, isAdmin bool) (int64, storage.UpsertResult, error) {
+	const op = "storage.sqlite.UpsertUser"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	var (
+		id          int64
+		currentHash []byte
+		currentAlgo string
+		currentFlag bool
+	)
+	err := s.db.QueryRowContext(ctx, "SELECT id, pass_hash, pass_algo, is_admin FROM users WHERE email = ?", email).
+		Scan(&id, &currentHash, &currentAlgo, &currentFlag)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if len(passHash) == 0 {
+			return 0, "", fmt.Errorf("%s: password hash is required for new user", op)
+		}
+		err := s.WithTx(ctx, func(tx *Storage) error {
+			res, err := tx.db.ExecContext(ctx, `INSERT INTO users (email, pass_hash, pass_algo, is_admin, created_at, updated_at)
+				VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+				email, passHash, passAlgo, isAdmin)
+			if err != nil {
+				return err
+			}
+			if id, err = res.LastInsertId(); err != nil {
+				return err
+			}
+			return tx.addEvent(ctx, models.DomainEvent{Type: models.EventUserRegistered, UserID: id, Data: map[string]string{"email": email}})
+		})
+		if err != nil {
+			return 0, "", fmt.Errorf("%s: %w", op, err)
+		}
+		return id, storage.Created, nil
+	case err != nil:
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(passHash) == 0 {
+		passHash, passAlgo = currentHash, currentAlgo // Пароль не меняется
+	}
+	if currentFlag == isAdmin && bytes.Equal(currentHash, passHash) {
+		return id, storage.Unchanged, nil
+	}
+
+	err = s.WithTx(ctx, func(tx *Storage) error {
+		if _, err := tx.db.ExecContext(ctx, "UPDATE users SET pass_hash = ?, pass_algo = ?, is_admin = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			passHash, passAlgo, isAdmin, id); err != nil {
+			return err
+		}
+		if bytes.Equal(currentHash, passHash) {
+			return nil // Изменился только флаг администратора
+		}
+		return tx.addEvent(ctx, models.DomainEvent{Type: models.EventPasswordChanged, UserID: id})
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+	return id, storage.Updated, nil
+}
+
+// GrantRole назначает пользователю роль по её имени
+func (s *Storage) GrantRole(ctx context.Context, userID int64, roleName string) (storage.UpsertResult, error) {
+	const op = "storage.sqlite.GrantRole"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	var roleID int64
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM roles WHERE name = ?", roleName).Scan(&roleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := storage.Created
+	err = s.WithTx(ctx, func(tx *Storage) error {
+		res, err := tx.db.ExecContext(ctx, "INSERT OR IGNORE INTO user_roles (user_id, role_id) VALUES (?, ?)", userID, roleID)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			result = storage.Unchanged // Роль уже была назначена
+			return nil
+		}
+		return tx.addEvent(ctx, models.DomainEvent{Type: models.EventRoleGranted, UserID: userID, Data: map[string]string{"role": roleName}})
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/storage"
+	"time"
+)
+
+// CreateSession сохраняет новый сеанс пользователя вместе с событием о входе
+func (s *Storage) CreateSession(ctx context.Context, session models.Session) error {
+	const op = "storage.sqlite.CreateSession"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	err := s.WithTx(ctx, func(tx *Storage) error {
+		_, err := tx.db.ExecContext(ctx,
+			"INSERT INTO sessions (id, user_id, app_id, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+			session.ID, session.UserID, session.AppID, session.CreatedAt.UTC(), session.ExpiresAt.UTC())
+		if err != nil {
+			return err
+		}
+		return tx.addEvent(ctx, models.DomainEvent{
+			Type:   models.EventUserLoggedIn,
+			UserID: session.UserID,
+			AppID:  session.AppID,
+			Data:   map[string]string{"session_id": session.ID},
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Session возвращает сеанс по идентификатору
+func (s *Storage) Session(ctx context.Context, id string) (models.Session, error) {
+	const op = "storage.sqlite.Session"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	var (
+		session models.Session
+		revoked sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, user_id, app_id, created_at, expires_at, revoked_at FROM sessions WHERE id = ?", id).
+		Scan(&session.ID, &session.UserID, &session.AppID, &session.CreatedAt, &session.ExpiresAt, &revoked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+		}
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	session.RevokedAt = revoked.Time
+	return session, nil
+}
+
+// RevokeSession отзывает сеанс, если он ещё действует
+func (s *Storage) RevokeSession(ctx context.Context, id string) error {
+	const op = "storage.sqlite.RevokeSession"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	if _, err := s.db.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RevokeUserSessions отзывает все действующие сеансы пользователя и возвращает их количество
+func (s *Storage) RevokeUserSessions(ctx context.Context, userID int64) (int64, error) {
+	const op = "storage.sqlite.RevokeUserSessions"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL",
+		time.Now().UTC(), userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	revoked, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return revoked, nil
+}
@@ -9,10 +9,34 @@ import (
 	"github.com/linemk/gRPC_auth/internal/storage"
 	"github.com/mattn/go-sqlite3"
 	_ "github.com/mattn/go-sqlite3" // Импортируем SQLite драйвер
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"time"
 )
 
+// tracer создаёт спаны запросов; без настроенного поставщика трасс спаны не записываются
+var tracer = otel.Tracer("github.com/linemk/gRPC_auth/internal/storage/sqlite")
+
 type Storage struct {
-	db *sql.DB // Поле для работы с базой данных через sql.DB
+	conn     *sql.DB       // Пул соединений с базой данных
+	db       executor      // Исполнитель запросов: сам пул или открытая транзакция
+	observer QueryObserver // Получатель длительности запросов, может отсутствовать
+}
+
+// QueryObserver получает длительность каждого метода хранилища
+type QueryObserver interface {
+	ObserveQuery(method string, duration time.Duration)
+}
+
+// executor объединяет методы, общие для sql.DB и sql.Tx
+type executor interface {
+	Prepare(query string) (*sql.Stmt, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
 func NewStorage(storagePath string) (*Storage, error) {
@@ -25,21 +49,74 @@ func NewStorage(storagePath string) (*Storage, error) {
 	}
 
 	// Возвращаем экземпляр Storage с открытой базой данных
-	return &Storage{db: db}, nil
+	return &Storage{conn: db, db: db}, nil
 }
 
-func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (uid int64, err error) {
-	const op = "storage.sqlite.SaveUser"
+// WithTx выполняет fn в транзакции: изменения фиксируются, только если fn вернула nil.
+// Если хранилище уже работает внутри транзакции, fn выполняется в ней же.
+func (s *Storage) WithTx(ctx context.Context, fn func(tx *Storage) error) error {
+	const op = "storage.sqlite.WithTx"
+
+	if _, ok := s.db.(*sql.Tx); ok {
+		return fn(s)
+	}
 
-	// Подготавливаем SQL-запрос для вставки пользователя
-	stmt, err := s.db.PrepareContext(ctx, "INSERT INTO users (email, pass_hash) VALUES (?, ?)")
+	tx, err := s.conn.BeginTx(ctx, nil)
 	if err != nil {
-		// Возвращаем ошибку, если не удалось подготовить запрос
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := fn(&Storage{conn: s.conn, db: tx, observer: s.observer}); err != nil {
+		_ = tx.Rollback() // Ошибка отката не важнее исходной ошибки
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// SetQueryObserver задаёт получателя длительности методов хранилища
+func (s *Storage) SetQueryObserver(observer QueryObserver) {
+	s.observer = observer
+}
+
+// instrument открывает спан метода op и возвращает функцию, которая закрывает его
+// и передаёт длительность наблюдателю; вызывается в начале метода, результат - через defer
+func (s *Storage) instrument(ctx context.Context, op string) (context.Context, func()) {
+	method := strings.TrimPrefix(op, "storage.sqlite.")
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemSqlite, semconv.DBOperationName(method)),
+	)
+	return ctx, func() {
+		span.End()
+		if s.observer != nil {
+			s.observer.ObserveQuery(method, time.Since(start))
+		}
 	}
+}
 
-	// Выполняем запрос с указанными значениями
-	res, err := stmt.ExecContext(ctx, email, passHash)
+func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (uid int64, err error) {
+	const op = "storage.sqlite.SaveUser"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	// Пользователь и событие о регистрации сохраняются в одной транзакции
+	err = s.WithTx(ctx, func(tx *Storage) error {
+		// Вставляем пользователя с указанными значениями
+		res, err := tx.db.ExecContext(ctx,
+			"INSERT INTO users (email, pass_hash, created_at, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)",
+			email, passHash)
+		if err != nil {
+			return err
+		}
+		// Получаем ID последней вставленной записи
+		if uid, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		return tx.addEvent(ctx, models.DomainEvent{Type: models.EventUserRegistered, UserID: uid, Data: map[string]string{"email": email}})
+	})
 	if err != nil {
 		var sqliteErr sqlite3.Error
 
@@ -51,34 +128,24 @@ func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (
 		// Возвращаем общую ошибку выполнения запроса
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-
-	// Получаем ID последней вставленной записи
-	id, err := res.LastInsertId()
-	if err != nil {
-		// Возвращаем ошибку, если не удалось получить ID
-		return 0, fmt.Errorf("%s: %w", op, err)
-	}
 	// Возвращаем ID нового пользователя
-	return id, nil
+	return uid, nil
 }
 
 func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 	const op = "storage.sqlite.User"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
 
 	// Подготавливаем SQL-запрос для выбора пользователя по email
-	stmt, err := s.db.Prepare("SELECT id, email, pass_hash FROM users WHERE email=?")
+	stmt, err := s.db.Prepare("SELECT " + userColumns + " FROM users WHERE email=?")
 	if err != nil {
 		// Возвращаем ошибку, если не удалось подготовить запрос
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Выполняем запрос с указанным email
-	row := stmt.QueryRowContext(ctx, email)
-
-	var user models.User
-
-	// Читаем результат запроса в структуру пользователя
-	err = row.Scan(&user.ID, &user.Email, &user.PassHash)
+	// Выполняем запрос с указанным email и читаем результат в структуру пользователя
+	user, err := scanUser(stmt.QueryRowContext(ctx, email))
 	if err != nil {
 		// Если пользователь не найден, возвращаем соответствующую ошибку
 		if errors.Is(err, sql.ErrNoRows) {
@@ -94,6 +161,8 @@ func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 
 func (s *Storage) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	const op = "storage.sqlite.IsAdmin"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
 
 	// Подготавливаем SQL-запрос для проверки, является ли пользователь админом
 	stmt, err := s.db.Prepare("SELECT is_admin FROM users WHERE id=?")
@@ -124,9 +193,11 @@ func (s *Storage) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 
 func (s *Storage) App(ctx context.Context, appID int) (models.App, error) {
 	const op = "storage.sqlite.App"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
 
 	// Подготавливаем SQL-запрос для выбора приложения по ID
-	stmt, err := s.db.Prepare("SELECT id, name, secret FROM apps WHERE id=?")
+	stmt, err := s.db.Prepare("SELECT id, name, secret, redirect_uris, public_client FROM apps WHERE id=?")
 	if err != nil {
 		// Возвращаем ошибку, если не удалось подготовить запрос
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
@@ -135,10 +206,13 @@ func (s *Storage) App(ctx context.Context, appID int) (models.App, error) {
 	// Выполняем запрос с указанным appID
 	row := stmt.QueryRowContext(ctx, appID)
 
-	var app models.App
+	var (
+		app          models.App
+		redirectURIs string
+	)
 
 	// Читаем результат запроса в структуру приложения
-	err = row.Scan(&app.ID, &app.Name, &app.Secret)
+	err = row.Scan(&app.ID, &app.Name, &app.Secret, &redirectURIs, &app.PublicClient)
 	if err != nil {
 		// Если приложение не найдено, возвращаем соответствующую ошибку
 		if errors.Is(err, sql.ErrNoRows) {
@@ -147,7 +221,15 @@ func (s *Storage) App(ctx context.Context, appID int) (models.App, error) {
 		// Возвращаем другую ошибку, если произошел сбой
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
+	if app.RedirectURIs, err = decodeRedirectURIs(redirectURIs); err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
 
 	// Возвращаем найденное приложение
 	return app, nil
 }
+
+// Close закрывает соединение с базой данных
+func (s *Storage) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+)
+
+// sessionPageSize - сколько сеансов читается за один запрос при обходе
+const sessionPageSize = 500
+
+// UserRoles возвращает роли пользователя
+func (s *Storage) UserRoles(ctx context.Context, userID int64) ([]models.Role, error) {
+	const op = "storage.sqlite.UserRoles"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, r.name, r.description
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = ?
+		ORDER BY r.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return roles, nil
+}
+
+// ForEachUserSession по одному передаёт в fn сеансы пользователя от старых к новым.
+// Сеансы читаются страницами, и курсор закрывается до вызова fn, чтобы медленный
+// получатель не держал блокировку чтения базы.
+func (s *Storage) ForEachUserSession(ctx context.Context, userID int64, fn func(session models.Session) error) error {
+	const op = "storage.sqlite.ForEachUserSession"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	var afterRowID int64
+	for {
+		page, lastRowID, err := s.userSessionPage(ctx, userID, afterRowID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, session := range page {
+			if err := fn(session); err != nil {
+				return err
+			}
+		}
+		if len(page) < sessionPageSize {
+			return nil
+		}
+		afterRowID = lastRowID
+	}
+}
+
+// userSessionPage читает следующую страницу сеансов в порядке вставки
+func (s *Storage) userSessionPage(ctx context.Context, userID, afterRowID int64) ([]models.Session, int64, error) {
+	// Измеряется каждая страница: время обработки сеансов получателем к запросам не относится
+	ctx, end := s.instrument(ctx, "storage.sqlite.ForEachUserSession")
+	defer end()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT rowid, id, user_id, app_id, created_at, expires_at, revoked_at
+		FROM sessions
+		WHERE user_id = ? AND rowid > ?
+		ORDER BY rowid
+		LIMIT ?`, userID, afterRowID, sessionPageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		page      []models.Session
+		lastRowID int64
+	)
+	for rows.Next() {
+		var (
+			session models.Session
+			revoked sql.NullTime
+		)
+		err := rows.Scan(&lastRowID, &session.ID, &session.UserID, &session.AppID,
+			&session.CreatedAt, &session.ExpiresAt, &revoked)
+		if err != nil {
+			return nil, 0, err
+		}
+		session.RevokedAt = revoked.Time
+		page = append(page, session)
+	}
+	return page, lastRowID, rows.Err()
+}
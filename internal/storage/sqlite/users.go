@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/linemk/gRPC_auth/internal/domain/models"
+	"github.com/linemk/gRPC_auth/internal/storage"
+)
+
+// userColumns - столбцы, которые читает scanUser
+const userColumns = "id, email, pass_hash, pass_algo, is_admin, status, created_at, updated_at, last_login_at, deleted_at"
+
+// scanner - общий интерфейс sql.Row и sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser читает пользователя из строки результата с набором столбцов userColumns
+func scanUser(row scanner) (models.User, error) {
+	var (
+		user                                       models.User
+		createdAt, updatedAt, lastLogin, deletedAt sql.NullTime
+	)
+	err := row.Scan(&user.ID, &user.Email, &user.PassHash, &user.PassAlgo, &user.IsAdmin, &user.Status,
+		&createdAt, &updatedAt, &lastLogin, &deletedAt)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.CreatedAt, user.UpdatedAt, user.LastLoginAt = createdAt.Time, updatedAt.Time, lastLogin.Time
+	user.DeletedAt = deletedAt.Time
+	return user, nil
+}
+
+// UserByID возвращает пользователя по идентификатору
+func (s *Storage) UserByID(ctx context.Context, userID int64) (models.User, error) {
+	const op = "storage.sqlite.UserByID"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	user, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// SetUserStatus переводит пользователя из состояния from в состояние to и записывает событие о смене.
+// Если состояние успело измениться, возвращается storage.ErrStatusChanged.
+func (s *Storage) SetUserStatus(ctx context.Context, userID int64, from, to models.UserStatus) error {
+	const op = "storage.sqlite.SetUserStatus"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	err := s.WithTx(ctx, func(tx *Storage) error {
+		res, err := tx.db.ExecContext(ctx,
+			"UPDATE users SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND status = ?",
+			to, userID, from)
+		if err != nil {
+			return err
+		}
+		// Выясняем, пропал ли пользователь или изменилось его состояние
+		if err := tx.expectAffected(ctx, res, userID); err != nil {
+			return err
+		}
+		return tx.addEvent(ctx, models.DomainEvent{
+			Type:   models.EventUserStatusChanged,
+			UserID: userID,
+			Data:   map[string]string{"from": string(from), "to": string(to)},
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// TouchLastLogin записывает время успешного входа пользователя
+func (s *Storage) TouchLastLogin(ctx context.Context, userID int64) error {
+	const op = "storage.sqlite.TouchLastLogin"
+	ctx, end := s.instrument(ctx, op)
+	defer end()
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE users SET last_login_at = CURRENT_TIMESTAMP WHERE id = ?", userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// expectAffected проверяет, что условное обновление пользователя изменило строку,
+// и различает отсутствие пользователя и параллельное изменение его состояния
+func (s *Storage) expectAffected(ctx context.Context, res sql.Result, userID int64) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+	if _, err := s.UserByID(ctx, userID); err != nil {
+		return err
+	}
+	return storage.ErrStatusChanged
+}
@@ -5,7 +5,24 @@ import (
 )
 
 var (
-	ErrUserExists   = errors.New("user already exists") // Ошибка: пользователь уже существует
-	ErrUserNotFound = errors.New("user not found")      // Ошибка: пользователь не найден
-	ErrAppNotFound  = errors.New("app not found")       // Ошибка: приложение не найдено
+	ErrUserExists      = errors.New("user already exists")               // Ошибка: пользователь уже существует
+	ErrUserNotFound    = errors.New("user not found")                    // Ошибка: пользователь не найден
+	ErrAppNotFound     = errors.New("app not found")                     // Ошибка: приложение не найдено
+	ErrRoleNotFound    = errors.New("role not found")                    // Ошибка: роль не найдена
+	ErrSessionNotFound = errors.New("session not found")                 // Ошибка: сеанс не найден
+	ErrCodeNotFound    = errors.New("authorization code not found")      // Ошибка: код авторизации не найден или истёк
+	ErrCodeUsed        = errors.New("authorization code already used")   // Ошибка: код авторизации уже обменян
+	ErrGracePeriodOver = errors.New("deletion grace period is over")     // Ошибка: срок восстановления истёк
+	ErrStatusChanged   = errors.New("user status changed concurrently")  // Ошибка: состояние пользователя изменилось параллельно
+	ErrSchemaOutdated  = errors.New("schema migrations are not applied") // Ошибка: схема базы старше, чем ожидает сервис
+	ErrSchemaDirty     = errors.New("schema migration is dirty")         // Ошибка: миграция прервана и требует ручного исправления
+)
+
+// UpsertResult описывает итог идемпотентной записи
+type UpsertResult string
+
+const (
+	Created   UpsertResult = "created"   // Запись создана
+	Updated   UpsertResult = "updated"   // Запись изменена
+	Unchanged UpsertResult = "unchanged" // Запись уже была в нужном состоянии
 )
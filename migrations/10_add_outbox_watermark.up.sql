@@ -0,0 +1,8 @@
+-- Наибольший номер события, удалённого из outbox по сроку хранения. Подписчик WatchEvents с курсором
+-- меньше него мог пропустить удалённые события и должен начать заново.
+CREATE TABLE IF NOT EXISTS outbox_watermark
+(
+    id             INTEGER PRIMARY KEY CHECK (id = 1),
+    pruned_through INTEGER NOT NULL DEFAULT 0
+);
+INSERT OR IGNORE INTO outbox_watermark (id) VALUES (1);
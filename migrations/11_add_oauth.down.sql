@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_oauth_codes_user;
+DROP INDEX IF EXISTS idx_oauth_codes_expires_at;
+DROP TABLE IF EXISTS oauth_codes;
+ALTER TABLE apps DROP COLUMN redirect_uris;
@@ -0,0 +1,20 @@
+-- Разрешённые адреса возврата OAuth 2.0: JSON массив строк, сравнение точное
+ALTER TABLE apps
+    ADD COLUMN redirect_uris TEXT NOT NULL DEFAULT '[]';
+
+-- Коды авторизации OAuth 2.0. Хранится только хэш кода; код одноразовый (used_at),
+-- session_id - сеанс, открытый по коду: при повторном предъявлении кода он отзывается.
+CREATE TABLE IF NOT EXISTS oauth_codes
+(
+    code_hash      TEXT PRIMARY KEY,
+    app_id         INTEGER   NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    user_id        INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    redirect_uri   TEXT      NOT NULL,
+    code_challenge TEXT      NOT NULL,
+    created_at     TIMESTAMP NOT NULL,
+    expires_at     TIMESTAMP NOT NULL,
+    used_at        TIMESTAMP,
+    session_id     TEXT      NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_oauth_codes_expires_at ON oauth_codes (expires_at);
+CREATE INDEX IF NOT EXISTS idx_oauth_codes_user ON oauth_codes (user_id);
@@ -0,0 +1 @@
+ALTER TABLE apps DROP COLUMN public_client;
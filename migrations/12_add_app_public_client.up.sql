@@ -0,0 +1,4 @@
+-- Публичный клиент OAuth 2.0 (браузерное или мобильное приложение) не может хранить секрет и защищён только PKCE.
+-- По умолчанию приложение конфиденциальное: при обмене кода на токен оно обязано предъявить секрет.
+ALTER TABLE apps
+    ADD COLUMN public_client BOOLEAN NOT NULL DEFAULT FALSE;
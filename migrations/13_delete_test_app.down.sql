@@ -0,0 +1 @@
+-- Удалённое тестовое приложение не восстанавливается: для локальной разработки его создаёт migrator seed.
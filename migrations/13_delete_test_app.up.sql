@@ -0,0 +1,9 @@
+-- Удаляет тестовое приложение, которое миграция 3 создавала во всех окружениях с общеизвестным секретом.
+-- Удаляется только строка с исходными учётными данными тестового приложения, вместе с его сеансами и кодами авторизации.
+-- Для локальной разработки и e2e тестов приложение создаётся заново из seeds/local: migrator seed после migrator up.
+DELETE FROM oauth_codes
+WHERE app_id IN (SELECT id FROM apps WHERE id = 1 AND name = 'test' AND secret = 'test-secret');
+DELETE FROM sessions
+WHERE app_id IN (SELECT id FROM apps WHERE id = 1 AND name = 'test' AND secret = 'test-secret');
+DELETE FROM apps
+WHERE id = 1 AND name = 'test' AND secret = 'test-secret';
@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN pass_algo TEXT NOT NULL DEFAULT 'bcrypt';
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_status;
+ALTER TABLE users DROP COLUMN last_login_at;
+ALTER TABLE users DROP COLUMN updated_at;
+ALTER TABLE users DROP COLUMN created_at;
+ALTER TABLE users DROP COLUMN status;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE users
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('pending_verification', 'active', 'suspended', 'locked', 'deleted'));
+-- SQLite не позволяет добавить столбец с непостоянным значением по умолчанию,
+-- поэтому отметки времени существующих пользователей заполняются отдельно
+ALTER TABLE users
+    ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE users
+    ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE users
+    ADD COLUMN last_login_at TIMESTAMP;
+UPDATE users
+SET created_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_users_status ON users (status);
\ No newline at end of file
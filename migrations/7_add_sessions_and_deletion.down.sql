@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_tombstones;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+ALTER TABLE users DROP COLUMN purged_at;
+ALTER TABLE users DROP COLUMN deleted_at;
+DROP TABLE IF EXISTS sessions;
\ No newline at end of file
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id         TEXT PRIMARY KEY,
+    user_id    INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    app_id     INTEGER   NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions (user_id);
+
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMP;
+-- Время очистки персональных данных при обезличивании (строка пользователя остаётся)
+ALTER TABLE users
+    ADD COLUMN purged_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+-- Надгробия стёртых учётных записей: только идентификатор и время удаления и очистки, без персональных данных.
+-- После очистки email освобождается и может быть зарегистрирован заново.
+CREATE TABLE IF NOT EXISTS user_tombstones
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER   NOT NULL,
+    deleted_at TIMESTAMP NOT NULL,
+    purged_at  TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_user_tombstones_user ON user_tombstones (user_id);
@@ -0,0 +1,36 @@
+-- Журнал аудита: только дозапись. Каждая запись содержит хэш предыдущей (prev_hash) и свой хэш (hash),
+-- поэтому изменение, удаление или вставка записи в середину обнаруживается проверкой цепочки.
+-- Персональные данные сюда не пишутся, пользователи указываются идентификаторами:
+-- очистка удалённых учётных записей журнал не затрагивает.
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    seq        INTEGER PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    type       TEXT      NOT NULL,
+    outcome    TEXT      NOT NULL,
+    actor_id   INTEGER   NOT NULL DEFAULT 0,
+    subject_id INTEGER   NOT NULL DEFAULT 0,
+    app_id     INTEGER   NOT NULL DEFAULT 0,
+    request_id TEXT      NOT NULL DEFAULT '',
+    details    TEXT      NOT NULL DEFAULT '{}',
+    prev_hash  TEXT      NOT NULL UNIQUE, -- У записи один преемник: параллельная дозапись не разветвит цепочку
+    hash       TEXT      NOT NULL UNIQUE
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_type ON audit_log (type);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_subject ON audit_log (subject_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+
+-- Изменить или удалить запись можно, только удалив эти триггеры; это тоже обнаружит проверка цепочки
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update
+    BEFORE UPDATE
+    ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete
+    BEFORE DELETE
+    ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
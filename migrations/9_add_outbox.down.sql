@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_status;
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS idx_outbox_user;
+DROP INDEX IF EXISTS idx_outbox_undispatched;
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,31 @@
+-- Outbox событий предметной области: событие записывается в одной транзакции с изменением состояния.
+-- Диспетчер раскладывает события по доставкам на вебхуки приложений (dispatched_at) и отправляет их.
+CREATE TABLE IF NOT EXISTS outbox
+(
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    type          TEXT      NOT NULL,
+    user_id       INTEGER   NOT NULL DEFAULT 0,
+    app_id        INTEGER   NOT NULL DEFAULT 0,
+    data          TEXT      NOT NULL DEFAULT '{}',
+    created_at    TIMESTAMP NOT NULL,
+    dispatched_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_undispatched ON outbox (id) WHERE dispatched_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_user ON outbox (user_id);
+
+-- Доставки событий на вебхуки: по одной на событие и приложение.
+-- Доставки с исчерпанными попытками (status = 'dead') остаются для разбора администратором.
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id        INTEGER   NOT NULL REFERENCES outbox (id) ON DELETE CASCADE,
+    app_id          INTEGER   NOT NULL,
+    status          TEXT      NOT NULL DEFAULT 'pending',
+    attempts        INTEGER   NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error      TEXT      NOT NULL DEFAULT '',
+    updated_at      TIMESTAMP NOT NULL,
+    UNIQUE (event_id, app_id) -- Повторная раскладка после сбоя не создаёт дубликатов
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status, id);
@@ -0,0 +1,4 @@
+package ssov1
+
+// Код пакета генерируется из proto/sso плагинами protoc-gen-go v1.36.5 и protoc-gen-go-grpc v1.5.1
+//go:generate protoc -I ../../../proto --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative sso/sso.proto sso/admin.proto
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestDeleteAccount_RestoreWithinGracePeriod(t *testing.T) {
+	ctx, st := suite.New(t)
+	adminCtx := st.AdminContext(ctx)
+
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass, AppId: appId})
+	require.NoError(t, err)
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+respLogin.GetToken())
+
+	respDel, err := st.AuthClient.DeleteAccount(userCtx, &ssov1.DeleteAccountRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, respReg.GetUserId(), respDel.GetUserId())
+	assert.InDelta(t, st.Cfg.Deletion.GracePeriod.Seconds(), respDel.GetPurgeAfter()-respDel.GetDeletedAt(), 1)
+
+	// Сеансы отозваны, вход в удалённую учётную запись невозможен
+	_, err = st.AuthClient.DeleteAccount(userCtx, &ssov1.DeleteAccountRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass, AppId: appId})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	respRestore, err := st.AdminClient.RestoreAccount(adminCtx, &ssov1.RestoreAccountRequest{UserId: respReg.GetUserId()})
+	require.NoError(t, err)
+	assert.Equal(t, "active", respRestore.GetStatus())
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass, AppId: appId})
+	require.NoError(t, err)
+
+	// Восстановить можно только удалённую учётную запись
+	_, err = st.AdminClient.RestoreAccount(adminCtx, &ssov1.RestoreAccountRequest{UserId: respReg.GetUserId()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestDeleteAccount_NoToken(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AuthClient.DeleteAccount(ctx, &ssov1.DeleteAccountRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
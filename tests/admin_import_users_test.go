@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const (
+	// Тестовый вектор SHA-512-crypt из спецификации crypt(3)
+	legacyHash     = "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"
+	legacyPassword = "Hello world!"
+)
+
+func TestImportUsers_LegacyHash_LoginUpgrades(t *testing.T) {
+	ctx, st := suite.New(t)
+	email := gofakeit.Email()
+
+	stream, err := st.AdminClient.ImportUsers(st.AdminContext(ctx))
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&ssov1.ImportUsersRequest{Row: 1, Email: email, PasswordHash: legacyHash}))
+	require.NoError(t, stream.Send(&ssov1.ImportUsersRequest{Row: 2, Email: "not-an-email", PasswordHash: legacyHash}))
+	require.NoError(t, stream.CloseSend())
+
+	progress, err := stream.Recv()
+	require.NoError(t, err)
+	assert.True(t, progress.GetDone())
+	assert.Equal(t, int64(1), progress.GetImported())
+	assert.Equal(t, int64(1), progress.GetFailed())
+	assert.Equal(t, int64(2), progress.GetCheckpoint())
+	require.Len(t, progress.GetErrors(), 1)
+
+	// Первый вход проверяет импортированный хэш и заменяет его на bcrypt, второй - уже по bcrypt
+	for i := 0; i < 2; i++ {
+		respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: legacyPassword, AppId: appId})
+		require.NoError(t, err)
+		assert.NotEmpty(t, respLogin.GetToken())
+	}
+
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: randomFakePassword(), AppId: appId})
+	require.Error(t, err)
+}
+
+func TestImportUsers_HashCostAboveLimit_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "pbkdf2 iterations", hash: "pbkdf2_sha256$100000000$salt$AAAA"},
+		{name: "sha512-crypt rounds", hash: "$6$rounds=999999999$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := st.AdminClient.ImportUsers(st.AdminContext(ctx))
+			require.NoError(t, err)
+			require.NoError(t, stream.Send(&ssov1.ImportUsersRequest{Row: 1, Email: gofakeit.Email(), PasswordHash: tt.hash}))
+			require.NoError(t, stream.CloseSend())
+
+			progress, err := stream.Recv()
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), progress.GetImported())
+			assert.Equal(t, int64(1), progress.GetFailed())
+			require.Len(t, progress.GetErrors(), 1)
+			assert.Contains(t, progress.GetErrors()[0].GetMessage(), "hash cost exceeds limit")
+		})
+	}
+}
+
+func TestImportUsers_WithoutToken_FailKey(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	stream, err := st.AdminClient.ImportUsers(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.CloseSend())
+
+	_, err = stream.Recv()
+	require.Error(t, err)
+	assert.Equal(t, "rpc error: code = Unauthenticated desc = bearer token is required", err.Error())
+}
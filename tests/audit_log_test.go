@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestQueryAuditLog_RecordsLoginsAndChainsHashes(t *testing.T) {
+	ctx, st := suite.New(t)
+	adminCtx := st.AdminContext(ctx)
+
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: "wrong" + pass, AppId: appId})
+	require.Error(t, err)
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass, AppId: appId})
+	require.NoError(t, err)
+
+	resp, err := st.AdminClient.QueryAuditLog(adminCtx, &ssov1.QueryAuditLogRequest{
+		SubjectId: respReg.GetUserId(),
+		Types:     []string{"user.register", "user.login"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetEvents(), 3)
+
+	register, failed, login := resp.GetEvents()[0], resp.GetEvents()[1], resp.GetEvents()[2]
+	assert.Equal(t, "user.register", register.GetType())
+	assert.Equal(t, "success", register.GetOutcome())
+	assert.Equal(t, "user.login", failed.GetType())
+	assert.Equal(t, "failure", failed.GetOutcome())
+	assert.Equal(t, "invalid_password", failed.GetDetails()["reason"])
+	assert.Equal(t, "success", login.GetOutcome())
+	assert.Equal(t, int64(appId), login.GetAppId())
+	assert.Less(t, register.GetSeq(), failed.GetSeq())
+	assert.Less(t, failed.GetSeq(), login.GetSeq())
+	for _, e := range resp.GetEvents() {
+		assert.Len(t, e.GetHash(), 64)
+		assert.NotEqual(t, e.GetPrevHash(), e.GetHash())
+	}
+
+	// Постраничная выборка: вторая страница начинается после курсора первой
+	page, err := st.AdminClient.QueryAuditLog(adminCtx, &ssov1.QueryAuditLogRequest{SubjectId: respReg.GetUserId(), Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.GetEvents(), 1)
+	assert.Equal(t, register.GetSeq(), page.GetNextAfterSeq())
+	next, err := st.AdminClient.QueryAuditLog(adminCtx, &ssov1.QueryAuditLogRequest{
+		SubjectId: respReg.GetUserId(),
+		Limit:     1,
+		AfterSeq:  page.GetNextAfterSeq(),
+	})
+	require.NoError(t, err)
+	require.Len(t, next.GetEvents(), 1)
+	assert.Equal(t, failed.GetSeq(), next.GetEvents()[0].GetSeq())
+}
+
+func TestQueryAuditLog_NotAdmin_FailKey(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AdminClient.QueryAuditLog(ctx, &ssov1.QueryAuditLogRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -219,3 +219,15 @@ func TestLogin_FailCases(t *testing.T) {
 	}
 
 }
+
+// Пользователь support@localhost из seeds/local задан готовым bcrypt хэшем
+func TestLogin_SeededPasswordHash(t *testing.T) {
+	ctx, st := suite.New(t)
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "support@localhost",
+		Password: "support",
+		AppId:    appId,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respLogin.GetToken())
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/linemk/gRPC_auth/internal/config"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"testing"
+)
+
+// certApp возвращает клиента Auth с сертификатом приложения из SSO_TEST_APP_CLIENT_CERT и SSO_TEST_APP_CLIENT_KEY
+// и идентификатор приложения, которому сертификат сопоставлен в grpc.tls.client_apps.
+// Тест пропускается, если сервер не проверяет клиентские сертификаты или сертификат не задан.
+func certApp(t *testing.T, st *suite.Suite) (ssov1.AuthClient, int) {
+	t.Helper()
+	certFile, keyFile := os.Getenv("SSO_TEST_APP_CLIENT_CERT"), os.Getenv("SSO_TEST_APP_CLIENT_KEY")
+	tlsCfg := st.Cfg.GRPC.TLS
+	if tlsCfg.CertFile == "" || tlsCfg.ClientAuth == "" || tlsCfg.ClientAuth == "none" || certFile == "" {
+		t.Skip("mTLS is not configured: set grpc.tls with client_auth and client_apps for the server, " +
+			"and SSO_TEST_APP_CLIENT_CERT and SSO_TEST_APP_CLIENT_KEY for the tests")
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	appID, ok := mappedApp(cert, tlsCfg)
+	require.True(t, ok, "client certificate is not mapped to an app in grpc.tls.client_apps")
+	return st.AuthClientWithCert(certFile, keyFile), appID
+}
+
+// mappedApp ищет приложение сертификата так же, как сервер: URI, DNS имена, затем Common Name
+func mappedApp(cert *x509.Certificate, cfg config.TLSConfig) (int, bool) {
+	var identities []string
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	identities = append(identities, cert.DNSNames...)
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	for _, identity := range identities {
+		if appID, ok := cfg.ClientApps[identity]; ok {
+			return appID, true
+		}
+	}
+	return 0, false
+}
+
+func TestLogin_ClientCert_WithoutAppID(t *testing.T) {
+	ctx, st := suite.New(t)
+	client, appID := certApp(t, st)
+
+	email, pass := gofakeit.Email(), randomFakePassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	// Приложение берётся из сертификата
+	resp, err := client.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(resp.GetToken(), claims)
+	require.NoError(t, err)
+	assert.Equal(t, appID, int(claims["app_id"].(float64)))
+
+	// Совпадающий AppId тоже принимается
+	_, err = client.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass, AppId: int32(appID)})
+	require.NoError(t, err)
+}
+
+func TestLogin_ClientCert_MismatchedAppID_FailKey(t *testing.T) {
+	ctx, st := suite.New(t)
+	client, appID := certApp(t, st)
+
+	email, pass := gofakeit.Email(), randomFakePassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	_, err = client.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass, AppId: int32(appID + 1)})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "AppId does not match client certificate", status.Convert(err).Message())
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/linemk/gRPC_auth/internal/services/dataexport"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// chunkReceiver - поток с частями архива
+type chunkReceiver interface {
+	Recv() (*ssov1.DataChunk, error)
+}
+
+func TestExportMyData_SignedArchive(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+	for range 2 {
+		_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass, AppId: appId})
+		require.NoError(t, err)
+	}
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass, AppId: appId})
+	require.NoError(t, err)
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+respLogin.GetToken())
+
+	stream, err := st.AuthClient.ExportMyData(userCtx, &ssov1.ExportMyDataRequest{})
+	require.NoError(t, err)
+	archive := receiveArchive(t, stream)
+
+	kinds := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(archive))
+	for scanner.Scan() {
+		var rec struct {
+			Kind string          `json:"kind"`
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		kinds[rec.Kind]++
+		if rec.Kind == "profile" {
+			assert.Contains(t, string(rec.Data), email)
+			assert.NotContains(t, string(rec.Data), "pass_hash")
+		}
+	}
+	assert.Equal(t, 1, kinds["profile"])
+	assert.Equal(t, 3, kinds["session"])
+	assert.Equal(t, 3, kinds["login"])
+
+	// Ключ подписи в репозитории не хранится: подпись проверяется, только если файл ключа
+	// передан и серверу, и тестам через SSO_DATA_EXPORT_SIGNING_KEY_FILE
+	keyFile := st.Cfg.DataExport.SigningKeyFile
+	if keyFile == "" {
+		t.Skip("data_export.signing_key_file is not set, archive is signed with a temporary key")
+	}
+	if !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join("..", keyFile) // Относительные пути конфигурации отсчитываются от корня репозитория
+	}
+	pub, err := dataexport.LoadPublicKey(keyFile)
+	require.NoError(t, err)
+	header, err := dataexport.Verify(bytes.NewReader(archive), pub)
+	require.NoError(t, err)
+	assert.Equal(t, respReg.GetUserId(), header.UserID)
+
+	// Изменённый архив не проходит проверку
+	tampered := bytes.Replace(archive, []byte(email), []byte("x"+email), 1)
+	_, err = dataexport.Verify(bytes.NewReader(tampered), pub)
+	assert.ErrorIs(t, err, dataexport.ErrInvalidSignature)
+}
+
+func TestExportUserData_Admin(t *testing.T) {
+	ctx, st := suite.New(t)
+	adminCtx := st.AdminContext(ctx)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: gofakeit.Email(), Password: randomFakePassword()})
+	require.NoError(t, err)
+
+	stream, err := st.AdminClient.ExportUserData(adminCtx, &ssov1.ExportUserDataRequest{UserId: respReg.GetUserId()})
+	require.NoError(t, err)
+	archive := receiveArchive(t, stream)
+	assert.Contains(t, string(archive), `"kind":"signature"`)
+
+	stream, err = st.AdminClient.ExportUserData(adminCtx, &ssov1.ExportUserDataRequest{UserId: respReg.GetUserId() + 1_000_000})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// receiveArchive собирает архив из потока
+func receiveArchive(t *testing.T, stream chunkReceiver) []byte {
+	t.Helper()
+	var archive []byte
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return archive
+		}
+		require.NoError(t, err)
+		archive = append(archive, chunk.GetData()...)
+	}
+}
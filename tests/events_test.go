@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/linemk/gRPC_auth/internal/lib/webhook"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// webhookWait - сколько ждать доставки события: опрос outbox раз в poll_interval плюс запас
+const webhookWait = 15 * time.Second
+
+func TestWebhook_UserRegistered_DeliveredSigned(t *testing.T) {
+	ctx, st := suite.New(t)
+	if len(st.Cfg.Events.Webhooks) == 0 {
+		t.Skip("events.webhooks is not configured: set SSO_EVENTS_WEBHOOKS for the server and the tests, see config/local.yaml")
+	}
+	hook := st.Cfg.Events.Webhooks[0]
+	u, err := url.Parse(hook.URL)
+	require.NoError(t, err)
+
+	type delivery struct {
+		body    []byte
+		header  http.Header
+		eventID string
+	}
+	received := make(chan delivery, 100)
+	ln, err := net.Listen("tcp", u.Host)
+	require.NoError(t, err)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{body: body, header: r.Header.Clone(), eventID: r.Header.Get("X-SSO-Event-ID")}
+	})}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	email := gofakeit.Email()
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: randomFakePassword()})
+	require.NoError(t, err)
+
+	timeout := time.After(webhookWait)
+	for {
+		select {
+		case d := <-received:
+			var event struct {
+				ID     int64             `json:"id"`
+				Type   string            `json:"type"`
+				UserID int64             `json:"user_id"`
+				Data   map[string]string `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(d.body, &event))
+			if event.UserID != respReg.GetUserId() {
+				continue // Событие другого теста
+			}
+			assert.Equal(t, "user.registered", event.Type)
+			assert.Equal(t, email, event.Data["email"])
+			assert.NotEmpty(t, d.eventID)
+			assert.NoError(t, webhook.Verify([]byte(hook.Secret), d.header, d.body, time.Now(), time.Minute))
+			return
+		case <-timeout:
+			t.Fatalf("user.registered for user %d was not delivered in %s", respReg.GetUserId(), webhookWait)
+		}
+	}
+}
+
+func TestListDeadLetters_Admin(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	resp, err := st.AdminClient.ListDeadLetters(st.AdminContext(ctx), &ssov1.ListDeadLettersRequest{Limit: 10})
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(resp.GetDeadLetters()), 10)
+	for _, d := range resp.GetDeadLetters() {
+		assert.NotEmpty(t, d.GetEventType())
+		assert.NotEmpty(t, d.GetLastError())
+		assert.Positive(t, d.GetAttempts())
+	}
+}
+
+func TestListDeadLetters_NegativeLimit_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AdminClient.ListDeadLetters(st.AdminContext(ctx), &ssov1.ListDeadLettersRequest{Limit: -1})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListDeadLetters_NotAdmin_FailKey(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.AdminClient.ListDeadLetters(ctx, &ssov1.ListDeadLettersRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestWatchEvents_ResumeFromCursor(t *testing.T) {
+	ctx, st := suite.New(t)
+	ctx, cancel := context.WithTimeout(ctx, webhookWait)
+	defer cancel()
+
+	email := gofakeit.Email()
+	first, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: randomFakePassword()})
+	require.NoError(t, err)
+
+	watchCtx, stopWatch := context.WithCancel(st.AdminContext(ctx))
+	stream, err := st.AdminClient.WatchEvents(watchCtx, &ssov1.WatchEventsRequest{Types: []string{"user.registered"}})
+	require.NoError(t, err)
+	resp := recvUserEvent(t, stream, first.GetUserId())
+	stopWatch()
+	assert.Equal(t, "user.registered", resp.GetEvent().GetType())
+	assert.Equal(t, email, resp.GetEvent().GetData()["email"])
+	assert.Equal(t, resp.GetEvent().GetId(), resp.GetCursor())
+
+	// Событие, записанное без подписчика, приходит после переподключения с курсором
+	second, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: gofakeit.Email(), Password: randomFakePassword()})
+	require.NoError(t, err)
+	stream, err = st.AdminClient.WatchEvents(st.AdminContext(ctx), &ssov1.WatchEventsRequest{
+		Types:   []string{"user.*"},
+		AfterId: resp.GetCursor(),
+	})
+	require.NoError(t, err)
+	next := recvUserEvent(t, stream, second.GetUserId())
+	assert.Greater(t, next.GetCursor(), resp.GetCursor())
+}
+
+func TestWatchEvents_InvalidType_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	stream, err := st.AdminClient.WatchEvents(st.AdminContext(ctx), &ssov1.WatchEventsRequest{Types: []string{"user.*.x*"}})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWatchEvents_NotAdmin_FailKey(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	stream, err := st.AdminClient.WatchEvents(ctx, &ssov1.WatchEventsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+// recvUserEvent читает поток до события пользователя userID, пропуская чужие события и сдвиги курсора
+func recvUserEvent(t *testing.T, stream ssov1.Admin_WatchEventsClient, userID int64) *ssov1.WatchEventsResponse {
+	t.Helper()
+
+	for {
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		if resp.GetEvent() != nil && resp.GetEvent().GetUserId() == userID {
+			return resp
+		}
+	}
+}
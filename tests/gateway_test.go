@@ -0,0 +1,229 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Origin браузерного приложения 1 из config/local.yaml
+const spaOrigin = "http://localhost:5173"
+
+// gatewayPost отправляет JSON в шлюз и разбирает ответ
+func gatewayPost(st *suite.Suite, path string, body any) (int, map[string]any) {
+	st.Helper()
+	if st.GatewayURL == "" {
+		st.Skip("gateway.addr is not set")
+	}
+	data, err := json.Marshal(body)
+	require.NoError(st, err)
+	resp, err := st.HTTPClient.Post(st.GatewayURL+path, "application/json", bytes.NewReader(data))
+	require.NoError(st, err)
+	defer resp.Body.Close()
+
+	var out map[string]any
+	require.NoError(st, json.NewDecoder(resp.Body).Decode(&out))
+	return resp.StatusCode, out
+}
+
+func TestGateway_RegisterLogin_HappyPath(t *testing.T) {
+	_, st := suite.New(t)
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+
+	code, reg := gatewayPost(st, "/v1/auth/register", map[string]any{"email": email, "password": pass})
+	require.Equal(t, http.StatusOK, code, reg)
+	assert.NotEmpty(t, reg["userId"])
+
+	code, login := gatewayPost(st, "/v1/auth/login", map[string]any{"email": email, "password": pass, "appId": appId})
+	require.Equal(t, http.StatusOK, code, login)
+	assert.NotEmpty(t, login["token"])
+}
+
+func TestGateway_StatusMapping(t *testing.T) {
+	_, st := suite.New(t)
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+
+	code, _ := gatewayPost(st, "/v1/auth/register", map[string]any{"email": email, "password": pass})
+	require.Equal(t, http.StatusOK, code)
+
+	tests := []struct {
+		name     string
+		path     string
+		body     map[string]any
+		wantCode int
+	}{
+		{"empty password", "/v1/auth/login", map[string]any{"email": email, "appId": appId}, http.StatusBadRequest},
+		{"wrong password", "/v1/auth/login", map[string]any{"email": email, "password": randomFakePassword(), "appId": appId}, http.StatusUnauthorized},
+		{"user exists", "/v1/auth/register", map[string]any{"email": email, "password": pass}, http.StatusConflict},
+		{"no token", "/v1/auth/delete-account", map[string]any{}, http.StatusUnauthorized},
+		{"unknown path", "/v1/auth/unknown", map[string]any{}, http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := gatewayPost(st, tt.path, tt.body)
+			assert.Equal(t, tt.wantCode, code, body)
+			assert.NotEmpty(t, body["message"])
+		})
+	}
+}
+
+func TestGateway_OpenAPI(t *testing.T) {
+	_, st := suite.New(t)
+	if st.GatewayURL == "" {
+		t.Skip("gateway.addr is not set")
+	}
+
+	resp, err := st.HTTPClient.Get(st.GatewayURL + "/v1/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.NotEmpty(t, doc.OpenAPI)
+	for _, path := range []string{"/v1/auth/register", "/v1/auth/login", "/v1/auth/is-admin"} {
+		assert.Contains(t, doc.Paths, path)
+	}
+}
+
+// gatewayRPC вызывает метод по пути gRPC-Web и Connect с заданным Content-Type
+func gatewayRPC(st *suite.Suite, method, contentType string, body []byte, header map[string]string) (*http.Response, []byte) {
+	st.Helper()
+	if st.GatewayURL == "" {
+		st.Skip("gateway.addr is not set")
+	}
+	req, err := http.NewRequest(http.MethodPost, st.GatewayURL+method, bytes.NewReader(body))
+	require.NoError(st, err)
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	resp, err := st.HTTPClient.Do(req)
+	require.NoError(st, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(st, err)
+	return resp, data
+}
+
+// envelope упаковывает сообщение в конверт gRPC-Web и Connect
+func envelope(flags byte, data []byte) []byte {
+	out := make([]byte, 5, 5+len(data))
+	out[0] = flags
+	binary.BigEndian.PutUint32(out[1:], uint32(len(data)))
+	return append(out, data...)
+}
+
+// splitEnvelopes разбирает тело ответа на конверты
+func splitEnvelopes(t *testing.T, data []byte) (flags []byte, messages [][]byte) {
+	t.Helper()
+	for len(data) > 0 {
+		require.GreaterOrEqual(t, len(data), 5)
+		size := int(binary.BigEndian.Uint32(data[1:5]))
+		require.GreaterOrEqual(t, len(data), 5+size)
+		flags = append(flags, data[0])
+		messages = append(messages, data[5:5+size])
+		data = data[5+size:]
+	}
+	return flags, messages
+}
+
+func TestGateway_GRPCWeb_Login(t *testing.T) {
+	ctx, st := suite.New(t)
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	req, err := json.Marshal(map[string]any{"email": email, "password": pass, "appId": appId})
+	require.NoError(t, err)
+	resp, body := gatewayRPC(st, "/auth.Auth/Login", "application/grpc-web+json", envelope(0, req), nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	flags, messages := splitEnvelopes(t, body)
+	require.Equal(t, []byte{0x00, 0x80}, flags) // Сообщение ответа и трейлеры
+	var login struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(messages[0], &login))
+	assert.NotEmpty(t, login.Token)
+	assert.Contains(t, string(messages[1]), "grpc-status: 0")
+}
+
+func TestGateway_Connect_Errors(t *testing.T) {
+	_, st := suite.New(t)
+	email := gofakeit.Email()
+
+	resp, body := gatewayRPC(st, "/auth.Auth/Login", "application/json",
+		[]byte(`{"email":"`+email+`","password":"wrong","appId":1}`), map[string]string{"Connect-Protocol-Version": "1"})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	var connectErr struct {
+		Code string `json:"code"`
+	}
+	require.NoError(t, json.Unmarshal(body, &connectErr))
+	assert.Equal(t, "unauthenticated", connectErr.Code)
+
+	resp, _ = gatewayRPC(st, "/auth.Auth/Login", "text/plain", []byte(`{}`), nil)
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+}
+
+func TestGateway_CORS(t *testing.T) {
+	_, st := suite.New(t)
+	if st.GatewayURL == "" {
+		t.Skip("gateway.addr is not set")
+	}
+
+	preflight := func(origin string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, st.GatewayURL+"/auth.Auth/Login", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+		resp, err := st.HTTPClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := preflight(spaOrigin)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, spaOrigin, resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.True(t, strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), "x-grpc-web"))
+
+	resp = preflight("https://evil.example.com")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestGateway_Origin_OtherApp_PermissionDenied(t *testing.T) {
+	ctx, st := suite.New(t)
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	// Origin сопоставлен приложению 1, поэтому вход в другое приложение запрещён
+	resp, _ := gatewayRPC(st, "/auth.Auth/Login", "application/json",
+		[]byte(`{"email":"`+email+`","password":"`+pass+`","appId":2}`), map[string]string{"Origin": spaOrigin})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, spaOrigin, resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// Origin не подтверждает приложение и не заменяет appId
+	resp, _ = gatewayRPC(st, "/auth.Auth/Login", "application/json",
+		[]byte(`{"email":"`+email+`","password":"`+pass+`"}`), map[string]string{"Origin": spaOrigin})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestHealth_Serving(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	// Сервер в целом и каждый сервис по отдельности
+	for _, service := range []string{"", ssov1.Auth_ServiceDesc.ServiceName, ssov1.Admin_ServiceDesc.ServiceName} {
+		resp, err := st.HealthClient.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err, service)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus(), service)
+	}
+}
+
+func TestHealth_UnknownService_NotFound(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	_, err := st.HealthClient.Check(ctx, &healthpb.HealthCheckRequest{Service: "sso.Unknown"})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
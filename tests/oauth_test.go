@@ -0,0 +1,322 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Адрес возврата приложения 1 из seeds/local
+const oauthRedirectURI = "http://localhost:5173/oauth/callback"
+
+// Конфиденциальный клиент - приложение 2 из seeds/local
+const (
+	backendAppID       = 2
+	backendSecret      = "test-backend-secret"
+	backendRedirectURI = "http://localhost:5174/oauth/callback"
+)
+
+var csrfField = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// oauthClient - браузер пользователя: хранит cookie и не следует перенаправлениям
+type oauthClient struct {
+	st   *suite.Suite
+	http *http.Client
+}
+
+func newOAuthClient(st *suite.Suite) *oauthClient {
+	st.Helper()
+	if st.GatewayURL == "" {
+		st.Skip("gateway.addr is not set")
+	}
+	jar, err := cookiejar.New(nil)
+	require.NoError(st, err)
+	return &oauthClient{st: st, http: &http.Client{
+		Transport: st.HTTPClient.Transport,
+		Jar:       jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// pkce возвращает code_verifier и code_challenge S256
+func pkce() (string, string) {
+	verifier := gofakeit.Password(true, true, true, false, false, 64)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizeQuery(challenge, state string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {strconv.Itoa(appId)},
+		"redirect_uri":          {oauthRedirectURI},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+// openForm открывает страницу входа и возвращает ответ и токен CSRF формы
+func (c *oauthClient) openForm(query url.Values) (*http.Response, string) {
+	c.st.Helper()
+	resp, err := c.http.Get(c.st.GatewayURL + "/oauth/authorize?" + query.Encode())
+	require.NoError(c.st, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(c.st, err)
+	var token string
+	if m := csrfField.FindSubmatch(body); m != nil {
+		token = string(m[1])
+	}
+	return resp, token
+}
+
+// submit отправляет форму входа и возвращает ответ без перехода по перенаправлению
+func (c *oauthClient) submit(query url.Values, csrf, email, password, action string) *http.Response {
+	c.st.Helper()
+	form := url.Values{}
+	for k, v := range query {
+		form[k] = v
+	}
+	form.Set("csrf_token", csrf)
+	form.Set("email", email)
+	form.Set("password", password)
+	form.Set("action", action)
+	resp, err := c.http.PostForm(c.st.GatewayURL+"/oauth/authorize", form)
+	require.NoError(c.st, err)
+	_ = resp.Body.Close()
+	return resp
+}
+
+// authorize проходит вход и согласие и возвращает код авторизации
+func (c *oauthClient) authorize(email, password, challenge, state string) string {
+	c.st.Helper()
+	query := authorizeQuery(challenge, state)
+	resp, csrf := c.openForm(query)
+	require.Equal(c.st, http.StatusOK, resp.StatusCode)
+	require.NotEmpty(c.st, csrf)
+
+	resp = c.submit(query, csrf, email, password, "allow")
+	require.Equal(c.st, http.StatusSeeOther, resp.StatusCode)
+	location, err := resp.Location()
+	require.NoError(c.st, err)
+	require.True(c.st, strings.HasPrefix(location.String(), oauthRedirectURI+"?"), location.String())
+	assert.Equal(c.st, state, location.Query().Get("state"))
+	require.NotEmpty(c.st, location.Query().Get("code"), location.String())
+	return location.Query().Get("code")
+}
+
+// exchange обменивает код на токен и возвращает статус и тело ответа
+func (c *oauthClient) exchange(code, verifier string) (int, map[string]any) {
+	c.st.Helper()
+	resp, err := c.st.HTTPClient.PostForm(c.st.GatewayURL+"/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {strconv.Itoa(appId)},
+		"code":          {code},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {verifier},
+	})
+	require.NoError(c.st, err)
+	defer resp.Body.Close()
+	assert.Equal(c.st, "no-store", resp.Header.Get("Cache-Control"))
+
+	var out map[string]any
+	require.NoError(c.st, json.NewDecoder(resp.Body).Decode(&out))
+	return resp.StatusCode, out
+}
+
+func TestOAuth_AuthorizationCode_HappyPath(t *testing.T) {
+	ctx, st := suite.New(t)
+	client := newOAuthClient(st)
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	reg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	verifier, challenge := pkce()
+	code := client.authorize(email, pass, challenge, "xyz")
+
+	httpCode, body := client.exchange(code, verifier)
+	require.Equal(t, http.StatusOK, httpCode, body)
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.InDelta(t, st.Cfg.TokenTTL.Seconds(), body["expires_in"], 2)
+
+	// Токен выдаётся тем же кодом, что и при входе по паролю
+	token, err := jwt.Parse(body["access_token"].(string), func(*jwt.Token) (interface{}, error) {
+		return []byte(appSecret), nil
+	})
+	require.NoError(t, err)
+	claims := token.Claims.(jwt.MapClaims)
+	assert.Equal(t, reg.GetUserId(), int64(claims["uid"].(float64)))
+	assert.Equal(t, appId, int(claims["app_id"].(float64)))
+}
+
+func TestOAuth_CodeReuse_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+	client := newOAuthClient(st)
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	verifier, challenge := pkce()
+	code := client.authorize(email, pass, challenge, "")
+	httpCode, body := client.exchange(code, verifier)
+	require.Equal(t, http.StatusOK, httpCode, body)
+	userCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+body["access_token"].(string))
+
+	httpCode, body = client.exchange(code, verifier)
+	assert.Equal(t, http.StatusBadRequest, httpCode)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	// Повтор кода отзывает сеанс, открытый по нему: код мог быть перехвачен
+	_, err = st.AuthClient.DeleteAccount(userCtx, &ssov1.DeleteAccountRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestOAuth_WrongVerifier_FailCase(t *testing.T) {
+	ctx, st := suite.New(t)
+	client := newOAuthClient(st)
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	verifier, challenge := pkce()
+	code := client.authorize(email, pass, challenge, "")
+	other, _ := pkce()
+
+	httpCode, body := client.exchange(code, other)
+	assert.Equal(t, http.StatusBadRequest, httpCode)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	// Код сгорает и после неудачной попытки
+	httpCode, body = client.exchange(code, verifier)
+	assert.Equal(t, http.StatusBadRequest, httpCode)
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestOAuth_Authorize_FailCases(t *testing.T) {
+	ctx, st := suite.New(t)
+	client := newOAuthClient(st)
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+	_, challenge := pkce()
+
+	t.Run("unregistered redirect_uri", func(t *testing.T) {
+		query := authorizeQuery(challenge, "")
+		query.Set("redirect_uri", "https://evil.example.com/callback")
+		resp, csrf := client.openForm(query)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Location"))
+		assert.Empty(t, csrf)
+	})
+	t.Run("no pkce", func(t *testing.T) {
+		query := authorizeQuery("", "s1")
+		query.Del("code_challenge_method")
+		resp, _ := client.openForm(query)
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		location, err := resp.Location()
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_request", location.Query().Get("error"))
+		assert.Equal(t, "s1", location.Query().Get("state"))
+	})
+	t.Run("denied", func(t *testing.T) {
+		query := authorizeQuery(challenge, "s2")
+		_, csrf := client.openForm(query)
+		resp := client.submit(query, csrf, "", "", "deny")
+		require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+		location, err := resp.Location()
+		require.NoError(t, err)
+		assert.Equal(t, "access_denied", location.Query().Get("error"))
+		assert.Equal(t, "s2", location.Query().Get("state"))
+	})
+	t.Run("wrong password", func(t *testing.T) {
+		query := authorizeQuery(challenge, "")
+		_, csrf := client.openForm(query)
+		resp := client.submit(query, csrf, email, randomFakePassword(), "allow")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Location"))
+	})
+	t.Run("no csrf token", func(t *testing.T) {
+		resp := client.submit(authorizeQuery(challenge, ""), "", email, pass, "allow")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestOAuth_ConfidentialClient_RequiresSecret(t *testing.T) {
+	ctx, st := suite.New(t)
+	client := newOAuthClient(st)
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	_, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	verifier, challenge := pkce()
+	query := authorizeQuery(challenge, "")
+	query.Set("client_id", strconv.Itoa(backendAppID))
+	query.Set("redirect_uri", backendRedirectURI)
+	resp, csrf := client.openForm(query)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = client.submit(query, csrf, email, pass, "allow")
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	location, err := resp.Location()
+	require.NoError(t, err)
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code, location.String())
+
+	exchange := func(secret string) (int, map[string]any) {
+		req, err := http.NewRequest(http.MethodPost, st.GatewayURL+"/oauth/token", strings.NewReader(url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {backendRedirectURI},
+			"code_verifier": {verifier},
+		}.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(strconv.Itoa(backendAppID), secret)
+		resp, err := st.HTTPClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var out map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return resp.StatusCode, out
+	}
+
+	// Без секрета и с неверным секретом код не обменивается и не сгорает
+	httpCode, body := exchange("")
+	assert.Equal(t, http.StatusUnauthorized, httpCode)
+	assert.Equal(t, "invalid_client", body["error"])
+	httpCode, body = exchange("wrong-secret")
+	assert.Equal(t, http.StatusUnauthorized, httpCode)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	httpCode, body = exchange(backendSecret)
+	require.Equal(t, http.StatusOK, httpCode, body)
+	token, err := jwt.Parse(body["access_token"].(string), func(*jwt.Token) (interface{}, error) {
+		return []byte(backendSecret), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, backendAppID, int(token.Claims.(jwt.MapClaims)["app_id"].(float64)))
+}
@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+func TestRequestID_PropagatedAndGenerated(t *testing.T) {
+	ctx, st := suite.New(t)
+	header := st.Cfg.GRPC.Interceptors.RequestIDHeader
+
+	// Идентификатор клиента возвращается без изменений
+	var md metadata.MD
+	reqCtx := metadata.AppendToOutgoingContext(ctx, header, "test-request-1")
+	_, err := st.AuthClient.Register(reqCtx, &ssov1.RegisterRequest{Email: gofakeit.Email(), Password: randomFakePassword()}, grpc.Header(&md))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test-request-1"}, md.Get(header))
+
+	// Без идентификатора сервер создаёт новый, в том числе для ошибочных вызовов
+	// (в ответе без тела заголовки приходят вместе с трейлерами)
+	var trailer metadata.MD
+	md = nil
+	_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: gofakeit.Email(), Password: randomFakePassword(), AppId: appId},
+		grpc.Header(&md), grpc.Trailer(&trailer))
+	require.Error(t, err)
+	ids := append(md.Get(header), trailer.Get(header)...)
+	require.Len(t, ids, 1)
+	assert.NotEmpty(t, ids[0])
+}
@@ -2,44 +2,179 @@ package suite
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	grpcapp "github.com/linemk/gRPC_auth/internal/app/grpc"
 	"github.com/linemk/gRPC_auth/internal/config"
 	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"net"
-	"strconv"
+	"net/http"
+	"os"
+	"slices"
 	"testing"
 )
 
 const (
 	grpcHost = "localhost"
+
+	// Администратор из seeds/local
+	adminEmail    = "admin@localhost"
+	adminPassword = "admin"
+	adminAppID    = 1
 )
 
 type Suite struct {
 	*testing.T
-	Cfg        *config.Config
-	AuthClient ssov1.AuthClient
+	Cfg          *config.Config
+	AuthClient   ssov1.AuthClient
+	AdminClient  ssov1.AdminClient
+	HealthClient healthpb.HealthClient
+	GatewayURL   string       // Адрес HTTP/JSON шлюза, например http://localhost:8080
+	HTTPClient   *http.Client // Клиент шлюза с теми же TLS параметрами, что и у gRPC
 }
 
 func New(t *testing.T) (context.Context, *Suite) {
 	t.Parallel()
 	t.Helper()
-	cfg := config.MustLoadByPath("../config/local.yaml")
+	cfg, err := config.Read("../config/local.yaml") // Секреты сервера клиенту не нужны
+	if err != nil {
+		t.Fatal(err)
+	}
 	ctx, cancelCtx := context.WithTimeout(context.Background(), cfg.GRPC.Timeout)
 	t.Cleanup(func() {
 		t.Helper()
 		cancelCtx()
 	})
-	cc, err := grpc.DialContext(context.Background(), grpcAddress(cfg), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// Каждый клиент подключается к первому слушателю, обслуживающему его сервис
+	authConn := dial(t, listenerFor(t, cfg, grpcapp.ServiceAuth))
+	adminConn := dial(t, listenerFor(t, cfg, grpcapp.ServiceAdmin))
+	tlsCfg := clientTLS(t, cfg.GRPC.TLS)
+	return ctx, &Suite{
+		T:            t,
+		Cfg:          cfg,
+		AuthClient:   ssov1.NewAuthClient(authConn),
+		AdminClient:  ssov1.NewAdminClient(adminConn),
+		HealthClient: healthpb.NewHealthClient(authConn),
+		GatewayURL:   gatewayURL(cfg, tlsCfg != nil),
+		HTTPClient:   &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}},
+	}
+}
+
+// AdminContext логинится под администратором из seeds/local и возвращает контекст с его токеном
+func (s *Suite) AdminContext(ctx context.Context) context.Context {
+	s.Helper()
+	resp, err := s.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    adminEmail,
+		Password: adminPassword,
+		AppId:    adminAppID,
+	})
+	if err != nil {
+		s.Fatal(err)
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+resp.GetToken())
+}
+
+// AuthClientWithCert подключается к слушателю Auth с клиентским сертификатом из certFile и keyFile
+// вместо SSO_TEST_CLIENT_CERT, например чтобы войти от имени приложения, которому сопоставлен сертификат
+func (s *Suite) AuthClientWithCert(certFile, keyFile string) ssov1.AuthClient {
+	s.Helper()
+	l := listenerFor(s.T, s.Cfg, grpcapp.ServiceAuth)
+	tlsCfg := clientTLS(s.T, *l.TLS)
+	if tlsCfg == nil {
+		s.Fatalf("listener %s has no tls", l.Name)
+	}
+	clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		s.Fatal(err)
+	}
+	tlsCfg.Certificates = []tls.Certificate{clientCert}
+	cc, err := grpc.DialContext(context.Background(), grpcTarget(l), grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	if err != nil {
+		s.Fatal(err)
+	}
+	s.Cleanup(func() { _ = cc.Close() })
+	return ssov1.NewAuthClient(cc)
+}
+
+// clientTLS доверяет сертификату сервера из конфигурации; nil, если TLS выключен.
+// Сертификат клиента берётся из SSO_TEST_CLIENT_CERT и SSO_TEST_CLIENT_KEY, если сервер его требует.
+func clientTLS(t *testing.T, cfg config.TLSConfig) *tls.Config {
+	t.Helper()
+	if cfg.CertFile == "" {
+		return nil
+	}
+	serverCert, err := os.ReadFile(cfg.CertFile)
 	if err != nil {
 		t.Fatal(err)
 	}
-	return ctx, &Suite{
-		T:          t,
-		Cfg:        cfg,
-		AuthClient: ssov1.NewAuthClient(cc),
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(serverCert)
+	tlsCfg := &tls.Config{RootCAs: roots, ServerName: grpcHost}
+	if certFile, keyFile := os.Getenv("SSO_TEST_CLIENT_CERT"), os.Getenv("SSO_TEST_CLIENT_KEY"); certFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{clientCert}
+	}
+	return tlsCfg
+}
+
+// listenerFor возвращает первый слушатель, обслуживающий service
+func listenerFor(t *testing.T, cfg *config.Config, service string) config.ListenerConfig {
+	t.Helper()
+	for _, l := range cfg.GRPC.ListenerConfigs() {
+		if len(l.Services) == 0 || slices.Contains(l.Services, service) {
+			return l
+		}
+	}
+	t.Fatalf("no listener serves %s", service)
+	return config.ListenerConfig{}
+}
+
+// dial подключается к слушателю с его параметрами TLS
+func dial(t *testing.T, l config.ListenerConfig) *grpc.ClientConn {
+	t.Helper()
+	creds := insecure.NewCredentials()
+	if tlsCfg := clientTLS(t, *l.TLS); tlsCfg != nil {
+		creds = credentials.NewTLS(tlsCfg)
+	}
+	cc, err := grpc.DialContext(context.Background(), grpcTarget(l), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
 	}
+	return cc
 }
-func grpcAddress(cfg *config.Config) string {
-	return net.JoinHostPort(grpcHost, strconv.Itoa(cfg.GRPC.Port))
+
+// grpcTarget возвращает адрес подключения к слушателю; сервер на всех интерфейсах доступен через localhost
+func grpcTarget(l config.ListenerConfig) string {
+	if l.Network == "unix" {
+		return "unix:" + l.Address
+	}
+	host, port, err := net.SplitHostPort(l.Address)
+	if err != nil {
+		return l.Address
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = grpcHost
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// gatewayURL строит адрес шлюза; порт берётся из конфигурации, хост - как у gRPC
+func gatewayURL(cfg *config.Config, secure bool) string {
+	_, port, err := net.SplitHostPort(cfg.Gateway.Addr)
+	if err != nil {
+		return ""
+	}
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(grpcHost, port)
 }
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/linemk/gRPC_auth/tests/suite"
+	ssov1 "github.com/linemk/proto_buf/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestUserLifecycle_StatusBlocksLogin(t *testing.T) {
+	ctx, st := suite.New(t)
+	adminCtx := st.AdminContext(ctx)
+
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: pass})
+	require.NoError(t, err)
+
+	tests := []struct {
+		status string
+		code   codes.Code
+	}{
+		{status: "suspended", code: codes.PermissionDenied},
+		{status: "active", code: codes.OK},
+		{status: "locked", code: codes.ResourceExhausted},
+		{status: "active", code: codes.OK},
+		{status: "deleted", code: codes.NotFound},
+	}
+	for _, tt := range tests {
+		respStatus, err := st.AdminClient.SetUserStatus(adminCtx, &ssov1.SetUserStatusRequest{
+			UserId: respReg.GetUserId(),
+			Status: tt.status,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, respStatus.GetStatus())
+
+		_, err = st.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: pass, AppId: appId})
+		assert.Equal(t, tt.code, status.Code(err), tt.status)
+	}
+}
+
+func TestUserLifecycle_InvalidTransition_FailKey(t *testing.T) {
+	ctx, st := suite.New(t)
+	adminCtx := st.AdminContext(ctx)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: gofakeit.Email(), Password: randomFakePassword()})
+	require.NoError(t, err)
+
+	// Из активного состояния нельзя вернуться в ожидание подтверждения
+	_, err = st.AdminClient.SetUserStatus(adminCtx, &ssov1.SetUserStatusRequest{
+		UserId: respReg.GetUserId(),
+		Status: "pending_verification",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}